@@ -0,0 +1,73 @@
+package dynhist
+
+import (
+	"math"
+	"sort"
+)
+
+// AccuracyEntry describes approximation error of a percentile.
+type AccuracyEntry struct {
+	Percentile float64
+	Estimated  float64
+	Exact      float64
+	AbsError   float64
+	RelError   float64
+}
+
+// AccuracyReport compares percentiles estimated from buckets with exact values from RawValues.
+//
+// It returns nil if RawValues are not enabled or empty.
+func (c *Collector) AccuracyReport(ps []float64) []AccuracyEntry {
+	if c == nil {
+		return nil
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	if len(c.RawValues) == 0 {
+		return nil
+	}
+
+	sorted := append([]float64(nil), c.RawValues...)
+	sort.Float64s(sorted)
+
+	return c.accuracy(sorted, ps)
+}
+
+// accuracy compares percentiles estimated from buckets with exact values of sorted.
+func (c *Collector) accuracy(sorted, ps []float64) []AccuracyEntry {
+	res := make([]AccuracyEntry, 0, len(ps))
+
+	for _, p := range ps {
+		e := AccuracyEntry{
+			Percentile: p,
+			Estimated:  c.percentile(p),
+			Exact:      exactPercentile(sorted, p),
+		}
+
+		e.AbsError = math.Abs(e.Estimated - e.Exact)
+		if e.Exact != 0 {
+			e.RelError = e.AbsError / math.Abs(e.Exact)
+		}
+
+		res = append(res, e)
+	}
+
+	return res
+}
+
+// exactPercentile returns nearest-rank percentile of sorted values.
+func exactPercentile(sorted []float64, percent float64) float64 {
+	i := int(math.Ceil(percent*float64(len(sorted))/100)) - 1
+
+	if i < 0 {
+		i = 0
+	}
+
+	if i >= len(sorted) {
+		i = len(sorted) - 1
+	}
+
+	return sorted[i]
+}
@@ -0,0 +1,53 @@
+package dynhist_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vearutop/dynhist-go"
+	"github.com/vearutop/dynhist-go/internal/dataset"
+)
+
+func TestCollector_AccuracyReport(t *testing.T) {
+	ps := []float64{50, 90, 99}
+	prevErr := math.Inf(1)
+
+	for _, limit := range []int{10, 50, 200} {
+		c := dynhist.Collector{
+			BucketsLimit: limit,
+			WeightFunc:   dynhist.LatencyWidth,
+			RawValues:    []float64{},
+		}
+		r := dataset.New(1)
+
+		for i := 0; i < 10000; i++ {
+			c.Add(math.Exp(r.NormFloat64()))
+		}
+
+		report := c.AccuracyReport(ps)
+		require.Len(t, report, len(ps))
+
+		maxErr := 0.0
+
+		for i, e := range report {
+			assert.Equal(t, ps[i], e.Percentile)
+			assert.InDelta(t, math.Abs(e.Estimated-e.Exact), e.AbsError, 1e-9)
+
+			if e.RelError > maxErr {
+				maxErr = e.RelError
+			}
+		}
+
+		assert.Less(t, maxErr, prevErr, "limit %d", limit)
+		prevErr = maxErr
+	}
+}
+
+func TestCollector_AccuracyReport_disabled(t *testing.T) {
+	c := dynhist.Collector{}
+	c.Add(1)
+
+	assert.Nil(t, c.AccuracyReport([]float64{50}))
+}
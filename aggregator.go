@@ -0,0 +1,101 @@
+package dynhist
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Aggregator periodically drains registered collectors into a central collector.
+//
+// It allows workers to own separate collectors without contention on a shared lock.
+type Aggregator struct {
+	mu         sync.Mutex
+	central    *Collector
+	collectors []*Collector
+}
+
+// NewAggregator creates an aggregator that merges data into central collector.
+//
+// A new Collector with default configuration is used if central is nil.
+func NewAggregator(central *Collector) *Aggregator {
+	if central == nil {
+		central = &Collector{}
+	}
+
+	return &Aggregator{central: central}
+}
+
+// Central returns central collector.
+func (a *Aggregator) Central() *Collector {
+	return a.central
+}
+
+// Register adds collector to be drained, it is safe to call during Run. A nil collector is ignored.
+func (a *Aggregator) Register(c *Collector) {
+	if c == nil {
+		return
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.collectors = append(a.collectors, c)
+}
+
+// Unregister removes collector and merges its remaining data, it is safe to call during Run.
+func (a *Aggregator) Unregister(c *Collector) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	for i, rc := range a.collectors {
+		if rc == c {
+			a.collectors = append(a.collectors[:i], a.collectors[i+1:]...)
+
+			a.drain(c)
+
+			return
+		}
+	}
+}
+
+// Drain moves data of registered collectors into central collector.
+//
+// Every collector is reset under its lock right after taking a copy of data,
+// so that values added concurrently are neither lost nor counted twice.
+func (a *Aggregator) Drain() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	for _, c := range a.collectors {
+		a.drain(c)
+	}
+}
+
+func (a *Aggregator) drain(c *Collector) {
+	total, buckets := c.drain()
+
+	a.central.Lock()
+	defer a.central.Unlock()
+
+	a.central.mergeBuckets(total, buckets)
+}
+
+// Run drains registered collectors every interval until ctx is done.
+//
+// Registered collectors are drained once more before returning ctx.Err().
+func (a *Aggregator) Run(ctx context.Context, interval time.Duration) error {
+	tick, stop := newTicker(interval)
+	defer stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			a.Drain()
+
+			return ctx.Err()
+		case <-tick:
+			a.Drain()
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package dynhist_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vearutop/dynhist-go"
+)
+
+func TestAggregator_Run(t *testing.T) {
+	tick := make(chan time.Time)
+
+	defer dynhist.SetTicker(func(d time.Duration) (<-chan time.Time, func()) {
+		return tick, func() {}
+	})()
+
+	a := dynhist.NewAggregator(&dynhist.Collector{BucketsLimit: 10})
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+
+	go func() {
+		done <- a.Run(ctx, time.Second)
+	}()
+
+	const (
+		workers = 8
+		values  = 10000
+	)
+
+	wg := sync.WaitGroup{}
+	expectedSum := 0.0
+
+	for w := 0; w < workers; w++ {
+		c := &dynhist.Collector{BucketsLimit: 5}
+		a.Register(c)
+
+		wg.Add(1)
+
+		go func(w int) {
+			defer wg.Done()
+
+			for i := 0; i < values; i++ {
+				c.Add(float64(i % (100 + w)))
+			}
+
+			if w%2 == 0 {
+				a.Unregister(c)
+			}
+		}(w)
+
+		for i := 0; i < values; i++ {
+			expectedSum += float64(i % (100 + w))
+		}
+	}
+
+	// Rotations concurrent with additions.
+	go func() {
+		for i := 0; i < 20; i++ {
+			select {
+			case tick <- time.Now():
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	wg.Wait()
+	cancel()
+
+	assert.True(t, errors.Is(<-done, context.Canceled))
+
+	central := a.Central()
+	assert.Equal(t, workers*values, central.Count)
+	assert.InDelta(t, expectedSum, central.Sum, 1e-6*expectedSum)
+	assert.NoError(t, central.Validate())
+	assert.Equal(t, 0.0, central.Min)
+	assert.Equal(t, float64(100+workers-2), central.Max)
+}
+
+func TestAggregator_Register_nil(t *testing.T) {
+	c := &dynhist.Collector{}
+	c.Add(1)
+
+	a := dynhist.NewAggregator(&dynhist.Collector{})
+	a.Register(nil)
+	a.Register(c)
+
+	assert.NotPanics(t, a.Drain)
+	assert.Equal(t, 1, a.Central().Count)
+}
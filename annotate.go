@@ -0,0 +1,145 @@
+package dynhist
+
+import "strings"
+
+// labelSeparator joins labels of merged buckets.
+const labelSeparator = "; "
+
+// Annotate attaches label to the bucket that contains value, for example "cache hit" or "timeout spike".
+//
+// Labels stay with buckets through merges, a merged bucket gets labels of both buckets joined
+// with "; ", a label that is already present is not repeated. Annotating a labeled bucket
+// appends label by the same rule, an empty label removes labels of the bucket. Labels are
+// rendered at the end of bucket rows and encoded in JSON. Labels are dropped when buckets are
+// replaced, for example by Merge, LoadBuckets or Reset. Collectors without labels have no overhead.
+//
+// It returns false if no bucket contains value.
+func (c *Collector) Annotate(value float64, label string) bool {
+	if c == nil {
+		return false
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	x := c.placeValue(value)
+	tol := c.tolerance(x)
+
+	for i, b := range c.Buckets {
+		if x < b.Min-tol {
+			return false
+		}
+
+		if x > b.Max+tol {
+			continue
+		}
+
+		if len(c.labels) != len(c.Buckets) {
+			c.labels = make([]string, len(c.Buckets))
+		}
+
+		if label == "" {
+			c.labels[i] = ""
+		} else {
+			c.labels[i] = joinLabels(c.labels[i], label)
+		}
+
+		c.generation++
+
+		return true
+	}
+
+	return false
+}
+
+// BucketLabels returns labels of buckets set with Annotate, nil if there are no labels.
+func (c *Collector) BucketLabels() []string {
+	if c == nil {
+		return nil
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	if len(c.labels) != len(c.Buckets) {
+		return nil
+	}
+
+	return append([]string(nil), c.labels...)
+}
+
+// insertLabel adds an empty label for a new bucket at index i.
+func (c *Collector) insertLabel(i int) {
+	if len(c.labels) != len(c.Buckets)-1 {
+		c.labels = nil
+
+		return
+	}
+
+	c.labels = append(c.labels, "")
+	copy(c.labels[i+1:], c.labels[i:])
+	c.labels[i] = ""
+}
+
+// mergeLabels joins labels of buckets merged at mergePoint-1.
+func (c *Collector) mergeLabels(mergePoint int) {
+	if len(c.labels) != len(c.Buckets)+1 {
+		c.labels = nil
+
+		return
+	}
+
+	merged := joinLabels(c.labels[mergePoint-1], c.labels[mergePoint])
+	c.labels = append(c.labels[:mergePoint-1], c.labels[mergePoint:]...)
+	c.labels[mergePoint-1] = merged
+}
+
+// joinLabels appends labels of b to a skipping labels that a already has.
+func joinLabels(a, b string) string {
+	if a == "" {
+		return b
+	}
+
+	for _, l := range strings.Split(b, labelSeparator) {
+		if l == "" {
+			continue
+		}
+
+		found := false
+
+		for _, existing := range strings.Split(a, labelSeparator) {
+			if existing == l {
+				found = true
+
+				break
+			}
+		}
+
+		if !found {
+			a += labelSeparator + l
+		}
+	}
+
+	return a
+}
+
+// rowLabels sets labels of rows by boundaries of labeled buckets, folded rows are not labeled.
+func rowLabels(rows []BarRow, buckets []Bucket, labels []string) {
+	if len(labels) != len(buckets) {
+		return
+	}
+
+	byMin := make(map[float64]string)
+
+	for i, l := range labels {
+		if l != "" {
+			byMin[buckets[i].Min] = l
+		}
+	}
+
+	for i := range rows {
+		if rows[i].Folded == 0 {
+			rows[i].Label = byMin[rows[i].Min]
+		}
+	}
+}
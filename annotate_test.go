@@ -0,0 +1,93 @@
+package dynhist_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vearutop/dynhist-go"
+)
+
+func TestCollector_Annotate(t *testing.T) {
+	c := &dynhist.Collector{BucketsLimit: 4}
+
+	assert.False(t, c.Annotate(0, "cache hit"), "no buckets")
+	assert.Nil(t, c.BucketLabels())
+
+	for _, v := range []float64{0, 0, 10, 20, 30} {
+		c.Add(v)
+	}
+
+	assert.Nil(t, c.BucketLabels(), "no labels")
+	assert.True(t, c.Annotate(0, "cache hit"))
+	assert.True(t, c.Annotate(30, "timeout"))
+	assert.True(t, c.Annotate(30, "timeout"), "label is not repeated")
+	assert.False(t, c.Annotate(25, "gap"))
+	assert.False(t, c.Annotate(31, "above"))
+
+	assert.Equal(t, []string{"cache hit", "", "", "timeout"}, c.BucketLabels())
+
+	// New buckets are inserted without labels, merges keep labels with values.
+	for _, v := range []float64{1, 2, 3, 12, 13, 25, 29, 31} {
+		c.Add(v)
+	}
+
+	require.Len(t, c.Buckets, 4)
+	assert.Equal(t, []dynhist.Bucket{
+		{Min: 0, Max: 3, Count: 5, Sum: 6},
+		{Min: 10, Max: 13, Count: 3, Sum: 35},
+		{Min: 20, Max: 25, Count: 2, Sum: 45},
+		{Min: 29, Max: 31, Count: 3, Sum: 90},
+	}, c.Buckets)
+	assert.Equal(t, []string{"cache hit", "", "", "timeout"}, c.BucketLabels())
+
+	assert.Equal(t, `[  min   max] cnt total% (13 events)
+[ 0.00  3.00]  5 38.46% ...................................... # cache hit
+[10.00 13.00]  3 23.08% .......................
+[20.00 25.00]  2 15.38% ...............
+[29.00 31.00]  3 23.08% ....................... # timeout <- SLO (30)
+`, c.Render(dynhist.RenderOptions{Thresholds: map[string]float64{"SLO": 30}}))
+
+	rows := c.BarData(0, dynhist.RenderOptions{})
+	assert.Equal(t, "cache hit", rows[0].Label)
+	assert.Equal(t, "timeout", rows[3].Label)
+
+	// Labels are joined when labeled buckets merge, duplicates are skipped.
+	assert.True(t, c.Annotate(12, "slow"))
+	assert.True(t, c.Annotate(20, "slow"))
+	assert.True(t, c.Annotate(20, "cache hit"))
+	assert.True(t, c.Annotate(30, "slow"))
+
+	c = c.Downsample(3)
+
+	assert.Equal(t, []string{"cache hit", "slow", "slow; cache hit; timeout"}, c.BucketLabels())
+
+	j, err := json.Marshal(c)
+	require.NoError(t, err)
+	assert.Contains(t, string(j), `"labels":[{"bucket":0,"label":"cache hit"},{"bucket":1,"label":"slow"},{"bucket":2,"label":"slow; cache hit; timeout"}]`)
+
+	var c2 dynhist.Collector
+
+	require.NoError(t, json.Unmarshal(j, &c2))
+	assert.Equal(t, c.BucketLabels(), c2.BucketLabels())
+	assert.Equal(t, c.String(), c2.String())
+
+	assert.True(t, c.Annotate(30, ""))
+	assert.Equal(t, []string{"cache hit", "slow", ""}, c.BucketLabels())
+
+	cl := c.Clone()
+	assert.Equal(t, c.BucketLabels(), cl.BucketLabels())
+
+	c.Reset()
+	c.Add(1)
+	assert.Nil(t, c.BucketLabels())
+}
+
+func TestCollector_UnmarshalJSON_invalidLabel(t *testing.T) {
+	var c dynhist.Collector
+
+	err := json.Unmarshal([]byte(`{"version":1,"count":1,"sum":1,"min":1,"max":1,`+
+		`"buckets":[{"min":1,"max":1,"count":1,"sum":1}],"labels":[{"bucket":1,"label":"x"}]}`), &c)
+	assert.EqualError(t, err, "invalid bucket: label of bucket 1 out of 1")
+}
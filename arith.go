@@ -0,0 +1,177 @@
+package dynhist
+
+import (
+	"math"
+	"sort"
+)
+
+// ScaleCounts multiplies counts and sums of all buckets by f.
+//
+// Counts are integers, so scaled counts are rounded conserving the rounded total
+// (largest remainder first), buckets are kept even if their count drops to zero.
+// Threshold counts are scaled too, P² estimates and RawValues are not affected,
+// exact largest values of KeepTopK are discarded.
+// Negative or non-finite factors are ignored.
+func (c *Collector) ScaleCounts(f float64) {
+	if c == nil {
+		return
+	}
+
+	if f < 0 || math.IsNaN(f) || math.IsInf(f, 0) {
+		return
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	weights := make([]float64, len(c.Buckets))
+
+	for i, b := range c.Buckets {
+		weights[i] = f * float64(b.Count)
+	}
+
+	counts := roundCounts(weights)
+	c.Count = 0
+	c.Sum *= f
+
+	for i := range c.Buckets {
+		c.Buckets[i].Count = counts[i]
+		c.Buckets[i].Sum *= f
+		c.Count += counts[i]
+	}
+
+	for i := range c.thresholds {
+		c.thresholds[i].count = int(math.Round(f * float64(c.thresholds[i].count)))
+	}
+
+	c.resetTop()
+	c.rawPartial = true
+	c.generation++
+}
+
+// WeightedMerge returns a new collector with data of a and b with counts multiplied by wa and wb.
+//
+// It is useful to combine sampled sources, e.g. a collector of 1% sample merged with weight 100.
+// Buckets are resampled to the union of boundaries like in Merge, weighted counts are rounded
+// conserving the rounded total. Configuration (BucketsLimit, WeightFunc, Transform, etc.)
+// is taken from a. Negative or non-finite weights are treated as zero, a nil collector is treated as empty.
+func WeightedMerge(a, b *Collector, wa, wb float64) *Collector {
+	if a == nil {
+		a = &Collector{}
+	}
+
+	if b == nil {
+		b = &Collector{}
+	}
+
+	ta, ba := a.snapshotBuckets()
+	tb, bb := b.snapshotBuckets()
+
+	a.Lock()
+	res := &Collector{
+		BucketsLimit:   a.BucketsLimit,
+		WeightFunc:     a.WeightFunc,
+		Transform:      a.Transform,
+		Epsilon:        a.Epsilon,
+		EpsilonMode:    a.EpsilonMode,
+		Quantize:       a.Quantize,
+		SnapBoundaries: a.SnapBoundaries,
+		Name:           a.Name,
+		Unit:           a.Unit,
+		Help:           a.Help,
+	}
+	estimated := a.SumEstimated
+	a.Unlock()
+
+	b.Lock()
+	res.SumEstimated = estimated || b.SumEstimated
+	b.Unlock()
+
+	wa, wb = finiteWeight(wa), finiteWeight(wb)
+
+	if wa == 0 {
+		ba = nil
+	}
+
+	if wb == 0 {
+		bb = nil
+	}
+
+	if len(ba) == 0 && len(bb) == 0 {
+		return res
+	}
+
+	ra, rb := align(ba, bb)
+	weights := make([]float64, len(ra))
+
+	for i := range ra {
+		weights[i] = wa*float64(ra[i].Count) + wb*float64(rb[i].Count)
+		ra[i].Sum = wa*ra[i].Sum + wb*rb[i].Sum
+	}
+
+	counts := roundCounts(weights)
+	total := Bucket{Min: math.Inf(1), Max: math.Inf(-1)}
+
+	for i := range ra {
+		ra[i].Count = counts[i]
+		total.Count += counts[i]
+		total.Sum += ra[i].Sum
+	}
+
+	for _, src := range []struct {
+		total   Bucket
+		buckets []Bucket
+	}{{ta, ba}, {tb, bb}} {
+		if len(src.buckets) > 0 {
+			total.Min = math.Min(total.Min, src.total.Min)
+			total.Max = math.Max(total.Max, src.total.Max)
+		}
+	}
+
+	buckets := collapse(ra)
+	if len(buckets) == 0 {
+		return res
+	}
+
+	res.mergeBuckets(total, buckets)
+
+	return res
+}
+
+func finiteWeight(w float64) float64 {
+	if w < 0 || math.IsNaN(w) || math.IsInf(w, 0) {
+		return 0
+	}
+
+	return w
+}
+
+// roundCounts rounds weights to integers with sum equal to rounded sum of weights,
+// largest fractional parts are rounded up.
+func roundCounts(weights []float64) []int {
+	counts := make([]int, len(weights))
+	order := make([]int, len(weights))
+	sum, floorSum := 0.0, 0
+
+	for i, w := range weights {
+		counts[i] = int(math.Floor(w))
+		order[i] = i
+		sum += w
+		floorSum += counts[i]
+	}
+
+	sort.SliceStable(order, func(i, j int) bool {
+		fi := weights[order[i]] - math.Floor(weights[order[i]])
+		fj := weights[order[j]] - math.Floor(weights[order[j]])
+
+		return fi > fj
+	})
+
+	rest := int(math.Round(sum)) - floorSum
+
+	for k := 0; k < rest && k < len(order); k++ {
+		counts[order[k]]++
+	}
+
+	return counts
+}
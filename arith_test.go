@@ -0,0 +1,89 @@
+package dynhist_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vearutop/dynhist-go"
+	"github.com/vearutop/dynhist-go/internal/dataset"
+)
+
+func TestCollector_ScaleCounts(t *testing.T) {
+	c := dynhist.Collector{BucketsLimit: 5}
+
+	for i := 0; i < 101; i++ {
+		c.Add(float64(i % 7))
+	}
+
+	sum := c.Sum
+	p50 := c.Percentile(50)
+
+	c.ScaleCounts(2)
+	assert.Equal(t, 202, c.Count)
+	assert.Equal(t, 2*sum, c.Sum)
+	assert.Equal(t, p50, c.Percentile(50))
+
+	c.ScaleCounts(1.0 / 3)
+	assert.Equal(t, 67, c.Count)
+
+	cnt := 0
+	for _, b := range c.Buckets {
+		cnt += b.Count
+	}
+
+	assert.Equal(t, c.Count, cnt)
+	assert.Len(t, c.Buckets, 5)
+
+	c.ScaleCounts(-1)
+	assert.Equal(t, 67, c.Count)
+}
+
+func TestWeightedMerge(t *testing.T) {
+	c := dynhist.Collector{BucketsLimit: 20}
+	r := dataset.New(1)
+
+	for i := 0; i < 10000; i++ {
+		c.Add(r.ExpFloat64())
+	}
+
+	m := dynhist.WeightedMerge(&c, &c, 0.5, 0.5)
+	assert.Equal(t, c.Count, m.Count)
+	assert.InDelta(t, c.Sum, m.Sum, 1e-6)
+
+	for _, p := range []float64{10, 50, 90, 99} {
+		assert.InDelta(t, c.Percentile(p), m.Percentile(p), 1e-9, p)
+	}
+
+	assert.Equal(t, 0, dynhist.WeightedMerge(&c, &c, 0, -1).Count)
+}
+
+func TestWeightedMerge_sampled(t *testing.T) {
+	full := dynhist.Collector{BucketsLimit: 30}
+	sampled := dynhist.Collector{BucketsLimit: 30}
+	expected := dynhist.Collector{BucketsLimit: 30}
+	r := dataset.New(2)
+
+	for i := 0; i < 20000; i++ {
+		v := r.ExpFloat64()
+		full.Add(v)
+		expected.Add(v)
+	}
+
+	for i := 0; i < 200000; i++ {
+		v := r.ExpFloat64()
+		expected.Add(v)
+
+		if i%100 == 0 {
+			sampled.Add(v)
+		}
+	}
+
+	m := dynhist.WeightedMerge(&full, &sampled, 1, 100)
+	assert.Equal(t, 220000, m.Count)
+	assert.Equal(t, 30, m.BucketsLimit)
+
+	for _, p := range []float64{25, 50, 75, 90} {
+		e := expected.Percentile(p)
+		assert.InDelta(t, e, m.Percentile(p), 0.1*e, p)
+	}
+}
@@ -0,0 +1,57 @@
+package dynhist
+
+// autoLimitFactor defines hard limit of buckets for AutoLimit relative to BucketsLimit.
+const autoLimitFactor = 10
+
+// mergeAuto merges buckets over the limit while merges satisfy AutoLimit constraints.
+func (c *Collector) mergeAuto(limit int) {
+	hardLimit := autoLimitFactor * limit
+	if hardLimit > MaxBucketsHardLimit {
+		hardLimit = MaxBucketsHardLimit
+	}
+
+	for len(c.Buckets) > limit {
+		mergePoint, forced := c.mergePoint()
+
+		if !c.mergeAllowed(mergePoint) {
+			if len(c.Buckets) <= hardLimit {
+				return
+			}
+
+			forced = true
+		}
+
+		if forced {
+			c.LimitPressure++
+		}
+
+		c.mergeAt(mergePoint)
+	}
+}
+
+func (c *Collector) mergeAllowed(mergePoint int) bool {
+	b1 := c.Buckets[mergePoint-1]
+	b2 := c.Buckets[mergePoint]
+
+	if c.MaxBucketShare > 0 && float64(b1.Count+b2.Count) > c.MaxBucketShare*float64(c.Count) {
+		return false
+	}
+
+	if c.MaxRelativeWidth > 0 && c.Max > c.Min && (b2.Max-b1.Min)/(c.Max-c.Min) > c.MaxRelativeWidth {
+		return false
+	}
+
+	return true
+}
+
+// BucketsCount returns current number of buckets.
+func (c *Collector) BucketsCount() int {
+	if c == nil {
+		return 0
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	return len(c.Buckets)
+}
@@ -0,0 +1,61 @@
+package dynhist_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vearutop/dynhist-go"
+	"github.com/vearutop/dynhist-go/internal/dataset"
+)
+
+func TestCollector_AutoLimit(t *testing.T) {
+	build := func(gen func(r *dataset.Source) float64) *dynhist.Collector {
+		c := &dynhist.Collector{
+			BucketsLimit:     10,
+			AutoLimit:        true,
+			MaxBucketShare:   0.1,
+			MaxRelativeWidth: 0.05,
+		}
+		r := dataset.New(1)
+
+		for i := 0; i < 10000; i++ {
+			c.Add(gen(r))
+		}
+
+		return c
+	}
+
+	unimodal := build(func(r *dataset.Source) float64 {
+		return r.NormFloat64()*10 + 100
+	})
+
+	multimodal := build(func(r *dataset.Source) float64 {
+		return r.NormFloat64() + float64(r.Intn(5))*10 + 80
+	})
+
+	assert.Greater(t, unimodal.BucketsCount(), 10)
+	assert.Greater(t, multimodal.BucketsCount(), unimodal.BucketsCount())
+	assert.LessOrEqual(t, multimodal.BucketsCount(), 100)
+
+	for _, c := range []*dynhist.Collector{unimodal, multimodal} {
+		for _, b := range c.Buckets {
+			if b.Min != b.Max {
+				assert.LessOrEqual(t, float64(b.Count), 0.1*float64(c.Count)+1, b)
+			}
+		}
+	}
+}
+
+func TestCollector_AutoLimit_hardLimit(t *testing.T) {
+	c := dynhist.Collector{
+		BucketsLimit:   3,
+		AutoLimit:      true,
+		MaxBucketShare: 0.0001,
+	}
+
+	for i := 0; i < 1000; i++ {
+		c.Add(float64(i))
+	}
+
+	assert.Equal(t, 30, c.BucketsCount())
+}
@@ -0,0 +1,196 @@
+package dynhist
+
+// BarRow describes a bucket as rendered by Render.
+type BarRow struct {
+	Bucket
+
+	// MinText and MaxText are formatted boundaries.
+	MinText, MaxText string
+
+	// HumanMin and HumanMax are boundaries formatted with ValueFormatter if DualUnits is enabled.
+	HumanMin, HumanMax string
+
+	// Percent is a share of bucket according to Weighting.
+	Percent float64
+
+	// Bar is a length of bar in characters.
+	Bar int
+
+	// Folded is a number of buckets combined in an open-ended row by FoldOutliers, 0 for regular rows.
+	Folded int
+
+	// Label is a label of bucket set with Annotate.
+	Label string
+}
+
+// BarData returns buckets with formatting and scaling decisions of Render.
+//
+// Bar length is scaled so that 100% takes maxWidth characters, non-positive maxWidth means 100
+// which is used by Render. Buckets omitted due to MaxRows are not included.
+func (c *Collector) BarData(maxWidth int, opts RenderOptions) []BarRow {
+	if c == nil {
+		return nil
+	}
+
+	state := c.renderState(nil)
+	buckets, labels := state.buckets, state.labels
+
+	rows, _, _ := barData(buckets, maxWidth, opts)
+	rowLabels(rows, buckets, labels)
+
+	return rows
+}
+
+// barData returns rows of rendered buckets, number of omitted buckets and their share in percents.
+func barData(buckets []Bucket, maxWidth int, opts RenderOptions) (rows []BarRow, omitted int, omittedPercent float64) {
+	if len(buckets) == 0 {
+		return nil, 0, 0
+	}
+
+	if maxWidth <= 0 {
+		maxWidth = 100
+	}
+
+	totalWeight := opts.Weighting.total(buckets)
+
+	buckets, lower, upper := foldOutliers(buckets, opts.FoldOutliers)
+	buckets, dropped := topRows(buckets, opts.MaxRows)
+	mainFormat, humanFormat := opts.boundsFormats()
+
+	if totalWeight > 0 {
+		omittedPercent = 100 * opts.Weighting.total(dropped) / totalWeight
+	}
+
+	baseWeight := totalWeight
+	if opts.PercentBase == PercentOfDisplayed {
+		baseWeight = opts.Weighting.total(buckets)
+	}
+
+	scale := func(b Bucket, width float64) float64 {
+		if baseWeight == 0 {
+			return 0
+		}
+
+		return width * opts.Weighting.weigh(b) / baseWeight
+	}
+
+	rows = make([]BarRow, 0, len(buckets))
+
+	for _, b := range buckets {
+		r := BarRow{
+			Bucket:  b,
+			MinText: mainFormat(b.Min),
+			MaxText: mainFormat(b.Max),
+			Percent: scale(b, 100),
+			Bar:     int(scale(b, float64(maxWidth))),
+		}
+
+		if humanFormat != nil {
+			r.HumanMin = humanFormat(b.Min)
+			r.HumanMax = humanFormat(b.Max)
+		}
+
+		rows = append(rows, r)
+	}
+
+	lower.apply(&rows[0], "<", mainFormat, humanFormat)
+	upper.apply(&rows[len(rows)-1], ">", mainFormat, humanFormat)
+
+	return rows, len(dropped), omittedPercent
+}
+
+// boundsFormats returns formatters of boundaries and of secondary human-readable boundaries.
+func (opts RenderOptions) boundsFormats() (main, human func(v float64) string) {
+	if opts.ValueFormatter == nil {
+		return opts.decimal(formatFixed), nil
+	}
+
+	if opts.DualUnits {
+		return opts.decimal(formatRaw), opts.ValueFormatter
+	}
+
+	return opts.ValueFormatter, nil
+}
+
+// fold describes edge buckets combined by FoldOutliers.
+type fold struct {
+	n     int
+	bound float64 // Boundary of the nearest regular bucket.
+}
+
+// apply replaces boundaries of a row with an open-ended boundary.
+func (f fold) apply(r *BarRow, sign string, mainFormat, humanFormat func(v float64) string) {
+	if f.n == 0 {
+		return
+	}
+
+	r.Folded = f.n
+	r.MinText = sign
+	r.MaxText = mainFormat(f.bound)
+
+	if humanFormat != nil {
+		r.HumanMin = sign
+		r.HumanMax = humanFormat(f.bound)
+	}
+}
+
+// foldOutliers combines edge buckets with cumulative share of count below share.
+//
+// Buckets are returned unchanged if nothing is left between combined edges.
+func foldOutliers(buckets []Bucket, share float64) (res []Bucket, lower, upper fold) {
+	if share <= 0 {
+		return buckets, fold{}, fold{}
+	}
+
+	total := 0
+
+	for _, b := range buckets {
+		total += b.Count
+	}
+
+	limit := share * float64(total)
+
+	lo, cnt := 0, 0
+	for lo < len(buckets) && float64(cnt+buckets[lo].Count) < limit {
+		cnt += buckets[lo].Count
+		lo++
+	}
+
+	hi, cnt := len(buckets), 0
+	for hi > lo && float64(cnt+buckets[hi-1].Count) < limit {
+		cnt += buckets[hi-1].Count
+		hi--
+	}
+
+	if hi == lo || (lo == 0 && hi == len(buckets)) {
+		return buckets, fold{}, fold{}
+	}
+
+	res = make([]Bucket, 0, hi-lo+2)
+
+	if lo > 0 {
+		res = append(res, combine(buckets[:lo]))
+		lower = fold{n: lo, bound: buckets[lo].Min}
+	}
+
+	res = append(res, buckets[lo:hi]...)
+
+	if hi < len(buckets) {
+		res = append(res, combine(buckets[hi:]))
+		upper = fold{n: len(buckets) - hi, bound: buckets[hi-1].Max}
+	}
+
+	return res, lower, upper
+}
+
+// combine returns a bucket that spans buckets.
+func combine(buckets []Bucket) Bucket {
+	res := Bucket{Min: buckets[0].Min, Max: buckets[len(buckets)-1].Max}
+
+	for _, b := range buckets {
+		res.Count += b.Count
+		res.Sum += b.Sum
+	}
+
+	return res
+}
@@ -0,0 +1,81 @@
+package dynhist_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vearutop/dynhist-go"
+	"github.com/vearutop/dynhist-go/internal/dataset"
+)
+
+func TestCollector_BarData(t *testing.T) {
+	r := dataset.New(1)
+
+	for k := 0; k < 50; k++ {
+		c := randomCollector(r)
+		rows := c.BarData(0, dynhist.RenderOptions{})
+
+		// Trivial renderer over bar data.
+		bLen, cLen, pLen := 0, len(fmt.Sprintf("%d", c.Count)), 5
+
+		for _, row := range rows {
+			if l := len(row.MinText); l > bLen {
+				bLen = l
+			}
+
+			if l := len(row.MaxText); l > bLen {
+				bLen = l
+			}
+
+			if l := len(fmt.Sprintf("%.2f", row.Percent)); l > pLen {
+				pLen = l
+			}
+		}
+
+		var res strings.Builder
+
+		fmt.Fprintf(&res, "[%*s %*s] %*s %*s (%d events)\n", bLen, "min", bLen, "max", cLen, "cnt", pLen+1, "total%", c.Count)
+
+		for _, row := range rows {
+			fmt.Fprintf(&res, "[%*s %*s] %*d %*.2f%%", bLen, row.MinText, bLen, row.MaxText, cLen, row.Count, pLen, row.Percent)
+
+			if row.Bar > 0 {
+				res.WriteString(" " + strings.Repeat(".", row.Bar))
+			}
+
+			res.WriteString("\n")
+		}
+
+		assert.Equal(t, c.String(), res.String())
+	}
+}
+
+func TestCollector_BarData_maxWidth(t *testing.T) {
+	c := dynhist.Collector{BucketsLimit: 3}
+
+	for i := 0; i < 10; i++ {
+		c.Add(float64(i))
+	}
+
+	rows := c.BarData(20, dynhist.RenderOptions{ValueFormatter: dynhist.DurationFormatter(1e6), DualUnits: true})
+
+	assert.Equal(t, []dynhist.BarRow{
+		{
+			Bucket:  dynhist.Bucket{Min: 0, Max: 3, Count: 4, Sum: 6},
+			MinText: "0", MaxText: "3", HumanMin: "0s", HumanMax: "3ms",
+			Percent: 40, Bar: 8,
+		},
+		{
+			Bucket:  dynhist.Bucket{Min: 4, Max: 6, Count: 3, Sum: 15},
+			MinText: "4", MaxText: "6", HumanMin: "4ms", HumanMax: "6ms",
+			Percent: 30, Bar: 6,
+		},
+		{
+			Bucket:  dynhist.Bucket{Min: 7, Max: 9, Count: 3, Sum: 24},
+			MinText: "7", MaxText: "9", HumanMin: "7ms", HumanMax: "9ms",
+			Percent: 30, Bar: 6,
+		},
+	}, rows)
+}
@@ -0,0 +1,42 @@
+package dynhist
+
+import "time"
+
+// now is a replaceable clock for tests.
+var now = time.Now
+
+// renderCache keeps the last result of CachedString.
+type renderCache struct {
+	valid      bool
+	text       string
+	generation uint64
+	at         time.Time
+}
+
+// CachedString returns a memoized result of String.
+//
+// Cached text is returned if collected data has not changed since rendering or if it is younger than maxAge,
+// otherwise text is rendered again. Changes of configuration fields (for example PrintSum) and direct
+// modifications of Buckets are not detected, such changes are visible after maxAge.
+func (c *Collector) CachedString(maxAge time.Duration) string {
+	if c == nil {
+		return ""
+	}
+
+	c.Lock()
+	cache, generation := c.cache, c.generation
+	c.Unlock()
+
+	if cache.valid && (cache.generation == generation || now().Sub(cache.at) < maxAge) {
+		return cache.text
+	}
+
+	// Formatting happens outside of the lock, data changed meanwhile makes cache stale for the next call.
+	text := c.String()
+
+	c.Lock()
+	c.cache = renderCache{valid: true, text: text, generation: generation, at: now()}
+	c.Unlock()
+
+	return text
+}
@@ -0,0 +1,95 @@
+package dynhist_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vearutop/dynhist-go"
+)
+
+func TestCollector_CachedString(t *testing.T) {
+	ts := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	defer dynhist.SetNow(func() time.Time { return ts })()
+
+	c := dynhist.Collector{}
+	c.Add(1)
+
+	s := c.CachedString(time.Second)
+	assert.Equal(t, c.String(), s)
+
+	// Unchanged data is served from cache regardless of age.
+	ts = ts.Add(time.Hour)
+	c.PrintSum = true
+	assert.Equal(t, s, c.CachedString(time.Second))
+
+	// Changed data is rendered again when cache is older than maxAge.
+	c.PrintSum = false
+	c.Add(2)
+	s = c.CachedString(time.Second)
+	assert.Contains(t, s, "(2 events)")
+
+	// Changed data is served from cache while it is younger than maxAge.
+	c.Add(3)
+	ts = ts.Add(500 * time.Millisecond)
+	assert.Equal(t, s, c.CachedString(time.Second))
+
+	ts = ts.Add(500 * time.Millisecond)
+	s = c.CachedString(time.Second)
+	assert.Contains(t, s, "(3 events)")
+	assert.Equal(t, c.String(), s)
+
+	// Zero maxAge renders on every change.
+	c.Reset()
+	assert.Equal(t, "", c.CachedString(0))
+}
+
+func BenchmarkCollector_CachedString(b *testing.B) {
+	for _, tc := range []struct {
+		name string
+		f    func(c *dynhist.Collector) string
+	}{
+		{name: "String", f: (*dynhist.Collector).String},
+		{name: "CachedString", f: func(c *dynhist.Collector) string { return c.CachedString(100 * time.Millisecond) }},
+	} {
+		tc := tc
+
+		b.Run(tc.name, func(b *testing.B) {
+			c := dynhist.Collector{}
+
+			for i := 0; i < 1000; i++ {
+				c.Add(float64(i))
+			}
+
+			done := make(chan struct{})
+			stopped := make(chan struct{})
+
+			// Concurrent writer keeps changing data.
+			go func() {
+				defer close(stopped)
+
+				for i := 0; ; i++ {
+					select {
+					case <-done:
+						return
+					default:
+						c.Add(float64(i % 1000))
+					}
+				}
+			}()
+
+			b.ReportAllocs()
+			b.ResetTimer()
+
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					_ = tc.f(&c)
+				}
+			})
+
+			b.StopTimer()
+			close(done)
+			<-stopped
+		})
+	}
+}
@@ -0,0 +1,256 @@
+package dynhist
+
+import "math"
+
+// cdf is a piecewise-linear model of cumulative distribution built from sorted buckets.
+//
+// Values are assumed to be uniformly spread within a bucket of non-zero width,
+// so cumulative count grows linearly from Min to Max. A zero-width bucket is a point mass,
+// cumulative count jumps by bucket count at its value. Gaps between buckets have no values,
+// cumulative count is flat there.
+type cdf []Bucket
+
+// cdfEpsilon is a relative tolerance of target count, it absorbs rounding of percent conversions,
+// so that a target at the end of a bucket does not fall into the next bucket.
+const cdfEpsilon = 1e-12
+
+// count returns cumulative count of values less than or equal to x.
+func (m cdf) count(x float64) float64 {
+	cum := 0.0
+
+	for _, b := range m {
+		if x < b.Min {
+			break
+		}
+
+		if x >= b.Max {
+			cum += float64(b.Count)
+
+			continue
+		}
+
+		return cum + float64(b.Count)*(x-b.Min)/(b.Max-b.Min)
+	}
+
+	return cum
+}
+
+// value returns the smallest value with cumulative count of at least target.
+//
+// Value is interpolated within a bucket, point mass value is returned for a zero-width bucket.
+// Values beyond total count are clamped to the largest boundary.
+func (m cdf) value(target float64) float64 {
+	cur := cdfCursor{m: m}
+
+	return cur.value(target)
+}
+
+// sum returns cumulative sum of values less than or equal to x.
+//
+// Bucket sum is pro-rated by width like count, the mean of a clipped part is shifted
+// to its middle, as values are assumed uniform within bucket.
+func (m cdf) sum(x float64) float64 {
+	cum := 0.0
+
+	for _, b := range m {
+		if x < b.Min {
+			break
+		}
+
+		if x >= b.Max {
+			cum += b.Sum
+
+			continue
+		}
+
+		frac := (x - b.Min) / (b.Max - b.Min)
+
+		return cum + frac*(b.Sum+float64(b.Count)*(x-b.Max)/2)
+	}
+
+	return cum
+}
+
+// mirror returns the model of negated values, so that walking it from the bottom
+// walks the original distribution from the top.
+func (m cdf) mirror() cdf {
+	res := make(cdf, len(m))
+
+	for i, b := range m {
+		res[len(m)-1-i] = Bucket{Min: -b.Max, Max: -b.Min, Count: b.Count, Sum: -b.Sum}
+	}
+
+	return res
+}
+
+// PercentileFromMax returns the value above which the largest percent (0-100) of values lie.
+//
+// It walks buckets from the maximum with the same interpolation as Percentile, so that
+// PercentileFromMax(p) equals Percentile(100-p) within buckets. Unlike the subtraction,
+// it is not affected by rounding of 100-p, and for a target at a gap between buckets
+// it returns the upper boundary of the gap (the lowest of the largest values),
+// while Percentile returns the lower one. Maximum is returned for 0, minimum for 100.
+func (c *Collector) PercentileFromMax(percent float64) float64 {
+	if c == nil {
+		return math.NaN()
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	if c.Count == 0 {
+		return c.Transform.inv(0)
+	}
+
+	return c.Transform.inv(-cdf(c.Buckets).mirror().value(percent * float64(c.Count) / 100))
+}
+
+// SumAbove returns the sum of values greater than or equal to v.
+//
+// Buckets crossing v are pro-rated like in Range assuming uniform distribution within bucket,
+// so that SumAbove(v) and the sum of Range(Min, v) add up to total sum, except for
+// a point mass at v that both of them include. It returns 0 if there are no values above v.
+func (c *Collector) SumAbove(v float64) float64 {
+	_, buckets := c.snapshotBuckets()
+
+	return -cdf(buckets).mirror().sum(-v)
+}
+
+// cdfCursor finds values of non-decreasing targets in a single pass over buckets.
+type cdfCursor struct {
+	m   cdf
+	i   int     // Index of current bucket.
+	cum float64 // Cumulative count before current bucket.
+}
+
+// value returns the same result as cdf.value, target must not be less than in a previous call.
+func (cur *cdfCursor) value(target float64) float64 {
+	m := cur.m
+
+	if len(m) == 0 {
+		return 0
+	}
+
+	// Tolerance only selects the bucket, value is interpolated at exact target.
+	adj := target - cdfEpsilon*target
+
+	for ; cur.i < len(m); cur.i++ {
+		b := m[cur.i]
+		n := float64(b.Count)
+
+		if cur.cum+n >= adj && (n > 0 || adj <= 0) {
+			if b.Min == b.Max || n == 0 {
+				return b.Min
+			}
+
+			f := math.Min(math.Max((target-cur.cum)/n, 0), 1)
+
+			return b.Min + f*(b.Max-b.Min)
+		}
+
+		cur.cum += n
+	}
+
+	return m[len(m)-1].Max
+}
+
+// QuantilePoint is a point of quantile function, V is a value at percentile P (0-100).
+type QuantilePoint struct {
+	P float64
+	V float64
+}
+
+// QuantileCurve returns points of quantile function for plotting, it is consistent with Percentile.
+//
+// If points is positive, the function is sampled at evenly spaced percentiles from 0 to 100
+// (at least 2 points) in a single pass over buckets. Otherwise knots of the piecewise-linear
+// function are returned, start and end of every non-empty bucket, so that a gap between buckets
+// is a pair of knots with the same P and a zero-width bucket is a pair of knots with the same V.
+// Nil is returned if there are no values.
+func (c *Collector) QuantileCurve(points int) []QuantilePoint {
+	if c == nil {
+		return nil
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	if c.Count == 0 {
+		return nil
+	}
+
+	t := c.Transform
+	total := float64(c.Count)
+
+	if points <= 0 {
+		res := make([]QuantilePoint, 0, 2*len(c.Buckets))
+		cum := 0.0
+
+		for _, b := range c.Buckets {
+			if b.Count == 0 {
+				continue
+			}
+
+			res = append(res, QuantilePoint{P: 100 * cum / total, V: t.inv(b.Min)})
+			cum += float64(b.Count)
+			res = append(res, QuantilePoint{P: 100 * cum / total, V: t.inv(b.Max)})
+		}
+
+		return res
+	}
+
+	if points < 2 {
+		points = 2
+	}
+
+	res := make([]QuantilePoint, points)
+	cur := cdfCursor{m: c.Buckets}
+
+	for k := range res {
+		p := 100 * float64(k) / float64(points-1)
+		res[k] = QuantilePoint{P: p, V: t.inv(cur.value(p * total / 100))}
+	}
+
+	res[points-1].V = t.inv(c.Max)
+
+	return res
+}
+
+// CumulativeCount is a number of values not greater than LE.
+type CumulativeCount struct {
+	LE    float64
+	Count int
+}
+
+// CumulativeCounts returns cumulative counts at upper boundaries of buckets in ascending order,
+// like "le" buckets of Prometheus.
+//
+// The last entry is at the largest value (Max) with total count, there is no +Inf entry.
+// Nil is returned if there are no values.
+func (c *Collector) CumulativeCounts() []CumulativeCount {
+	if c == nil {
+		return nil
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	return cumulativeCounts(c.Buckets, c.Transform)
+}
+
+// cumulativeCounts returns cumulative counts of buckets with boundaries converted by t.
+func cumulativeCounts(buckets []Bucket, t *Transform) []CumulativeCount {
+	if len(buckets) == 0 {
+		return nil
+	}
+
+	res := make([]CumulativeCount, len(buckets))
+	cnt := 0
+
+	for i, b := range buckets {
+		cnt += b.Count
+		res[i] = CumulativeCount{LE: t.inv(b.Max), Count: cnt}
+	}
+
+	return res
+}
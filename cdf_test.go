@@ -0,0 +1,261 @@
+package dynhist_test
+
+import (
+	"math"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vearutop/dynhist-go"
+	"github.com/vearutop/dynhist-go/internal/dataset"
+)
+
+func TestCollector_Rank(t *testing.T) {
+	c := dynhist.Collector{}
+
+	assert.Equal(t, 0.0, c.Rank(1))
+
+	for _, v := range []float64{1, 2, 3, 4, 5, 5, 5, 5, 10, 20} {
+		c.Add(v)
+	}
+
+	c.Buckets = []dynhist.Bucket{
+		{Min: 1, Max: 4, Count: 4, Sum: 10},
+		{Min: 5, Max: 5, Count: 4, Sum: 20},
+		{Min: 10, Max: 20, Count: 2, Sum: 30},
+	}
+
+	for _, tc := range []struct {
+		v, rank float64
+	}{
+		{v: 0, rank: 0},
+		{v: 1, rank: 0},
+		{v: 2.5, rank: 20},
+		{v: 4, rank: 40},
+		{v: 4.5, rank: 40}, // Gap.
+		{v: 5, rank: 80},   // Point mass.
+		{v: 15, rank: 90},
+		{v: 25, rank: 100},
+	} {
+		assert.InDelta(t, tc.rank, c.Rank(tc.v), 1e-9, tc.v)
+	}
+
+	for _, tc := range []struct {
+		p, v float64
+	}{
+		{p: 0, v: 1},
+		{p: 20, v: 2.5},
+		{p: 40, v: 4},
+		{p: 50, v: 5},
+		{p: 80, v: 5},
+		{p: 90, v: 15},
+		{p: 100, v: 20},
+	} {
+		assert.InDelta(t, tc.v, c.Percentile(tc.p), 1e-9, tc.p)
+	}
+}
+
+func TestCollector_Rank_roundTrip(t *testing.T) {
+	r := dataset.New(1)
+
+	for k := 0; k < 100; k++ {
+		c := randomCollector(r)
+		if k%3 == 0 {
+			c = &dynhist.Collector{BucketsLimit: c.BucketsLimit, Transform: dynhist.SqrtTransform}
+
+			for i := 0; i < 1000; i++ {
+				c.Add(r.ExpFloat64() * 100)
+			}
+		}
+
+		rows := c.BarData(0, dynhist.RenderOptions{})
+
+		for i := 0; i < 100; i++ {
+			v := c.MinValue() + r.Float64()*(c.MaxValue()-c.MinValue())
+			j := sort.Search(len(rows), func(j int) bool { return rows[j].Max >= v })
+
+			// Identity within buckets, lower boundary of a gap between buckets.
+			expected := v
+			if rows[j].Min > v {
+				expected = rows[j-1].Max
+			}
+
+			assert.InDelta(t, expected, c.Percentile(c.Rank(v)), 1e-9*math.Max(1, math.Abs(v)), "%d %v", k, v)
+		}
+
+		for i := 0; i < 100; i++ {
+			p := 100 * r.Float64()
+			v := c.Percentile(p)
+			j := sort.Search(len(rows), func(j int) bool { return rows[j].Max >= v })
+
+			// Identity except for a point mass, rank of its value includes the whole bucket.
+			tolerance := 1e-9
+			if rows[j].Min == rows[j].Max {
+				tolerance += rows[j].Percent
+			}
+
+			rank := c.Rank(v)
+			assert.GreaterOrEqual(t, rank, p-1e-9, "%d %v", k, p)
+			assert.LessOrEqual(t, rank, p+tolerance, "%d %v", k, p)
+		}
+	}
+}
+
+func TestCollector_QuantileCurve(t *testing.T) {
+	c := &dynhist.Collector{}
+	assert.Nil(t, c.QuantileCurve(10))
+	assert.Nil(t, c.QuantileCurve(0))
+
+	r := dataset.New(1)
+
+	for k := 0; k < 30; k++ {
+		c := randomCollector(r)
+		if k%3 == 0 {
+			c = &dynhist.Collector{BucketsLimit: c.BucketsLimit, Transform: dynhist.SqrtTransform}
+
+			for i := 0; i < 1000; i++ {
+				c.Add(r.ExpFloat64() * 100)
+			}
+		}
+
+		curve := c.QuantileCurve(101)
+		assert.Len(t, curve, 101)
+		assert.Equal(t, 0.0, curve[0].P)
+		assert.Equal(t, 100.0, curve[100].P)
+		assert.InDelta(t, c.MinValue(), curve[0].V, 1e-9*math.Max(1, math.Abs(c.MinValue())), k)
+		assert.Equal(t, c.MaxValue(), curve[100].V, k)
+		assert.Equal(t, c.MaxValue(), c.Percentile(100), k)
+
+		for i, p := range curve {
+			assert.Equal(t, c.Percentile(p.P), p.V, "%d %v", k, p.P)
+
+			if i > 0 {
+				assert.Greater(t, p.P, curve[i-1].P)
+				assert.GreaterOrEqual(t, p.V, curve[i-1].V)
+			}
+		}
+
+		knots := c.QuantileCurve(0)
+		assert.Equal(t, 0.0, knots[0].P)
+		assert.InDelta(t, 100, knots[len(knots)-1].P, 1e-9)
+
+		for i, p := range knots {
+			if i > 0 {
+				assert.GreaterOrEqual(t, p.P, knots[i-1].P)
+				assert.GreaterOrEqual(t, p.V, knots[i-1].V)
+			}
+
+			// Knots are on the interpolated curve, a point mass or a gap has a vertical segment.
+			if i%2 == 1 && p.P < 100-1e-9 {
+				assert.InDelta(t, p.V, c.Percentile(p.P), 1e-9*math.Max(1, math.Abs(p.V)), "%d %v", k, p.P)
+			}
+		}
+	}
+
+	// Maximum is exact despite rounding of transformed values.
+	sq := dynhist.Collector{BucketsLimit: 10, Transform: dynhist.SqrtTransform}
+
+	for i := 1; i <= 99; i++ {
+		sq.Add(float64(i * i))
+	}
+
+	assert.Equal(t, 9801.0, sq.Percentile(100))
+	assert.Equal(t, 9801.0, sq.QuantileCurve(11)[10].V)
+
+	c.Add(5)
+	assert.Equal(t, []dynhist.QuantilePoint{{P: 0, V: 5}, {P: 100, V: 5}}, c.QuantileCurve(1))
+	assert.Equal(t, []dynhist.QuantilePoint{{P: 0, V: 5}, {P: 100, V: 5}}, c.QuantileCurve(0))
+}
+
+func TestCollector_CumulativeCounts(t *testing.T) {
+	c := &dynhist.Collector{}
+	assert.Nil(t, c.CumulativeCounts())
+
+	r := dataset.New(1)
+
+	for k := 0; k < 30; k++ {
+		c := randomCollector(r)
+		if k%3 == 0 {
+			c = &dynhist.Collector{BucketsLimit: c.BucketsLimit, Transform: dynhist.SqrtTransform}
+
+			for i := 0; i < 1000; i++ {
+				c.Add(r.ExpFloat64() * 100)
+			}
+		}
+
+		cc := c.CumulativeCounts()
+		assert.Len(t, cc, len(c.Buckets))
+		assert.Equal(t, c.TotalCount(), cc[len(cc)-1].Count, k)
+		assert.Equal(t, c.MaxValue(), cc[len(cc)-1].LE, k)
+
+		for i, e := range cc {
+			if i > 0 {
+				assert.Greater(t, e.LE, cc[i-1].LE, k)
+				assert.GreaterOrEqual(t, e.Count, cc[i-1].Count, k)
+			}
+
+			assert.InDelta(t, float64(e.Count), c.Rank(e.LE)*float64(c.TotalCount())/100, 1e-6, "%d %v", k, e.LE)
+		}
+	}
+
+	c.Add(1)
+	c.Add(3)
+	c.Add(3)
+	assert.Equal(t, []dynhist.CumulativeCount{{LE: 1, Count: 1}, {LE: 3, Count: 3}}, c.CumulativeCounts())
+}
+
+func TestCollector_PercentileFromMax(t *testing.T) {
+	c := &dynhist.Collector{}
+	assert.NoError(t, c.LoadBuckets([]dynhist.Bucket{
+		{Min: 0, Max: 10, Count: 50, Sum: 250},
+		{Min: 20, Max: 30, Count: 50, Sum: 1250},
+	}))
+
+	assert.Equal(t, 30.0, c.PercentileFromMax(0))
+	assert.InDelta(t, 25.0, c.PercentileFromMax(25), 1e-9)
+	assert.InDelta(t, 0.0, c.PercentileFromMax(100), 1e-9)
+
+	// Largest half of values starts at the upper boundary of the gap.
+	assert.InDelta(t, 20.0, c.PercentileFromMax(50), 1e-9)
+	assert.InDelta(t, 10.0, c.Percentile(50), 1e-9)
+
+	assert.Equal(t, 1500.0, c.SumAbove(0))
+	assert.Equal(t, 1250.0, c.SumAbove(15))
+	assert.InDelta(t, 687.5, c.SumAbove(25), 1e-9)
+	assert.Equal(t, 0.0, c.SumAbove(31))
+
+	assert.Equal(t, 0.0, (&dynhist.Collector{}).PercentileFromMax(50))
+	assert.Equal(t, 0.0, (&dynhist.Collector{}).SumAbove(1))
+}
+
+func TestCollector_PercentileFromMax_consistency(t *testing.T) {
+	r := dataset.New(1)
+
+	for k := 0; k < 200; k++ {
+		c := randomCollector(r)
+
+		for _, p := range []float64{0, 0.1, 1, 5, 10, 25, 50, 75, 90, 99, 100} {
+			top := c.PercentileFromMax(p)
+			bottom := c.Percentile(100 - p)
+			tol := 1e-9 * math.Max(1, math.Abs(top))
+
+			assert.GreaterOrEqual(t, top, bottom-tol, "p %v", p)
+
+			if top-bottom > tol {
+				// Results differ only at a gap, there are no values between them.
+				assert.InDelta(t, c.Rank(bottom), c.Rank((top+bottom)/2), 1e-9, "p %v", p)
+			}
+		}
+
+		assert.InDelta(t, c.Sum, c.SumAbove(c.Min), 1e-9*math.Abs(c.Sum))
+		assert.Equal(t, 0.0, c.SumAbove(c.Max+1))
+
+		for i := 0; i < 20; i++ {
+			v := c.Min + (c.Max-c.Min)*(float64(i)+r.Float64())/20
+			above := c.SumAbove(v)
+			_, below := c.Range(c.Min, v)
+
+			assert.InDelta(t, c.Sum, above+below.Sum, 1e-9*math.Abs(c.Sum), "v %v", v)
+		}
+	}
+}
@@ -0,0 +1,23 @@
+package dynhist
+
+// AddClassified collects value like Add and returns index of the bucket that received the value.
+//
+// Index refers to buckets after merging that could be triggered by the value, isNewMax is true
+// if value extends the maximum or is the first value. Value is compared with boundaries after
+// Quantize and Transform, a value within Epsilon tolerance of the maximum does not extend it.
+// It returns -1 and false for a nil collector.
+func (c *Collector) AddClassified(v float64) (bucketIndex int, isNewMax bool) {
+	if c == nil {
+		return -1, false
+	}
+
+	c.Lock()
+	defer c.unlockAndNotify()
+
+	empty, max := c.Count == 0, c.Max
+
+	c.add(v)
+	c.mergeOverLimit()
+
+	return c.placed, empty || c.Max > max
+}
@@ -0,0 +1,51 @@
+package dynhist_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vearutop/dynhist-go"
+)
+
+func TestCollector_AddClassified(t *testing.T) {
+	c := dynhist.Collector{BucketsLimit: 3}
+
+	for _, tc := range []struct {
+		name   string
+		v      float64
+		idx    int
+		newMax bool
+	}{
+		{name: "first", v: 5, idx: 0, newMax: true},
+		{name: "new max", v: 10, idx: 1, newMax: true},
+		{name: "new min", v: 1, idx: 0},
+		{name: "in bucket", v: 10, idx: 2},
+		{name: "gap insert, merge", v: 7, idx: 1},             // [1] [5 7] [10]
+		{name: "new max, merge", v: 20, idx: 2, newMax: true}, // [1] [5 10] [20]
+		{name: "in merged bucket", v: 6, idx: 1},
+	} {
+		idx, newMax := c.AddClassified(tc.v)
+		assert.Equal(t, tc.idx, idx, tc.name)
+		assert.Equal(t, tc.newMax, newMax, tc.name)
+		assert.LessOrEqual(t, c.Buckets[idx].Min, tc.v, tc.name)
+		assert.GreaterOrEqual(t, c.Buckets[idx].Max, tc.v, tc.name)
+	}
+}
+
+func TestCollector_AddClassified_tolerance(t *testing.T) {
+	c := &dynhist.Collector{Epsilon: 0.1}
+	c.Add(1)
+	c.Add(2)
+
+	idx, newMax := c.AddClassified(2.05)
+	assert.Equal(t, 1, idx)
+	assert.False(t, newMax)
+
+	q := &dynhist.Collector{Quantize: 1}
+	q.Add(1)
+
+	idx, newMax = q.AddClassified(1.4)
+	assert.Equal(t, 0, idx)
+	assert.False(t, newMax)
+	assert.Equal(t, 1, q.BucketsCount())
+}
@@ -0,0 +1,97 @@
+package dynhist
+
+import "time"
+
+// Clone returns an independent deep copy of collector configuration and data.
+//
+// Sinks and callbacks (RawWriter, Trace, OnMerge, WatchPercentile watchers) are not cloned,
+// so that the clone does not interleave output or notifications with the original.
+func (c *Collector) Clone() *Collector {
+	if c == nil {
+		return nil
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	res := &Collector{
+		Name:             c.Name,
+		Unit:             c.Unit,
+		Help:             c.Help,
+		BucketsLimit:     c.BucketsLimit,
+		Bucket:           c.Bucket,
+		Buckets:          append([]Bucket(nil), c.Buckets...),
+		PrintSum:         c.PrintSum,
+		PrintGaps:        c.PrintGaps,
+		SumEstimated:     c.SumEstimated,
+		ValueFormatter:   c.ValueFormatter,
+		WeightFunc:       c.WeightFunc,
+		StrictWeights:    c.StrictWeights,
+		RawText:          c.RawText,
+		MaxLineLen:       c.MaxLineLen,
+		WarmupCount:      c.WarmupCount,
+		AutoLimit:        c.AutoLimit,
+		MaxBucketShare:   c.MaxBucketShare,
+		MaxRelativeWidth: c.MaxRelativeWidth,
+		Offset:           c.Offset,
+		PreserveSpikes:   c.PreserveSpikes,
+		TrackQuantiles:   append([]float64(nil), c.TrackQuantiles...),
+		Transform:        c.Transform,
+		Epsilon:          c.Epsilon,
+		EpsilonMode:      c.EpsilonMode,
+		Quantize:         c.Quantize,
+		SnapBoundaries:   c.SnapBoundaries,
+		quantiles:        append([]p2Quantile(nil), c.quantiles...),
+		KeepTopK:         c.KeepTopK,
+		top:              append([]float64(nil), c.top...),
+		topCount:         c.topCount,
+		rawPartial:       c.rawPartial,
+		thresholds:       append([]trackedThreshold(nil), c.thresholds...),
+		TrackIDs:         c.TrackIDs,
+		ids:              append([]uint64(nil), c.ids...),
+		lastID:           c.lastID,
+		labels:           append([]string(nil), c.labels...),
+		TrackFreshness:   c.TrackFreshness,
+		seen:             append([]int64(nil), c.seen...),
+		PrintPercentiles: append([]float64(nil), c.PrintPercentiles...),
+		StaleAfter:       c.StaleAfter,
+		Merges:           c.Merges,
+		LastMergeWidth:   c.LastMergeWidth,
+		MergedWidth:      c.MergedWidth,
+		LimitPressure:    c.LimitPressure,
+	}
+
+	if c.sources != nil {
+		res.sources = make(map[string]time.Time, len(c.sources))
+
+		for s, t := range c.sources {
+			res.sources[s] = t
+		}
+	}
+
+	if c.RawValues != nil {
+		res.RawValues = append(make([]float64, 0, len(c.RawValues)), c.RawValues...)
+	}
+
+	return res
+}
+
+// Downsample returns a clone of collector merged down to at most n buckets with WeightFunc.
+//
+// The collector itself is not changed, counts and sums are conserved exactly.
+// AutoLimit and WarmupCount are disabled in the result, so that it keeps n buckets.
+func (c *Collector) Downsample(n int) *Collector {
+	if c == nil {
+		return nil
+	}
+
+	res := c.Clone()
+	res.BucketsLimit = clampBucketsLimit(n)
+	res.AutoLimit = false
+	res.WarmupCount = 0
+
+	res.setDefaults()
+	res.mergeDown(res.BucketsLimit)
+
+	return res
+}
@@ -0,0 +1,94 @@
+package dynhist_test
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vearutop/dynhist-go"
+	"github.com/vearutop/dynhist-go/internal/dataset"
+)
+
+// TestCollector_noCopy makes sure go vet copylocks check reports value copies of Collector.
+func TestCollector_noCopy(t *testing.T) {
+	locker := reflect.TypeOf((*sync.Locker)(nil)).Elem()
+	typ := reflect.TypeOf(dynhist.Collector{})
+
+	found := false
+
+	for i := 0; i < typ.NumField(); i++ {
+		f := typ.Field(i)
+		if f.Type.Kind() == reflect.Struct && reflect.PtrTo(f.Type).Implements(locker) {
+			found = true
+		}
+	}
+
+	assert.True(t, found, "Collector must hold a lock by value to be checked by copylocks")
+}
+
+func TestCollector_Clone(t *testing.T) {
+	c := dynhist.Collector{BucketsLimit: 3, TrackQuantiles: []float64{50}, RawValues: []float64{}}
+	require.NoError(t, c.TrackThreshold("slow", 5))
+
+	for i := 0; i < 10; i++ {
+		c.Add(float64(i))
+	}
+
+	cl := c.Clone()
+	assert.Equal(t, c.String(), cl.String())
+	p50, _ := c.QuickPercentile(50)
+	clP50, _ := cl.QuickPercentile(50)
+	assert.Equal(t, p50, clP50)
+	assert.Equal(t, c.ThresholdCounts(), cl.ThresholdCounts())
+	assert.Equal(t, c.RawValues, cl.RawValues)
+
+	before := c.String()
+
+	for i := 0; i < 10; i++ {
+		cl.Add(100)
+	}
+
+	assert.Equal(t, before, c.String())
+	assert.Equal(t, 10, c.Count)
+	assert.Len(t, c.RawValues, 10)
+	assert.Equal(t, 4, c.ThresholdCounts()["slow"])
+	assert.Equal(t, 20, cl.Count)
+	assert.Equal(t, 14, cl.ThresholdCounts()["slow"])
+}
+
+func TestCollector_Downsample(t *testing.T) {
+	c := dynhist.Collector{BucketsLimit: 200}
+	r := dataset.New(1)
+
+	for i := 0; i < 100000; i++ {
+		c.Add(r.ExpFloat64())
+	}
+
+	before := c.String()
+	d := c.Downsample(20)
+
+	assert.Equal(t, before, c.String(), "source is untouched")
+	assert.Len(t, c.Buckets, 200)
+	require.Len(t, d.Buckets, 20)
+	assert.Equal(t, 20, d.BucketsLimit)
+	assert.Equal(t, c.Count, d.Count)
+	assert.Equal(t, c.Sum, d.Sum)
+	assert.Equal(t, c.Min, d.Min)
+	assert.Equal(t, c.Max, d.Max)
+
+	for _, p := range []float64{1, 10, 25, 50, 75, 90, 99, 99.9} {
+		v := d.Percentile(p)
+
+		for _, b := range d.Buckets {
+			if v >= b.Min && v <= b.Max {
+				assert.InDelta(t, c.Percentile(p), v, b.Max-b.Min, p)
+			}
+		}
+	}
+
+	d.Add(100)
+	assert.Equal(t, before, c.String())
+	assert.Len(t, d.Buckets, 20)
+}
@@ -0,0 +1,135 @@
+package dynhist
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// snapshotBuckets returns a copy of buckets and totals in original units taken under the lock.
+// Nil collector has no buckets.
+func (c *Collector) snapshotBuckets() (Bucket, []Bucket) {
+	if c == nil {
+		return Bucket{}, nil
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	total, buckets := c.Bucket, append([]Bucket(nil), c.Buckets...)
+	c.Transform.outward(&total, buckets)
+
+	return total, buckets
+}
+
+// unionBounds returns sorted boundaries of all buckets, values of zero-width buckets are repeated
+// to produce zero-width ranges.
+func unionBounds(sets ...[]Bucket) []float64 {
+	points := make(map[float64]bool)
+
+	for _, buckets := range sets {
+		for _, b := range buckets {
+			points[b.Min] = points[b.Min] || b.Min == b.Max
+			points[b.Max] = points[b.Max] || b.Min == b.Max
+		}
+	}
+
+	bounds := make([]float64, 0, 2*len(points))
+
+	for p := range points {
+		bounds = append(bounds, p)
+	}
+
+	sort.Float64s(bounds)
+
+	res := make([]float64, 0, len(bounds)+len(points))
+
+	for _, p := range bounds {
+		res = append(res, p)
+
+		if points[p] {
+			res = append(res, p)
+		}
+	}
+
+	if len(res) == 1 {
+		res = append(res, res[0])
+	}
+
+	return res
+}
+
+// align resamples two sets of buckets onto the union of their boundaries.
+func align(a, b []Bucket) (ra, rb []Bucket) {
+	bounds := unionBounds(a, b)
+
+	return resample(a, bounds), resample(b, bounds)
+}
+
+// RenderComparison renders buckets aligned with baseline and a difference of count shares.
+//
+// Columns show count and share of collector, share of baseline and the signed difference
+// of shares in percentage points. Ranges that only have data in one of the collectors are flagged
+// with "new" or "gone".
+func (c *Collector) RenderComparison(baseline *Collector, opts RenderOptions) string {
+	total, buckets := c.snapshotBuckets()
+	baseTotal, baseBuckets := baseline.snapshotBuckets()
+
+	if len(buckets) == 0 && len(baseBuckets) == 0 {
+		return ""
+	}
+
+	ra, rb := align(buckets, baseBuckets)
+
+	var rows, baseRows []Bucket
+
+	for i := range ra {
+		if ra[i].Count == 0 && rb[i].Count == 0 {
+			continue
+		}
+
+		rows = append(rows, ra[i])
+		baseRows = append(baseRows, rb[i])
+	}
+
+	bounds := boundsColumn{format: formatFixed, open: "[", close: "]"}
+	if opts.ValueFormatter != nil {
+		bounds.format = opts.ValueFormatter
+	}
+
+	bounds.prepare(rows)
+
+	cLen := printfLen("%d", total.Count)
+
+	var res strings.Builder
+
+	bounds.header(&res)
+	fmt.Fprintf(&res, " %*s  total%%  base%%       Δ%% (%d events, baseline %d events)\n",
+		cLen, "cnt", total.Count, baseTotal.Count)
+
+	for i, r := range rows {
+		s, bs := 0.0, 0.0
+
+		if total.Count > 0 {
+			s = share(r.Count, total.Count)
+		}
+
+		if baseTotal.Count > 0 {
+			bs = share(baseRows[i].Count, baseTotal.Count)
+		}
+
+		bounds.row(&res, i)
+		fmt.Fprintf(&res, " %*d %6.2f%% %5.2f%% %+7.2f%%", cLen, r.Count, s, bs, s-bs)
+
+		switch {
+		case r.Count == 0:
+			res.WriteString(" gone")
+		case baseRows[i].Count == 0:
+			res.WriteString(" new")
+		}
+
+		res.WriteString("\n")
+	}
+
+	return res.String()
+}
@@ -0,0 +1,76 @@
+package dynhist_test
+
+import (
+	"strings"
+	"testing"
+	"unicode"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vearutop/dynhist-go"
+)
+
+func TestCollector_RenderComparison(t *testing.T) {
+	baseline := dynhist.Collector{BucketsLimit: 4}
+	canary := dynhist.Collector{BucketsLimit: 4}
+
+	for i := 0; i < 100; i++ {
+		baseline.Add(float64(i))
+		canary.Add(float64(i))
+	}
+
+	assertColumnsAligned(t, canary.RenderComparison(&baseline, dynhist.RenderOptions{}))
+	assert.Equal(t, `[  min   max] cnt  total%  base%       Δ% (100 events, baseline 100 events)
+[ 0.00 20.00]  21  21.00% 21.00%   +0.00%
+[21.00 51.00]  31  31.00% 31.00%   +0.00%
+[52.00 81.00]  30  30.00% 30.00%   +0.00%
+[82.00 99.00]  18  18.00% 18.00%   +0.00%
+`, canary.RenderComparison(&baseline, dynhist.RenderOptions{}))
+
+	// Shifted tail, canary buckets are also rearranged.
+	for i := 0; i < 20; i++ {
+		canary.Add(150)
+	}
+
+	assertColumnsAligned(t, canary.RenderComparison(&baseline, dynhist.RenderOptions{}))
+	assert.Equal(t, `[   min    max] cnt  total%  base%       Δ% (120 events, baseline 100 events)
+[  0.00  20.00]  21  17.50% 21.00%   -3.50%
+[ 21.00  51.00]  31  25.83% 31.00%   -5.17%
+[ 52.00  81.00]  30  25.00% 30.00%   -5.00%
+[ 81.00  82.00]   1   0.83%  0.00%   +0.83% new
+[ 82.00  99.00]  17  14.17% 18.00%   -3.83%
+[150.00 150.00]  20  16.67%  0.00%  +16.67% new
+`, canary.RenderComparison(&baseline, dynhist.RenderOptions{}))
+}
+
+// assertColumnsAligned checks that cnt, total%, base% and Δ% columns end at the same rune offset in every line.
+func assertColumnsAligned(t *testing.T, s string) {
+	t.Helper()
+
+	lines := strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+	header := columnEnds(lines[0])
+
+	for _, l := range lines[1:] {
+		assert.Equal(t, header, columnEnds(l), l)
+	}
+}
+
+// columnEnds returns rune offsets of ends of the first four fields after bounds.
+func columnEnds(line string) []int {
+	runes := []rune(line)
+	ends := make([]int, 0, 4)
+	i := strings.IndexRune(line, ']') + 1
+
+	for len(ends) < 4 && i < len(runes) {
+		for i < len(runes) && unicode.IsSpace(runes[i]) {
+			i++
+		}
+
+		for i < len(runes) && !unicode.IsSpace(runes[i]) {
+			i++
+		}
+
+		ends = append(ends, i)
+	}
+
+	return ends
+}
@@ -0,0 +1,45 @@
+package dynhist
+
+import "sort"
+
+// CountOf returns number of collected values equal to v and whether the result is exact.
+//
+// Result is exact if v belongs to a zero-width bucket, if no bucket covers v (count is 0),
+// or if RawValues are enabled and have all values, which is not the case after Merge, LoadBuckets,
+// UnmarshalJSON or ScaleCounts until Reset. With Quantize or Epsilon a zero-width bucket also
+// counts nearby values, so it is only exact with RawValues. Otherwise (0, false) is returned.
+func (c *Collector) CountOf(v float64) (int, bool) {
+	if c == nil {
+		return 0, true
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	// Values equal to v are placed at x or within tolerance of it.
+	x := c.placeValue(v)
+	tol := c.tolerance(x)
+	i := sort.Search(len(c.Buckets), func(i int) bool { return c.Buckets[i].Max >= x-tol })
+
+	if i == len(c.Buckets) || c.Buckets[i].Min > x+tol {
+		return 0, true
+	}
+
+	if b := c.Buckets[i]; b.Min == b.Max && c.Quantize <= 0 && c.Epsilon <= 0 {
+		return b.Count, true
+	}
+
+	if c.RawValues != nil && !c.rawPartial {
+		cnt := 0
+
+		for _, r := range c.RawValues {
+			if r == v {
+				cnt++
+			}
+		}
+
+		return cnt, true
+	}
+
+	return 0, false
+}
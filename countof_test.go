@@ -0,0 +1,129 @@
+package dynhist_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vearutop/dynhist-go"
+)
+
+func TestCollector_CountOf(t *testing.T) {
+	for _, raw := range []bool{false, true} {
+		c := dynhist.Collector{BucketsLimit: 3, PreserveSpikes: 1}
+		if raw {
+			c.RawValues = []float64{}
+		}
+
+		for i := 0; i < 60; i++ {
+			c.Add(0)
+		}
+
+		for i := 1; i <= 30; i++ {
+			c.Add(float64(i))
+		}
+
+		for i := 0; i < 10; i++ {
+			c.Add(1000) // Timeout.
+		}
+
+		cnt, exact := c.CountOf(0)
+		assert.True(t, exact)
+		assert.Equal(t, 60, cnt)
+
+		cnt, exact = c.CountOf(1000)
+		assert.True(t, exact)
+		assert.Equal(t, 10, cnt)
+
+		// Value in a gap.
+		cnt, exact = c.CountOf(500)
+		assert.True(t, exact)
+		assert.Equal(t, 0, cnt)
+
+		// Value inside of a wide bucket.
+		cnt, exact = c.CountOf(7)
+		assert.Equal(t, raw, exact)
+
+		if raw {
+			assert.Equal(t, 1, cnt)
+		} else {
+			assert.Equal(t, 0, cnt)
+		}
+	}
+}
+
+func TestCollector_CountOf_tolerance(t *testing.T) {
+	for _, raw := range []bool{false, true} {
+		q := dynhist.Collector{Quantize: 1}
+		e := dynhist.Collector{Epsilon: 0.1}
+
+		if raw {
+			q.RawValues = []float64{}
+			e.RawValues = []float64{}
+		}
+
+		q.Add(1)
+		q.Add(1.4)
+		e.Add(2)
+		e.Add(2.05)
+
+		for _, tc := range []struct {
+			c *dynhist.Collector
+			v float64
+		}{
+			{c: &q, v: 1},
+			{c: &q, v: 1.4},
+			{c: &e, v: 2},
+			{c: &e, v: 2.05},
+		} {
+			cnt, exact := tc.c.CountOf(tc.v)
+			assert.Equal(t, raw, exact, tc.v)
+
+			if raw {
+				assert.Equal(t, 1, cnt, tc.v)
+			}
+		}
+
+		// Values far from buckets are not counted.
+		cnt, exact := q.CountOf(3)
+		assert.True(t, exact)
+		assert.Equal(t, 0, cnt)
+
+		cnt, exact = e.CountOf(2.5)
+		assert.True(t, exact)
+		assert.Equal(t, 0, cnt)
+	}
+}
+
+func TestCollector_CountOf_merged(t *testing.T) {
+	c := dynhist.Collector{BucketsLimit: 3, RawValues: []float64{}}
+
+	for i := 1; i <= 30; i++ {
+		c.Add(float64(i))
+	}
+
+	cnt, exact := c.CountOf(7)
+	assert.True(t, exact)
+	assert.Equal(t, 1, cnt)
+
+	other := dynhist.Collector{}
+	other.Add(7)
+	c.Merge(&other)
+
+	// Raw values do not have merged data.
+	cnt, exact = c.CountOf(7)
+	assert.False(t, exact)
+	assert.Equal(t, 0, cnt)
+
+	require.NoError(t, c.LoadBuckets([]dynhist.Bucket{{Min: 1, Max: 10, Count: 3, Sum: 15}}))
+	_, exact = c.CountOf(7)
+	assert.False(t, exact)
+
+	c.Reset()
+	c.Add(7)
+	c.Add(8)
+
+	cnt, exact = c.CountOf(7)
+	assert.True(t, exact)
+	assert.Equal(t, 1, cnt)
+}
@@ -0,0 +1,185 @@
+package dynhist
+
+import (
+	"bufio"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var multiCSVHeader = []string{"label", "bucket_min", "bucket_max", "count", "sum", "percent"}
+
+// multiCSVVersion prefixes a comment line with SchemaVersion that precedes CSV header.
+const multiCSVVersion = "# schema_version: "
+
+// ErrCSVHeader is returned by ReadMultiCSV when CSV header is missing or unexpected.
+var ErrCSVHeader = errors.New("unexpected CSV header")
+
+// WriteMultiCSV writes buckets of labeled collectors as CSV.
+//
+// Columns are label, bucket_min, bucket_max, count, sum, percent, rows are ordered by label
+// and then by buckets. Percent is a share of bucket count in the total count of a collector.
+// Header is preceded by a comment line with SchemaVersion, e.g. "# schema_version: 1".
+func WriteMultiCSV(w io.Writer, items map[string]*Collector) error {
+	labels := make([]string, 0, len(items))
+
+	for label := range items {
+		labels = append(labels, label)
+	}
+
+	sort.Strings(labels)
+
+	if _, err := io.WriteString(w, multiCSVVersion+strconv.Itoa(SchemaVersion)+"\n"); err != nil {
+		return err
+	}
+
+	cw := csv.NewWriter(w)
+
+	if err := cw.Write(multiCSVHeader); err != nil {
+		return err
+	}
+
+	for _, label := range labels {
+		total, buckets := items[label].snapshotBuckets()
+
+		for _, b := range buckets {
+			if err := cw.Write([]string{
+				label,
+				formatRaw(b.Min),
+				formatRaw(b.Max),
+				strconv.Itoa(b.Count),
+				formatRaw(b.Sum),
+				formatRaw(share(b.Count, total.Count)),
+			}); err != nil {
+				return err
+			}
+		}
+	}
+
+	cw.Flush()
+
+	return cw.Error()
+}
+
+// ReadMultiCSV reads labeled collectors from CSV produced by WriteMultiCSV.
+//
+// Percent column is ignored, buckets of each label are loaded with LoadBuckets.
+// Data without a schema version line is read as version 0, an unsupported version
+// results in VersionError.
+func ReadMultiCSV(r io.Reader) (map[string]*Collector, error) {
+	br := bufio.NewReader(r)
+
+	skipped, err := readMultiCSVVersion(br)
+	if err != nil {
+		return nil, err
+	}
+
+	cr := csv.NewReader(br)
+	cr.FieldsPerRecord = len(multiCSVHeader)
+
+	header, err := cr.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, ErrCSVHeader
+		}
+
+		return nil, err
+	}
+
+	for i, h := range multiCSVHeader {
+		if header[i] != h {
+			return nil, ErrCSVHeader
+		}
+	}
+
+	labels := make([]string, 0)
+	buckets := make(map[string][]Bucket)
+
+	for {
+		rec, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		b, err := parseCSVBucket(rec)
+		if err != nil {
+			line, _ := cr.FieldPos(0)
+
+			return nil, fmt.Errorf("line %d: %w", line+skipped, err)
+		}
+
+		label := rec[0]
+		if _, ok := buckets[label]; !ok {
+			labels = append(labels, label)
+		}
+
+		buckets[label] = append(buckets[label], b)
+	}
+
+	res := make(map[string]*Collector, len(labels))
+
+	for _, label := range labels {
+		c := &Collector{}
+
+		if err := c.LoadBuckets(buckets[label]); err != nil {
+			return nil, fmt.Errorf("label %q: %w", label, err)
+		}
+
+		res[label] = c
+	}
+
+	return res, nil
+}
+
+// readMultiCSVVersion reads and checks an optional schema version line, it returns number of lines read.
+func readMultiCSVVersion(br *bufio.Reader) (int, error) {
+	// Read error is reported by CSV reader.
+	if b, _ := br.Peek(1); len(b) == 0 || b[0] != '#' {
+		return 0, nil
+	}
+
+	line, err := br.ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return 0, err
+	}
+
+	v, err := strconv.Atoi(strings.TrimPrefix(strings.TrimRight(line, "\r\n"), multiCSVVersion))
+	if err != nil || !strings.HasPrefix(line, multiCSVVersion) {
+		return 0, ErrCSVHeader
+	}
+
+	return 1, checkVersion(v)
+}
+
+func parseCSVBucket(rec []string) (Bucket, error) {
+	var (
+		b   Bucket
+		err error
+	)
+
+	if b.Min, err = ParseFloat(rec[1]); err != nil {
+		return b, err
+	}
+
+	if b.Max, err = ParseFloat(rec[2]); err != nil {
+		return b, err
+	}
+
+	if b.Count, err = strconv.Atoi(rec[3]); err != nil {
+		return b, err
+	}
+
+	if b.Sum, err = ParseFloat(rec[4]); err != nil {
+		return b, err
+	}
+
+	return b, nil
+}
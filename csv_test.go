@@ -0,0 +1,81 @@
+package dynhist_test
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vearutop/dynhist-go"
+)
+
+func TestWriteMultiCSV(t *testing.T) {
+	items := map[string]*dynhist.Collector{
+		"GET /a,b":          {BucketsLimit: 3},
+		`POST "quoted"`:     {BucketsLimit: 3},
+		"ПОЛУЧИТЬ /ünïcode": {BucketsLimit: 3},
+	}
+
+	k := 0
+
+	for _, c := range items {
+		k++
+
+		for i := 0; i < 10*k; i++ {
+			c.Add(float64(i % (3 + k)))
+		}
+	}
+
+	buf := bytes.NewBuffer(nil)
+	require.NoError(t, dynhist.WriteMultiCSV(buf, items))
+
+	lines := strings.Split(buf.String(), "\n")
+	assert.Equal(t, "# schema_version: 1", lines[0])
+	assert.Equal(t, "label,bucket_min,bucket_max,count,sum,percent", lines[1])
+	assert.True(t, strings.HasPrefix(lines[2], `"GET /a,b",`))
+
+	res, err := dynhist.ReadMultiCSV(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	require.Len(t, res, len(items))
+
+	for label, c := range items {
+		r := res[label]
+		require.NotNil(t, r, label)
+
+		assert.Equal(t, c.Count, r.Count, label)
+		assert.Equal(t, c.Sum, r.Sum, label)
+		assert.Equal(t, c.Min, r.Min, label)
+		assert.Equal(t, c.Max, r.Max, label)
+		assert.Empty(t, dynhist.DiffString(c, r), label)
+	}
+}
+
+func TestReadMultiCSV_invalid(t *testing.T) {
+	_, err := dynhist.ReadMultiCSV(strings.NewReader(""))
+	assert.Equal(t, dynhist.ErrCSVHeader, err)
+
+	_, err = dynhist.ReadMultiCSV(strings.NewReader("a,b,c,d,e,f\n"))
+	assert.Equal(t, dynhist.ErrCSVHeader, err)
+
+	_, err = dynhist.ReadMultiCSV(strings.NewReader("label,bucket_min,bucket_max,count,sum,percent\n" +
+		"a,1,2,3,4.5,100\na,1,x,3,4.5,100\n"))
+	assert.EqualError(t, err, `line 3: strconv.ParseFloat: parsing "x": invalid syntax`)
+
+	_, err = dynhist.ReadMultiCSV(strings.NewReader("label,bucket_min,bucket_max,count,sum,percent\n" +
+		"a,3,4,3,10.5,50\na,1,2,3,4.5,50\n"))
+	assert.True(t, errors.Is(err, dynhist.ErrUnsortedBuckets), err)
+
+	_, err = dynhist.ReadMultiCSV(strings.NewReader("# schema_version: 9\n" +
+		"label,bucket_min,bucket_max,count,sum,percent\n"))
+	assert.True(t, errors.Is(err, dynhist.ErrUnsupportedVersion), err)
+
+	_, err = dynhist.ReadMultiCSV(strings.NewReader("# comment\n" +
+		"label,bucket_min,bucket_max,count,sum,percent\n"))
+	assert.Equal(t, dynhist.ErrCSVHeader, err)
+
+	_, err = dynhist.ReadMultiCSV(strings.NewReader("# schema_version: 1\n" +
+		"label,bucket_min,bucket_max,count,sum,percent\na,1,x,3,4.5,100\n"))
+	assert.EqualError(t, err, `line 3: strconv.ParseFloat: parsing "x": invalid syntax`)
+}
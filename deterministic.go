@@ -0,0 +1,34 @@
+package dynhist
+
+import (
+	"sort"
+
+	"github.com/vearutop/dynhist-go/internal/dataset"
+)
+
+// BuildDeterministic returns a collector of values with layout that does not depend on order of values.
+//
+// Values are sorted and then collected in a fixed pseudo-random order with weight (AvgWidth if nil),
+// so the same values always produce the same buckets and byte-stable String output, while the layout
+// is as good as of values arriving in mixed order. It is convenient for examples and documentation.
+// Merge weight ties always go to the lowest pair.
+func BuildDeterministic(values []float64, limit int, weight func(b1, b2, bTot Bucket) float64) *Collector {
+	shuffled := append([]float64(nil), values...)
+	sort.Float64s(shuffled)
+
+	r := dataset.New(1)
+
+	for i := len(shuffled) - 1; i > 0; i-- {
+		j := r.Intn(i + 1)
+		shuffled[i], shuffled[j] = shuffled[j], shuffled[i]
+	}
+
+	c := &Collector{BucketsLimit: limit, WeightFunc: weight}
+
+	for _, v := range shuffled {
+		c.add(v)
+		c.mergeDown(clampBucketsLimit(c.BucketsLimit))
+	}
+
+	return c
+}
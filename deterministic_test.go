@@ -0,0 +1,34 @@
+package dynhist_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vearutop/dynhist-go"
+	"github.com/vearutop/dynhist-go/internal/dataset"
+)
+
+func TestBuildDeterministic(t *testing.T) {
+	r := dataset.New(1)
+	values := make([]float64, 5000)
+
+	for i := range values {
+		values[i] = float64(int(100*r.ExpFloat64())) / 10
+	}
+
+	expected := dynhist.BuildDeterministic(values, 10, dynhist.LatencyWidth).String()
+	assert.Len(t, dynhist.BuildDeterministic(values, 10, nil).Buckets, 10)
+
+	for k := 0; k < 50; k++ {
+		for i := len(values) - 1; i > 0; i-- {
+			j := r.Intn(i + 1)
+			values[i], values[j] = values[j], values[i]
+		}
+
+		assert.Equal(t, expected, dynhist.BuildDeterministic(values, 10, dynhist.LatencyWidth).String())
+	}
+
+	c := dynhist.BuildDeterministic(nil, 10, nil)
+	assert.Equal(t, 0, c.Count)
+	assert.Equal(t, "", c.String())
+}
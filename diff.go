@@ -0,0 +1,63 @@
+package dynhist
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// DiffString returns a concise difference between buckets of a and b.
+//
+// Empty string is returned if collectors have equal totals and equal count shares in all ranges.
+// See DiffStringEpsilon.
+func DiffString(a, b *Collector) string {
+	return DiffStringEpsilon(a, b, 0)
+}
+
+// DiffStringEpsilon returns a concise difference between buckets of a and b.
+//
+// Buckets are resampled to the union of boundaries and only ranges with count share difference
+// exceeding epsilon percentage points are listed, with counts before and after and signed
+// difference of shares. A line with totals follows if there are differences.
+func DiffStringEpsilon(a, b *Collector, epsilon float64) string {
+	ta, ba := a.snapshotBuckets()
+	tb, bb := b.snapshotBuckets()
+
+	var res strings.Builder
+
+	if len(ba) > 0 || len(bb) > 0 {
+		ra, rb := align(ba, bb)
+
+		for i := range ra {
+			sa, sb := 0.0, 0.0
+
+			if ta.Count > 0 {
+				sa = share(ra[i].Count, ta.Count)
+			}
+
+			if tb.Count > 0 {
+				sb = share(rb[i].Count, tb.Count)
+			}
+
+			if ra[i].Count == rb[i].Count && sa == sb {
+				continue
+			}
+
+			if math.Abs(sb-sa) <= epsilon {
+				continue
+			}
+
+			fmt.Fprintf(&res, "[%s %s] %d -> %d %+.2f%%\n",
+				formatRaw(ra[i].Min), formatRaw(ra[i].Max), ra[i].Count, rb[i].Count, sb-sa)
+		}
+	}
+
+	if res.Len() == 0 && ta.Count == tb.Count && ta.Sum == tb.Sum {
+		return ""
+	}
+
+	fmt.Fprintf(&res, "count %d -> %d (%+d), sum %s -> %s\n",
+		ta.Count, tb.Count, tb.Count-ta.Count, formatRaw(ta.Sum), formatRaw(tb.Sum))
+
+	return res.String()
+}
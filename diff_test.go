@@ -0,0 +1,36 @@
+package dynhist_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vearutop/dynhist-go"
+)
+
+func TestDiffString(t *testing.T) {
+	a := dynhist.Collector{BucketsLimit: 3}
+	b := dynhist.Collector{BucketsLimit: 3}
+
+	for i := 0; i < 10; i++ {
+		a.Add(float64(i))
+		b.Add(float64(i))
+	}
+
+	assert.Equal(t, "", dynhist.DiffString(&a, &b))
+	assert.Equal(t, "", dynhist.DiffString(&dynhist.Collector{}, &dynhist.Collector{}))
+
+	b.Add(8)
+	b.Add(9)
+
+	assert.Equal(t, `[0 3] 4 -> 4 -6.67%
+[4 6] 3 -> 3 -5.00%
+[7 9] 3 -> 5 +11.67%
+count 10 -> 12 (+2), sum 45 -> 62
+`, dynhist.DiffString(&a, &b))
+
+	assert.Equal(t, `[7 9] 3 -> 5 +11.67%
+count 10 -> 12 (+2), sum 45 -> 62
+`, dynhist.DiffStringEpsilon(&a, &b, 10))
+
+	assert.Equal(t, "count 10 -> 12 (+2), sum 45 -> 62\n", dynhist.DiffStringEpsilon(&a, &b, 20))
+}
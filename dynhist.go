@@ -2,24 +2,66 @@
 package dynhist
 
 import (
+	"bufio"
 	"fmt"
+	"io"
 	"math"
 	"runtime/metrics"
-	"strings"
 	"sync"
+	"time"
 )
 
-// DefaultBucketsLimit is a default maximum number of buckets.
-const DefaultBucketsLimit = 20
+const (
+	// DefaultBucketsLimit is a default maximum number of buckets.
+	DefaultBucketsLimit = 20
+
+	// MinBucketsLimit is a minimal maximum number of buckets, smaller limits are clamped to it.
+	MinBucketsLimit = 2
+
+	// initialBucketsCap limits capacity preallocated for buckets with the first value.
+	initialBucketsCap = 256
+)
+
+// MaxBucketsHardLimit is a maximal number of buckets, larger limits are clamped to it.
+//
+// It protects from excessive memory and CPU usage caused by a misconfigured BucketsLimit,
+// it also limits AutoLimit growth. Change it before collectors are used.
+var MaxBucketsHardLimit = 100000
 
 // Collector groups and counts values by size using buckets.
+//
+// Collector must not be copied after first use: a copy shares backing arrays of Buckets
+// and other slices with the original and copies the state of the mutex, go vet reports
+// such copies. Use Clone to get an independent collector.
+//
+// A nil *Collector is a valid disabled collector, so that optional instrumentation does not
+// need nil checks: methods that collect values (Add, AddN, AddDuration, etc.) are no-ops,
+// String and Render return "", Percentile, PercentileFromMax, PercentileWithin and SumPercentile
+// return NaN, methods that load, validate or export data (LoadBuckets, UnmarshalJSON, Validate,
+// MarshalJSON, WritePrometheus, PushTo, etc.) return ErrNilCollector. Other methods return
+// zero values, for example Clone returns nil, Flush and Close return nil, AppendJSON returns dst.
 type Collector struct {
 	sync.Mutex
 
+	// Name is an optional metric name, used by exporters and in String header.
+	Name string
+
+	// Unit is an optional unit of values, for example "seconds".
+	Unit string
+
+	// Help is an optional description of values.
+	Help string
+
 	// BucketsLimit limits total number of buckets used.
+	//
+	// Zero value is replaced with DefaultBucketsLimit, values below MinBucketsLimit or above
+	// MaxBucketsHardLimit are clamped when first value is collected, see EffectiveBucketsLimit.
 	BucketsLimit int
 
-	// Bucket keeps total count.
+	// Bucket keeps totals, it is an implementation detail.
+	//
+	// Prefer TotalCount, TotalSum, MinValue and MaxValue, they are safe for concurrent use
+	// and return boundaries in original units if Transform is set.
 	Bucket
 
 	// Buckets is a list of available buckets.
@@ -28,21 +70,212 @@ type Collector struct {
 	// PrintSum enables printing of a summary value in a bucket.
 	PrintSum bool
 
+	// SumEstimated is set when sums of buckets are estimated from boundaries rather than collected,
+	// for example by LoadFromRuntimeMetrics or ParsePrometheusText, see EstimatedSumError.
+	SumEstimated bool
+
+	// PrintGaps enables a footer with a share of value range not covered by buckets, see GapFraction.
+	PrintGaps bool
+
+	// PrintPercentiles lists percentiles (0-100) to print in a footer of String, see RenderOptions.Percentiles.
+	PrintPercentiles []float64
+
+	// StaleAfter annotates percentiles printed in String if their Freshness exceeds it, see RenderOptions.StaleAfter.
+	StaleAfter time.Duration
+
+	// ValueFormatter formats bucket boundaries in String, see RenderOptions.ValueFormatter.
+	ValueFormatter func(v float64) string
+
 	// RawValues stores incoming events, disabled by default. Use non-nil value to enable.
 	RawValues []float64
 
+	// rawPartial is set when buckets have data that is not in RawValues (LoadBuckets, Merge, etc.).
+	rawPartial bool
+
 	// WeightFunc calculates weight of adjacent buckets with total available. Pair with minimal weight is merged.
 	// AvgWidth is used by default.
-	// See also LatencyWidth, ExpWidth.
+	// See also LatencyWidth, ExpWidth, LogNormalWidth, RelativeErrorWidth.
+	//
+	// Negative weights and -Inf are allowed and preferred over positive ones, NaN is treated as +Inf.
+	// Among pairs of equal weight the leftmost pair is merged.
 	WeightFunc func(b1, b2, bTot Bucket) float64
+
+	// StrictWeights enables a panic with offending pair of buckets when WeightFunc returns NaN or ±Inf,
+	// it helps to debug custom weight functions.
+	StrictWeights bool
+
+	// RawWriter receives every collected value, disabled by default.
+	//
+	// Values are buffered, use Flush or Close to write pending data, use ReplayRaw to read values back.
+	// Binary format (little-endian float64 after a header) is used by default.
+	RawWriter io.Writer
+
+	// RawText enables text format for RawWriter, one value per line.
+	RawText bool
+
+	// LastWriteErr keeps the last error of writing to RawWriter.
+	LastWriteErr error
+
+	rawBuf    *bufio.Writer
+	rawClosed bool
+
+	// Trace receives a line per merge and per insert of a new bucket, nil disables tracing.
+	//
+	// Merge lines show merged pair, its weight and the next minimal weight among other pairs,
+	// for example "merge [1 2] [3 3] w=2 next=3". Insert lines show value and bucket index,
+	// for example "insert 5 at 2". Write errors are ignored.
+	Trace io.Writer
+
+	// MaxLineLen limits length of a line in ReadLines, bufio.MaxScanTokenSize is used by default.
+	MaxLineLen int
+
+	// WarmupCount postpones merging of buckets until this many values are collected.
+	//
+	// During warmup every distinct value may occupy a bucket, when warmup ends buckets are merged
+	// down to BucketsLimit at once, so that initial layout does not depend on the order of values.
+	// Memory usage during warmup is proportional to WarmupCount, number of buckets is still
+	// bounded by MaxBucketsHardLimit.
+	WarmupCount int
+
+	// AutoLimit enables growing number of buckets up to 10 times BucketsLimit, but not over MaxBucketsHardLimit.
+	//
+	// When number of buckets exceeds BucketsLimit, a merge only happens if the merged bucket
+	// does not exceed MaxBucketShare of total count and MaxRelativeWidth of total range.
+	// Zero value of a constraint disables it. Merges are forced when hard limit is reached.
+	AutoLimit bool
+
+	// MaxBucketShare is a maximum fraction (0-1] of total count in a merged bucket for AutoLimit.
+	MaxBucketShare float64
+
+	// MaxRelativeWidth is a maximum width of a merged bucket relative to total range for AutoLimit.
+	MaxRelativeWidth float64
+
+	// Offset is subtracted from values collected with AddInt64.
+	Offset int64
+
+	// PreserveSpikes is a number of zero-width buckets with highest counts that are exempt from merging.
+	//
+	// Such buckets keep exact count of a repeated value (for example 0 for cache hits).
+	PreserveSpikes int
+
+	// TrackQuantiles is a list of percents (0-100] to estimate with P² algorithm, see QuickPercentile.
+	//
+	// Estimation uses a few floats per percent and does not depend on buckets.
+	// The list is applied when the first value is added.
+	TrackQuantiles []float64
+
+	// Transform is applied to values before bucketing, nil disables transformation.
+	//
+	// Boundaries of Buckets and totals Min and Max hold transformed values, while sums are kept
+	// in original units. Percentile, Render and exporters convert boundaries back to original units.
+	// See LogTransform, SqrtTransform.
+	Transform *Transform
+
+	// Epsilon is a tolerance for bucket membership, zero means exact comparison.
+	//
+	// A value within tolerance of a bucket is counted in that bucket without changing its boundaries.
+	// Adjacent buckets within tolerance of each other are merged first and combined by LoadBuckets.
+	Epsilon float64
+
+	// EpsilonMode defines whether Epsilon is absolute (default) or relative to the value.
+	EpsilonMode EpsilonMode
+
+	// Quantize is a quantum to round values to before bucketing, zero disables quantization.
+	//
+	// High resolution values (e.g. nanosecond durations) rarely repeat, quantization lets them
+	// share zero-width buckets and reduces merging. Values are rounded to the nearest multiple
+	// of the quantum, that does not shift distribution unlike truncation, but boundaries may be
+	// off by half of the quantum. Sums, RawValues, thresholds and P² estimates use original values,
+	// so means stay accurate. For durations in seconds, use time.Microsecond.Seconds() for example.
+	Quantize float64
+
+	// SnapBoundaries is a maximal error relative to bucket width to snap boundaries of a merged
+	// bucket to nice numbers (1, 2 or 5 × 10^k), zero disables snapping.
+	//
+	// Nice boundaries make exported buckets (Prometheus, JSON) easier to read. A boundary between
+	// adjacent buckets becomes Max of the lower bucket, buckets stay sorted and do not overlap,
+	// counts and sums are not changed. Boundaries are not moved across values of RawValues if enabled.
+	// With Transform, boundaries are nice up to rounding of the transformation.
+	SnapBoundaries float64
+
+	quantiles []p2Quantile
+
+	// KeepTopK is a number of largest values to keep exactly, 0 disables keeping.
+	//
+	// Largest values are kept in a heap of KeepTopK values, a value that is not larger than
+	// the smallest kept value is skipped in constant time. Percentile returns exact values for
+	// percentiles within kept values (for example p99.99 of a million values with KeepTopK 100),
+	// see also TopValues. Exact values are used only while they cover all collected data,
+	// LoadBuckets, UnmarshalJSON or ScaleCounts disable them until Reset.
+	KeepTopK int
+
+	top      []float64 // Min-heap of largest values.
+	topCount int       // Number of values that top represents, exact top requires topCount == Count.
+
+	thresholds []trackedThreshold
+
+	// TrackIDs enables stable identifiers of buckets, see BucketIDs and OnMerge.
+	TrackIDs bool
+
+	// OnMerge is called with identifiers of merged buckets and of the resulting bucket if TrackIDs is enabled.
+	//
+	// It is called while collector is locked and must not use collector.
+	OnMerge func(id1, id2, merged uint64)
+
+	ids    []uint64
+	lastID uint64
+
+	// labels of buckets, see Annotate, nil if there are no labels.
+	labels []string
+
+	// TrackFreshness enables time of the newest value in every bucket, see Freshness.
+	TrackFreshness bool
+
+	seen []int64 // Unix time in nanoseconds of the newest value of every bucket, 0 if unknown.
+
+	// placed is index of the bucket of the last added value, it is kept through merges for AddClassified.
+	placed int
+
+	// sources of pushed snapshots with time of the last snapshot, see ListenAndAggregate.
+	sources map[string]time.Time
+
+	watchers []*percentileWatcher
+	events   []watchEvent
+
+	// generation is incremented on every change of collected data.
+	generation uint64
+	cache      renderCache
+
+	// Merges is a number of merges of adjacent buckets.
+	Merges int
+
+	// LimitPressure is a number of merges that relaxed a protection (PreserveSpikes, AutoLimit
+	// constraints) to keep the number of buckets within limit.
+	//
+	// Growing value means BucketsLimit is too small for configured protections.
+	LimitPressure int
+
+	// LastMergeWidth is a width of the last merged bucket relative to total range.
+	LastMergeWidth float64
+
+	// MergedWidth is a cumulative width of merged buckets relative to total range.
+	MergedWidth float64
+}
+
+// MergeStats describes resolution loss caused by merging of buckets.
+type MergeStats struct {
+	Merges         int
+	LastMergeWidth float64
+	MergedWidth    float64
+	LimitPressure  int
 }
 
 // Bucket keeps count of values in boundaries.
 type Bucket struct {
-	Min   float64
-	Max   float64
-	Count int
-	Sum   float64
+	Min   float64 `json:"min"`
+	Max   float64 `json:"max"`
+	Count int     `json:"count"`
+	Sum   float64 `json:"sum"`
 }
 
 // ExpWidth creates a weight function with exponential bucket width growing.
@@ -71,185 +304,613 @@ func AvgWidth(b1, b2, bTot Bucket) float64 {
 }
 
 // Add collects value.
-func (c *Collector) Add(v float64) { //nolint:funlen,cyclop
+//
+// Values are bucketed as float64, which represents integers exactly only up to 2^53 (about 9e15).
+// Larger values, for example UnixNano timestamps (about 1.7e18), are rounded to a multiple of 256
+// and narrow buckets may collapse, use AddInt64 for such values.
+// Add on a nil collector is a no-op.
+func (c *Collector) Add(v float64) {
+	if c == nil {
+		return
+	}
+
+	c.Lock()
+	defer c.unlockAndNotify()
+
+	c.add(v)
+	c.mergeOverLimit()
+}
+
+// AddN collects value n times, for example from pre-aggregated data.
+//
+// It is equivalent to n calls of Add, but buckets are updated at once, so that time does not depend
+// on n unless RawValues or RawWriter are enabled. P² estimates of TrackQuantiles and WatchPercentile
+// are updated with weight n, the estimate differs from n calls of Add for large n. Non-positive n is ignored.
+// AddN on a nil collector is a no-op.
+func (c *Collector) AddN(v float64, n int) {
+	if c == nil {
+		return
+	}
+
+	if n <= 0 {
+		return
+	}
+
 	c.Lock()
-	defer func() {
-		if len(c.Buckets) > c.BucketsLimit {
-			minWeight := 0.0
-			mergePoint := 0
-
-			for i := 1; i < len(c.Buckets); i++ {
-				if mergePoint == 0 {
-					mergePoint = i
-					minWeight = c.WeightFunc(c.Buckets[i-1], c.Buckets[i], c.Bucket)
-
-					continue
-				}
-
-				weight := c.WeightFunc(c.Buckets[i-1], c.Buckets[i], c.Bucket)
-				if weight < minWeight {
-					minWeight = weight
-					mergePoint = i
-				}
+	defer c.unlockAndNotify()
+
+	c.addN(v, n)
+	c.mergeOverLimit()
+}
+
+// mergeOverLimit merges buckets down to BucketsLimit, or to MaxBucketsHardLimit while warmup is in progress.
+func (c *Collector) mergeOverLimit() {
+	if c.WarmupCount != 0 && c.Count < c.WarmupCount {
+		if len(c.Buckets) > MaxBucketsHardLimit {
+			c.mergeDown(MaxBucketsHardLimit)
+		}
+
+		return
+	}
+
+	c.mergeDown(clampBucketsLimit(c.BucketsLimit))
+}
+
+// mergeDown merges adjacent buckets with minimal weight until there are no more than limit buckets.
+//
+// Protections that conflict with the limit are relaxed in this order, so that the number of buckets
+// is always bounded and every call terminates (each merge removes a bucket):
+//   - AutoLimit constraints (MaxBucketShare, MaxRelativeWidth) let buckets grow over limit,
+//     but not over the AutoLimit hard limit, where merges are forced regardless of constraints;
+//   - PreserveSpikes is relaxed last, if every pair of buckets has a protected spike,
+//     the pair with minimal weight is merged anyway.
+//
+// Every merge that relaxes a protection increments LimitPressure.
+//
+// Pairs are kept in a heap across merges, only pairs with the merged bucket are reweighed,
+// so that merging down from n buckets takes O(n log n) weight calculations and comparisons
+// instead of O(n²) of repeated mergeOnce, merged buckets are still removed by copying the tail
+// of the slice. Merge sequence is the same as of repeated mergeOnce.
+func (c *Collector) mergeDown(limit int) {
+	if c.AutoLimit {
+		c.mergeAuto(limit)
+
+		return
+	}
+
+	// Protected spikes depend on all buckets, so they are found again for every merge.
+	if len(c.Buckets)-limit < 2 || c.PreserveSpikes > 0 {
+		for len(c.Buckets) > limit {
+			c.mergeOnce()
+		}
+
+		return
+	}
+
+	m := newPairMerger(c)
+
+	for len(c.Buckets) > limit {
+		m.mergeNext()
+	}
+}
+
+func (c *Collector) mergeOnce() {
+	mergePoint, forced := c.mergePoint()
+	if forced {
+		c.LimitPressure++
+	}
+
+	c.mergeAt(mergePoint)
+}
+
+// mergePoint returns index of the second bucket in a pair with minimal weight,
+// forced is true if all pairs are protected.
+func (c *Collector) mergePoint() (mergePoint int, forced bool) {
+	minWeight := 0.0
+	protected := c.spikes()
+
+	for i := 1; i < len(c.Buckets); i++ {
+		if c.Epsilon > 0 && c.nearDuplicate(c.Buckets[i-1], c.Buckets[i]) {
+			return i, false
+		}
+
+		if protected != nil && (protected[i-1] || protected[i]) {
+			continue
+		}
+
+		if mergePoint == 0 {
+			mergePoint = i
+			minWeight = c.weight(i)
+
+			continue
+		}
+
+		weight := c.weight(i)
+		if weight < minWeight {
+			minWeight = weight
+			mergePoint = i
+		}
+	}
+
+	if mergePoint == 0 {
+		// All pairs are protected, merging the best pair regardless of protection.
+		forced = true
+
+		for i := 1; i < len(c.Buckets); i++ {
+			weight := c.weight(i)
+			if mergePoint == 0 || weight < minWeight {
+				minWeight = weight
+				mergePoint = i
 			}
+		}
+	}
+
+	return mergePoint, forced
+}
+
+// weight returns weight of buckets at i-1 and i, NaN is replaced with +Inf.
+func (c *Collector) weight(i int) float64 {
+	b1, b2 := c.Buckets[i-1], c.Buckets[i]
+	w := c.WeightFunc(b1, b2, c.Bucket)
+
+	if c.StrictWeights && (math.IsNaN(w) || math.IsInf(w, 0)) {
+		panic(fmt.Sprintf("dynhist: WeightFunc returned %v for [%v %v] [%v %v]", w, b1.Min, b1.Max, b2.Min, b2.Max))
+	}
+
+	if math.IsNaN(w) {
+		return math.Inf(1)
+	}
+
+	return w
+}
+
+// mergeAt merges buckets at mergePoint-1 and mergePoint.
+func (c *Collector) mergeAt(mergePoint int) {
+	if c.Trace != nil {
+		c.traceMerge(mergePoint)
+	}
+
+	b1 := c.Buckets[mergePoint-1]
+	b2 := c.Buckets[mergePoint]
+	merged := Bucket{
+		Count: b1.Count + b2.Count,
+		Sum:   b1.Sum + b2.Sum,
+		Min:   b1.Min,
+		Max:   b2.Max,
+	}
+
+	c.Buckets = append(c.Buckets[:mergePoint-1], c.Buckets[mergePoint:]...)
+
+	if c.placed >= mergePoint {
+		c.placed--
+	}
+
+	c.Buckets[mergePoint-1] = merged
+	c.snap(mergePoint - 1)
+
+	c.Merges++
+	c.LastMergeWidth = 0
 
-			b1 := c.Buckets[mergePoint-1]
-			b2 := c.Buckets[mergePoint]
-			merged := Bucket{
-				Count: b1.Count + b2.Count,
-				Sum:   b1.Sum + b2.Sum,
-				Min:   b1.Min,
-				Max:   b2.Max,
+	if c.Max > c.Min {
+		c.LastMergeWidth = (merged.Max - merged.Min) / (c.Max - c.Min)
+	}
+
+	c.MergedWidth += c.LastMergeWidth
+
+	if c.TrackIDs {
+		c.mergeIDs(mergePoint)
+	}
+
+	if c.labels != nil {
+		c.mergeLabels(mergePoint)
+	}
+
+	if c.TrackFreshness {
+		c.mergeSeen(mergePoint)
+	}
+}
+
+// spikes marks zero-width buckets with highest counts, it returns nil if PreserveSpikes is disabled.
+func (c *Collector) spikes() []bool {
+	if c.PreserveSpikes <= 0 {
+		return nil
+	}
+
+	protected := make([]bool, len(c.Buckets))
+
+	for n := 0; n < c.PreserveSpikes; n++ {
+		best := -1
+
+		for i, b := range c.Buckets {
+			if b.Min != b.Max || protected[i] || b.Count < 2 {
+				continue
 			}
 
-			c.Buckets = append(c.Buckets[:mergePoint-1], c.Buckets[mergePoint:]...)
+			if best == -1 || b.Count > c.Buckets[best].Count {
+				best = i
+			}
+		}
 
-			c.Buckets[mergePoint-1] = merged
+		if best == -1 {
+			break
 		}
-		c.Unlock()
-	}()
 
-	if c.RawValues != nil {
-		c.RawValues = append(c.RawValues, v)
+		protected[best] = true
 	}
-	c.Count++
-	c.Sum += v
 
-	if len(c.Buckets) == 0 {
-		if c.BucketsLimit == 0 {
-			c.BucketsLimit = DefaultBucketsLimit
+	return protected
+}
+
+// MergeStats returns merge counters.
+func (c *Collector) MergeStats() MergeStats {
+	if c == nil {
+		return MergeStats{}
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	return MergeStats{
+		Merges:         c.Merges,
+		LastMergeWidth: c.LastMergeWidth,
+		MergedWidth:    c.MergedWidth,
+		LimitPressure:  c.LimitPressure,
+	}
+}
+
+// TotalCount returns number of collected values.
+func (c *Collector) TotalCount() int {
+	if c == nil {
+		return 0
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	return c.Count
+}
+
+// TotalSum returns sum of collected values.
+func (c *Collector) TotalSum() float64 {
+	if c == nil {
+		return 0
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	return c.Sum
+}
+
+// MinValue returns minimal collected value, or 0 if there are no values.
+func (c *Collector) MinValue() float64 {
+	if c == nil {
+		return 0
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	if c.Count == 0 {
+		return 0
+	}
+
+	return c.Transform.inv(c.Min)
+}
+
+// MaxValue returns maximal collected value, or 0 if there are no values.
+func (c *Collector) MaxValue() float64 {
+	if c == nil {
+		return 0
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	if c.Count == 0 {
+		return 0
+	}
+
+	return c.Transform.inv(c.Max)
+}
+
+// add inserts value into buckets without merging.
+func (c *Collector) add(v float64) {
+	c.addN(v, 1)
+}
+
+// addN inserts value n times into buckets without merging.
+//
+// Buckets are changed before totals, so that a panic of allocation (for example when out of memory)
+// leaves buckets and totals consistent.
+func (c *Collector) addN(v float64, n int) {
+	// Buckets are placed by quantized and transformed value, sums are kept in original units.
+	x := c.placeValue(v)
+	sum := v * float64(n)
+
+	i := c.place(x, n, sum)
+	c.placed = i
+
+	c.generation++
+	c.Count += n
+	c.Sum += sum
+
+	for i := range c.thresholds {
+		if v > c.thresholds[i].value {
+			c.thresholds[i].count += n
 		}
+	}
 
-		if c.WeightFunc == nil {
-			c.WeightFunc = AvgWidth
+	if c.KeepTopK > 0 {
+		c.keepTop(v, n)
+	}
+
+	if c.TrackFreshness {
+		c.touchSeen(i)
+	}
+
+	if c.TrackQuantiles != nil {
+		c.trackQuantiles(v, n)
+	}
+
+	// Raw values are kept one by one.
+	if c.RawValues != nil || c.RawWriter != nil {
+		for i := 0; i < n; i++ {
+			if c.RawValues != nil {
+				c.RawValues = append(c.RawValues, v)
+			}
+
+			if c.RawWriter != nil {
+				c.writeRaw(v)
+			}
 		}
+	}
+
+	if c.watchers != nil {
+		c.watch(v, n)
+	}
+}
 
-		c.Buckets = make([]Bucket, 1, c.BucketsLimit)
-		c.Buckets[0].Min = v
-		c.Buckets[0].Max = v
-		c.Buckets[0].Count = 1
-		c.Buckets[0].Sum = v
+// placeValue returns quantized and transformed value that is compared with bucket boundaries.
+func (c *Collector) placeValue(v float64) float64 {
+	x := v
+	if c.Quantize > 0 {
+		x = math.Round(v/c.Quantize) * c.Quantize
+	}
 
-		c.Min = v
-		c.Max = v
+	return c.Transform.fwd(x)
+}
 
-		return
+// place counts transformed value x n times in a bucket, creating a new bucket if needed,
+// it returns index of the bucket.
+func (c *Collector) place(x float64, n int, sum float64) int {
+	if len(c.Buckets) == 0 {
+		c.setDefaults()
+		c.insertBucket(0, Bucket{Count: n, Min: x, Max: x, Sum: sum})
+
+		c.Min = x
+		c.Max = x
+
+		return 0
 	}
 
-	if v < c.Min {
-		c.Buckets = append([]Bucket{{Count: 1, Min: v, Max: v, Sum: v}}, c.Buckets...)
-		c.Min = v
+	tol := c.tolerance(x)
 
-		return
+	if x < c.Min-tol {
+		c.insertBucket(0, Bucket{Count: n, Min: x, Max: x, Sum: sum})
+		c.Min = x
+
+		return 0
 	}
 
-	if v > c.Max {
-		c.Buckets = append(c.Buckets, Bucket{Count: 1, Min: v, Max: v, Sum: v})
-		c.Max = v
+	if x > c.Max+tol {
+		c.insertBucket(len(c.Buckets), Bucket{Count: n, Min: x, Max: x, Sum: sum})
+		c.Max = x
 
-		return
+		return len(c.Buckets) - 1
 	}
 
 	//  [1 3] [4 4] 5 [7 9]
 	for i, b := range c.Buckets {
-		if v >= b.Min {
-			if v <= b.Max {
-				c.Buckets[i].Count++
-				c.Buckets[i].Sum += v
+		if x >= b.Min-tol {
+			if x <= b.Max+tol {
+				c.Buckets[i].Count += n
+				c.Buckets[i].Sum += sum
 
-				return
+				return i
 			}
 		} else {
-			// Insert new bucket.
-			c.Buckets = append(c.Buckets, Bucket{})
-			copy(c.Buckets[i+1:], c.Buckets[i:])
-			c.Buckets[i] = Bucket{Count: 1, Min: v, Max: v, Sum: v}
+			c.insertBucket(i, Bucket{Count: n, Min: x, Max: x, Sum: sum})
 
-			return
+			return i
 		}
 	}
+
+	return len(c.Buckets) - 1
 }
 
-// String renders buckets value.
-func (c *Collector) String() string {
-	c.Lock()
-	defer c.Unlock()
+// allocBuckets allocates a slice of buckets, it is replaced in tests to simulate allocation failures.
+var allocBuckets = func(length, capacity int) []Bucket {
+	return make([]Bucket, length, capacity)
+}
 
-	if len(c.Buckets) == 0 {
-		return ""
+// insertBucket inserts a new bucket at index i.
+//
+// Buckets are not changed if allocation of a larger slice panics.
+func (c *Collector) insertBucket(i int, b Bucket) {
+	if c.Trace != nil {
+		c.traceInsert(b.Min, i)
 	}
 
-	nLen := printfLen("%.2f", c.Min)
-	if maxLen := printfLen("%.2f", c.Max); maxLen > nLen {
-		nLen = maxLen
+	buckets := c.Buckets
+
+	if len(buckets) == cap(buckets) {
+		capacity := 2 * cap(buckets)
+
+		if capacity == 0 {
+			capacity = c.BucketsLimit
+			if capacity > initialBucketsCap {
+				capacity = initialBucketsCap
+			}
+		}
+
+		buckets = allocBuckets(len(c.Buckets), capacity)
+		copy(buckets, c.Buckets)
+	}
+
+	buckets = buckets[:len(buckets)+1]
+	copy(buckets[i+1:], buckets[i:])
+	buckets[i] = b
+	c.Buckets = buckets
+
+	if c.TrackIDs {
+		c.insertID(i)
 	}
-	// if c.Max is +Inf, the second-largest element can be the longest.
-	if maxLen := printfLen("%.2f", c.Buckets[len(c.Buckets)-1].Min); maxLen > nLen {
-		nLen = maxLen
+
+	if c.labels != nil {
+		c.insertLabel(i)
 	}
 
-	cLen := printfLen("%d", c.Count)
-	sLen := 0
+	if c.TrackFreshness {
+		c.insertSeen(i)
+	}
+}
 
-	var res strings.Builder
+// EffectiveBucketsLimit returns BucketsLimit with defaults and clamping applied.
+func (c *Collector) EffectiveBucketsLimit() int {
+	if c == nil {
+		return 0
+	}
 
-	fmt.Fprintf(&res, "[%*s %*s] %*s total%%", nLen, "min", nLen, "max", cLen, "cnt")
+	c.Lock()
+	defer c.Unlock()
 
-	if c.PrintSum {
-		sLen = printfLen("%.2f", c.Sum)
-		fmt.Fprintf(&res, " %*s", sLen, "sum")
+	if c.BucketsLimit == 0 {
+		return clampBucketsLimit(DefaultBucketsLimit)
 	}
 
-	fmt.Fprintf(&res, " (%d events)\n", c.Count)
+	return clampBucketsLimit(c.BucketsLimit)
+}
 
-	for _, b := range c.Buckets {
-		percent := float64(100*b.Count) / float64(c.Count)
+// clampBucketsLimit returns limit within MinBucketsLimit and MaxBucketsHardLimit.
+func clampBucketsLimit(limit int) int {
+	if limit > MaxBucketsHardLimit {
+		limit = MaxBucketsHardLimit
+	}
 
-		fmt.Fprintf(&res, "[%*.2f %*.2f] %*d %5.2f%%", nLen, b.Min, nLen, b.Max, cLen, b.Count, percent)
+	if limit < MinBucketsLimit {
+		limit = MinBucketsLimit
+	}
 
-		if c.PrintSum {
-			fmt.Fprintf(&res, " %*.2f", sLen, b.Sum)
-		}
+	return limit
+}
 
-		if dots := strings.Repeat(".", int(percent)); len(dots) > 0 {
-			fmt.Fprint(&res, " ", dots)
-		}
+// setDefaults applies default configuration.
+func (c *Collector) setDefaults() {
+	if c.BucketsLimit == 0 {
+		c.BucketsLimit = DefaultBucketsLimit
+	}
+
+	c.BucketsLimit = clampBucketsLimit(c.BucketsLimit)
 
-		fmt.Fprintln(&res)
+	if c.WeightFunc == nil {
+		c.WeightFunc = AvgWidth
+	}
+}
+
+// String renders buckets value, it returns "" for a nil collector.
+func (c *Collector) String() string {
+	if c == nil {
+		return ""
 	}
 
-	return res.String()
+	return c.Render(RenderOptions{
+		PrintSum:       c.PrintSum,
+		PrintGaps:      c.PrintGaps,
+		ValueFormatter: c.ValueFormatter,
+		Percentiles:    c.PrintPercentiles,
+		StaleAfter:     c.StaleAfter,
+	})
 }
 
 // LoadFromRuntimeMetrics replaces existing buckets with data from metrics.Float64Histogram.
-func (c *Collector) LoadFromRuntimeMetrics(h *metrics.Float64Histogram) {
+//
+// Histogram has no sums, so sums of buckets are estimated with upper boundaries and SumEstimated is set.
+// Histogram must have at least two boundaries in ascending order and a count for every pair of them,
+// otherwise ErrInvalidHistogram is returned and collector is not changed. Buckets are prepared
+// before locking collector, so that concurrent Add calls wait only for the buckets to be replaced.
+func (c *Collector) LoadFromRuntimeMetrics(h *metrics.Float64Histogram) error {
+	if c == nil {
+		return ErrNilCollector
+	}
+
+	total, buckets, err := runtimeBuckets(h)
+	if err != nil {
+		return err
+	}
+
 	c.Lock()
 	defer c.Unlock()
 
-	c.Buckets = make([]Bucket, len(h.Buckets)-1)
+	c.generation++
+	c.SumEstimated = true
+	c.Buckets = buckets
+	c.labels = nil
+	c.seen = nil
+	c.resetTop()
+	c.rawPartial = true
 	c.BucketsLimit = len(h.Buckets)
-	c.Bucket = Bucket{
+	c.Bucket = total
+
+	if c.TrackIDs {
+		c.resetIDs()
+	}
+
+	return nil
+}
+
+// runtimeBuckets converts metrics.Float64Histogram to buckets with sums estimated by upper boundaries.
+func runtimeBuckets(h *metrics.Float64Histogram) (Bucket, []Bucket, error) {
+	if h == nil {
+		return Bucket{}, nil, fmt.Errorf("%w: nil histogram", ErrInvalidHistogram)
+	}
+
+	if len(h.Buckets) < 2 || len(h.Counts) != len(h.Buckets)-1 {
+		return Bucket{}, nil, fmt.Errorf("%w: %d boundaries and %d counts",
+			ErrInvalidHistogram, len(h.Buckets), len(h.Counts))
+	}
+
+	for i, b := range h.Buckets {
+		if math.IsNaN(b) || (i > 0 && b <= h.Buckets[i-1]) {
+			return Bucket{}, nil, fmt.Errorf("%w: boundary %v at %d", ErrInvalidHistogram, b, i)
+		}
+	}
+
+	buckets := make([]Bucket, len(h.Buckets)-1)
+	total := Bucket{
 		Min: h.Buckets[0],
 		Max: h.Buckets[0],
 	}
 
 	for i, b := range h.Buckets[1:] {
 		bb := Bucket{
-			Min:   c.Bucket.Max,
+			Min:   total.Max,
 			Max:   b,
 			Count: int(h.Counts[i]),
 		}
 
 		if bb.Count != 0 && !math.IsInf(b, 0) {
 			bb.Sum = float64(bb.Count) * b
-			c.Bucket.Sum += bb.Sum
+			total.Sum += bb.Sum
 		}
 
-		c.Bucket.Count += bb.Count
-		c.Bucket.Max = b
+		total.Count += bb.Count
+		total.Max = b
 
-		c.Buckets[i] = bb
+		buckets[i] = bb
 	}
+
+	return total, buckets, nil
 }
 
 func printfLen(format string, val interface{}) int {
@@ -258,19 +919,57 @@ func printfLen(format string, val interface{}) int {
 	return len(s)
 }
 
-// Percentile returns maximum boundary for a fraction of values.
+// Percentile returns a value below or equal to which a percent (0-100) of values fall.
+//
+// Value is interpolated within a bucket assuming uniform spread of values, a zero-width bucket
+// is a point mass, see Rank for the inverse function. Maximum is returned for 100 and above.
+// It returns NaN for a nil collector.
 func (c *Collector) Percentile(percent float64) float64 {
+	if c == nil {
+		return math.NaN()
+	}
+
 	c.Lock()
 	defer c.Unlock()
-	targetCount := int(percent * float64(c.Count) / 100)
 
-	count := 0
-	for _, b := range c.Buckets {
-		count += b.Count
-		if count >= targetCount {
-			return b.Max
-		}
+	return c.percentile(percent)
+}
+
+func (c *Collector) percentile(percent float64) float64 {
+	if v, ok := c.topPercentile(percent); ok {
+		return v
+	}
+
+	if percent >= 100 && c.Count > 0 {
+		return c.Transform.inv(c.Max)
+	}
+
+	return c.Transform.inv(cdf(c.Buckets).value(percent * float64(c.Count) / 100))
+}
+
+// Rank returns a percent (0-100) of values below or equal to v, it is an inverse of Percentile.
+//
+// Both functions use the same piecewise-linear model of distribution, so that Percentile(Rank(v))
+// equals v within buckets and Rank(Percentile(p)) equals p except for point masses.
+// For v in a gap between buckets Percentile(Rank(v)) is the lower boundary of the gap,
+// for p within a zero-width bucket Rank(Percentile(p)) is the percent at the end of that bucket.
+// It returns 0 if there are no values.
+func (c *Collector) Rank(v float64) float64 {
+	if c == nil {
+		return 0
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	if c.Count == 0 {
+		return 0
+	}
+
+	x := v
+	if c.Transform != nil {
+		x = c.Transform.Fwd(x)
 	}
 
-	return c.Max
+	return 100 * cdf(c.Buckets).count(x) / float64(c.Count)
 }
@@ -2,12 +2,15 @@ package dynhist_test
 
 import (
 	"fmt"
-	"math/rand"
+	"math"
 	"runtime/metrics"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"github.com/vearutop/dynhist-go"
+	"github.com/vearutop/dynhist-go/internal/dataset"
+	"github.com/vearutop/dynhist-go/internal/golden"
 )
 
 func BenchmarkCollector_Add(b *testing.B) {
@@ -73,25 +76,13 @@ func TestAvgWidth(t *testing.T) {
 	c := dynhist.Collector{
 		BucketsLimit: 10,
 	}
-	src := rand.NewSource(1)
-	r := rand.New(src) //nolint:gosec
+	r := dataset.New(1)
 
 	for i := 0; i < 10000; i++ {
 		c.Add(r.Float64())
 	}
 
-	assert.Equal(t, `[ min  max]   cnt total% (10000 events)
-[0.00 0.11]  1099 10.99% ..........
-[0.11 0.22]  1093 10.93% ..........
-[0.22 0.33]  1127 11.27% ...........
-[0.33 0.44]  1121 11.21% ...........
-[0.44 0.54]   999  9.99% .........
-[0.54 0.63]   964  9.64% .........
-[0.63 0.73]   953  9.53% .........
-[0.73 0.81]   841  8.41% ........
-[0.81 0.90]   797  7.97% .......
-[0.90 1.00]  1006 10.06% ..........
-`, c.String())
+	golden.Assert(t, "avg_width", c.String())
 }
 
 func TestAvgWidth_Concurrency(t *testing.T) {
@@ -125,36 +116,13 @@ func TestExpWidth(t *testing.T) {
 	c := dynhist.Collector{
 		WeightFunc: dynhist.ExpWidth(1.2, 0.9),
 	}
-	src := rand.NewSource(1)
-	r := rand.New(src) //nolint:gosec
+	r := dataset.New(1)
 
 	for i := 0; i < 100000; i++ {
 		c.Add(r.ExpFloat64())
 	}
 
-	assert.Equal(t,
-		`[  min   max]    cnt total% (100000 events)
-[ 0.00  0.01]    821  0.82%
-[ 0.01  0.03]   1970  1.97% .
-[ 0.03  0.06]   3259  3.26% ...
-[ 0.06  0.16]   8613  8.61% ........
-[ 0.16  0.24]   6511  6.51% ......
-[ 0.24  0.34]   7547  7.55% .......
-[ 0.34  0.59]  15568 15.57% ...............
-[ 0.59  0.77]   9219  9.22% .........
-[ 0.77  1.02]  10368 10.37% ..........
-[ 1.02  1.38]  10993 10.99% ..........
-[ 1.38  1.69]   6724  6.72% ......
-[ 1.69  2.06]   5684  5.68% .....
-[ 2.06  2.50]   4427  4.43% ....
-[ 2.50  3.05]   3524  3.52% ...
-[ 3.05  4.01]   2955  2.96% ..
-[ 4.01  5.17]   1250  1.25% .
-[ 5.17  6.56]    433  0.43%
-[ 6.58  8.43]    118  0.12%
-[ 8.47  8.50]      2  0.00%
-[ 8.59 10.80]     14  0.01%
-`, c.String())
+	golden.Assert(t, "exp_width", c.String())
 }
 
 var (
@@ -245,7 +213,7 @@ func TestLearnLatency(t *testing.T) {
 	t.Skip()
 	// points := append(latencyPoints, latencyPoints2...)
 	points := latencyPoints
-	rand.Shuffle(len(points), func(i, j int) { points[i], points[j] = points[j], points[i] })
+	dataset.New(1).Shuffle(len(points), func(i, j int) { points[i], points[j] = points[j], points[i] })
 
 	precise := dynhist.Collector{
 		BucketsLimit: len(points),
@@ -310,7 +278,7 @@ func TestCollector_LoadFromRuntimeMetrics(t *testing.T) {
 	h := samples[0].Value.Float64Histogram()
 
 	hh := dynhist.Collector{}
-	hh.LoadFromRuntimeMetrics(h)
+	require.NoError(t, hh.LoadFromRuntimeMetrics(h))
 
 	assert.Greater(t, hh.Bucket.Sum, float64(size))
 	assert.Greater(t, hh.Percentile(50), 1.0)
@@ -322,3 +290,214 @@ func TestCollector_String_empty(t *testing.T) {
 
 	assert.Equal(t, "", c.String())
 }
+
+func TestCollector_MergeStats(t *testing.T) {
+	c := dynhist.Collector{
+		BucketsLimit: 3,
+	}
+
+	for _, v := range []float64{0, 10, 5} {
+		c.Add(v)
+	}
+
+	assert.Equal(t, dynhist.MergeStats{}, c.MergeStats())
+
+	// [0 0] [1 1] [5 5] [10 10] -> [0 1] [5 5] [10 10].
+	c.Add(1)
+	assert.Equal(t, dynhist.MergeStats{Merges: 1, LastMergeWidth: 0.1, MergedWidth: 0.1}, c.MergeStats())
+
+	// [0 1] [5 5] [10 10] [20 20] -> [0 1] [5 10] [20 20].
+	c.Add(20)
+
+	st := c.MergeStats()
+	assert.Equal(t, 2, st.Merges)
+	assert.InDelta(t, 0.25, st.LastMergeWidth, 1e-9)
+	assert.InDelta(t, 0.35, st.MergedWidth, 1e-9)
+}
+
+func TestCollector_AddN(t *testing.T) {
+	c := dynhist.Collector{BucketsLimit: 3, RawValues: []float64{}}
+
+	c.AddN(1, 3)
+	c.AddN(5, 0)
+	c.AddN(5, -1)
+	c.AddN(10, 2)
+	c.AddN(3, 1)
+	c.AddN(20, 4)
+
+	assert.Equal(t, 10, c.Count)
+	assert.Equal(t, 106.0, c.Sum)
+	assert.Len(t, c.RawValues, 10)
+	assert.Equal(t, []dynhist.Bucket{
+		{Min: 1, Max: 3, Count: 4, Sum: 6},
+		{Min: 10, Max: 10, Count: 2, Sum: 20},
+		{Min: 20, Max: 20, Count: 4, Sum: 80},
+	}, c.Buckets)
+}
+
+func TestCollector_WeightFunc_nonFinite(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		weight   float64 // Weight of [6 6] [7 7].
+		expected []dynhist.Bucket
+	}{
+		{
+			name:   "positive",
+			weight: 3,
+			expected: []dynhist.Bucket{
+				{Min: 0, Max: 0, Count: 1}, {Min: 5, Max: 6, Count: 2, Sum: 11}, {Min: 7, Max: 7, Count: 1, Sum: 7},
+			},
+		},
+		{
+			name:   "negative",
+			weight: -1,
+			expected: []dynhist.Bucket{
+				{Min: 0, Max: 0, Count: 1}, {Min: 5, Max: 5, Count: 1, Sum: 5}, {Min: 6, Max: 7, Count: 2, Sum: 13},
+			},
+		},
+		{
+			name:   "-Inf",
+			weight: math.Inf(-1),
+			expected: []dynhist.Bucket{
+				{Min: 0, Max: 0, Count: 1}, {Min: 5, Max: 5, Count: 1, Sum: 5}, {Min: 6, Max: 7, Count: 2, Sum: 13},
+			},
+		},
+		{
+			name:   "NaN",
+			weight: math.NaN(),
+			expected: []dynhist.Bucket{
+				{Min: 0, Max: 0, Count: 1}, {Min: 5, Max: 6, Count: 2, Sum: 11}, {Min: 7, Max: 7, Count: 1, Sum: 7},
+			},
+		},
+	} {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			c := dynhist.Collector{
+				BucketsLimit: 3,
+				// NaN for the leftmost pair must not be preferred.
+				WeightFunc: func(b1, b2, bTot dynhist.Bucket) float64 {
+					switch {
+					case b1.Min == 0:
+						return math.NaN()
+					case b2.Min == 7:
+						return tc.weight
+					default:
+						return b2.Max - b1.Min
+					}
+				},
+			}
+
+			for _, v := range []float64{0, 5, 6, 7} {
+				c.Add(v)
+			}
+
+			assert.Equal(t, tc.expected, c.Buckets)
+		})
+	}
+}
+
+func TestCollector_StrictWeights(t *testing.T) {
+	c := dynhist.Collector{
+		BucketsLimit:  2,
+		StrictWeights: true,
+		WeightFunc: func(b1, b2, bTot dynhist.Bucket) float64 {
+			if b2.Min == 7 {
+				return math.Inf(1)
+			}
+
+			return b2.Max - b1.Min
+		},
+	}
+
+	c.Add(0)
+	c.Add(5)
+
+	assert.PanicsWithValue(t, "dynhist: WeightFunc returned +Inf for [5 5] [7 7]", func() {
+		c.Add(7)
+	})
+
+	// Collector is unlocked after panic.
+	assert.Equal(t, 3, c.BucketsCount())
+
+	c.StrictWeights = false
+	c.Add(8)
+	assert.Equal(t, 2, c.BucketsCount())
+}
+
+func TestCollector_TotalCount(t *testing.T) {
+	c := dynhist.Collector{BucketsLimit: 10}
+
+	assert.Equal(t, 0, c.TotalCount())
+	assert.Equal(t, 0.0, c.MinValue())
+	assert.Equal(t, 0.0, c.MaxValue())
+
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		for i := 1; i <= 1000; i++ {
+			c.Add(float64(i))
+		}
+	}()
+
+	// Getters are safe to call during concurrent Add.
+	for i := 0; i < 100; i++ {
+		n := c.TotalCount()
+		assert.LessOrEqual(t, c.TotalSum(), 500500.0)
+		assert.LessOrEqual(t, c.MinValue(), 1.0)
+		assert.LessOrEqual(t, c.MaxValue(), 1000.0)
+		assert.LessOrEqual(t, n, 1000)
+	}
+
+	<-done
+
+	assert.Equal(t, 1000, c.TotalCount())
+	assert.Equal(t, 500500.0, c.TotalSum())
+	assert.Equal(t, 1.0, c.MinValue())
+	assert.Equal(t, 1000.0, c.MaxValue())
+
+	lc := dynhist.Collector{Transform: dynhist.LogTransform}
+	assert.Equal(t, 0.0, lc.MinValue())
+
+	lc.Add(2)
+	lc.Add(8)
+	assert.InDelta(t, 2, lc.MinValue(), 1e-9)
+	assert.InDelta(t, 8, lc.MaxValue(), 1e-9)
+}
+
+func TestCollector_Add_allocationPanic(t *testing.T) {
+	for _, v := range []float64{0, 2.5, 10} {
+		c := dynhist.Collector{BucketsLimit: 4, RawValues: []float64{}}
+
+		for _, v := range []float64{1, 2, 3, 4} {
+			c.Add(v)
+		}
+
+		require.Equal(t, 4, cap(c.Buckets), "buckets are full")
+
+		before := fmt.Sprintf("%+v %+v %v", c.Bucket, c.Buckets, c.RawValues)
+
+		restore := dynhist.SetAllocBuckets(func(length, capacity int) []dynhist.Bucket {
+			panic("out of memory")
+		})
+
+		assert.Panics(t, func() { c.Add(v) }, v)
+		restore()
+
+		assert.Equal(t, before, fmt.Sprintf("%+v %+v %v", c.Bucket, c.Buckets, c.RawValues), v)
+
+		// Collector is unlocked and consistent.
+		c.Add(v)
+
+		cnt := 0
+		for _, b := range c.Buckets {
+			cnt += b.Count
+		}
+
+		assert.Equal(t, 5, c.Count, v)
+		assert.Equal(t, 5, cnt, v)
+		assert.Len(t, c.RawValues, 5, v)
+	}
+}
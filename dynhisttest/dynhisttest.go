@@ -0,0 +1,121 @@
+// Package dynhisttest provides test assertions for histograms.
+package dynhisttest
+
+import (
+	"math"
+	"sort"
+	"testing"
+
+	"github.com/vearutop/dynhist-go"
+)
+
+// AssertPercentileBelow checks that percentile of collector does not exceed max.
+func AssertPercentileBelow(t testing.TB, c *dynhist.Collector, percent, max float64) bool {
+	t.Helper()
+
+	if p := c.Percentile(percent); p > max {
+		t.Errorf("p%v is %v, expected not more than %v\n%s", percent, p, max, c.String())
+
+		return false
+	}
+
+	return true
+}
+
+// AssertFractionBetween checks that at least minFrac (0-1) of values is within [lo, hi].
+//
+// Fraction is estimated from buckets assuming uniform distribution within a bucket.
+func AssertFractionBetween(t testing.TB, c *dynhist.Collector, lo, hi, minFrac float64) bool {
+	t.Helper()
+
+	if frac := FractionBetween(c, lo, hi); frac < minFrac {
+		t.Errorf("fraction of values in [%v, %v] is %.4f, expected at least %v\n%s", lo, hi, frac, minFrac, c.String())
+
+		return false
+	}
+
+	return true
+}
+
+// AssertSimilar checks that Kolmogorov-Smirnov distance between distributions does not exceed maxKS.
+func AssertSimilar(t testing.TB, a, b *dynhist.Collector, maxKS float64) bool {
+	t.Helper()
+
+	if ks := KS(a, b); ks > maxKS {
+		t.Errorf("Kolmogorov-Smirnov distance is %.4f, expected not more than %v\n%s", ks, maxKS, dynhist.DiffString(a, b))
+
+		return false
+	}
+
+	return true
+}
+
+// FractionBetween returns estimated fraction (0-1) of values within [lo, hi].
+func FractionBetween(c *dynhist.Collector, lo, hi float64) float64 {
+	if hi < lo {
+		return 0
+	}
+
+	c.Lock()
+	count := c.Count
+	c.Unlock()
+
+	if count == 0 {
+		return 0
+	}
+
+	res := c.Resample([]float64{lo, hi})
+
+	return float64(res[0].Count) / float64(count)
+}
+
+// KS returns Kolmogorov-Smirnov distance, a maximum difference between cumulative distributions.
+//
+// Distributions are compared at boundaries of buckets of both collectors.
+func KS(a, b *dynhist.Collector) float64 {
+	bounds := []float64{math.Inf(-1)}
+
+	for _, c := range []*dynhist.Collector{a, b} {
+		c.Lock()
+		for _, bk := range c.Buckets {
+			bounds = append(bounds, bk.Min, bk.Max)
+		}
+		c.Unlock()
+	}
+
+	bounds = append(bounds, math.Inf(1))
+	sort.Float64s(bounds)
+
+	ca, cb := cumulative(a, bounds), cumulative(b, bounds)
+	ks := 0.0
+
+	for i := range ca {
+		if d := math.Abs(ca[i] - cb[i]); d > ks {
+			ks = d
+		}
+	}
+
+	return ks
+}
+
+// cumulative returns fractions of values up to every bound.
+func cumulative(c *dynhist.Collector, bounds []float64) []float64 {
+	res := make([]float64, len(bounds)-1)
+	cnt, total := 0, 0
+
+	ranges := c.Resample(bounds)
+	for _, r := range ranges {
+		total += r.Count
+	}
+
+	if total == 0 {
+		return res
+	}
+
+	for i, r := range ranges {
+		cnt += r.Count
+		res[i] = float64(cnt) / float64(total)
+	}
+
+	return res
+}
@@ -0,0 +1,74 @@
+package dynhisttest_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vearutop/dynhist-go"
+	"github.com/vearutop/dynhist-go/dynhisttest"
+)
+
+// recorder captures failures of assertions.
+type recorder struct {
+	testing.TB
+	errors []string
+}
+
+func (r *recorder) Helper() {}
+
+func (r *recorder) Errorf(format string, args ...interface{}) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func uniform(n int, scale float64) *dynhist.Collector {
+	c := &dynhist.Collector{BucketsLimit: 10}
+
+	for i := 0; i < n; i++ {
+		c.Add(scale * float64(i) / float64(n))
+	}
+
+	return c
+}
+
+func TestAssertPercentileBelow(t *testing.T) {
+	c := uniform(1000, 1)
+
+	assert.True(t, dynhisttest.AssertPercentileBelow(t, c, 99, 1))
+
+	r := &recorder{TB: t}
+	assert.False(t, dynhisttest.AssertPercentileBelow(r, c, 99, 0.5))
+	assert.Len(t, r.errors, 1)
+	assert.True(t, strings.HasPrefix(r.errors[0], "p99 is 0.989"), r.errors[0])
+	assert.Contains(t, r.errors[0], ", expected not more than 0.5\n[ min  max]")
+}
+
+func TestAssertFractionBetween(t *testing.T) {
+	c := uniform(1000, 1)
+
+	assert.True(t, dynhisttest.AssertFractionBetween(t, c, 0, 0.5, 0.45))
+	assert.InDelta(t, 0.5, dynhisttest.FractionBetween(c, 0, 0.5), 0.01)
+	assert.Equal(t, 0.0, dynhisttest.FractionBetween(c, 2, 1))
+
+	r := &recorder{TB: t}
+	assert.False(t, dynhisttest.AssertFractionBetween(r, c, 0, 0.5, 0.9))
+	assert.Len(t, r.errors, 1)
+	assert.Contains(t, r.errors[0], "fraction of values in [0, 0.5] is 0.")
+}
+
+func TestAssertSimilar(t *testing.T) {
+	a, b := uniform(1000, 1), uniform(3000, 1)
+
+	assert.True(t, dynhisttest.AssertSimilar(t, a, b, 0.02))
+	assert.Equal(t, 0.0, dynhisttest.KS(a, a))
+
+	c := uniform(1000, 2)
+	assert.InDelta(t, 0.5, dynhisttest.KS(a, c), 0.01)
+
+	r := &recorder{TB: t}
+	assert.False(t, dynhisttest.AssertSimilar(r, a, c, 0.1))
+	assert.Len(t, r.errors, 1)
+	assert.Contains(t, r.errors[0], "Kolmogorov-Smirnov distance is 0.5")
+	assert.Contains(t, r.errors[0], "count 1000 -> 1000 (+0)")
+}
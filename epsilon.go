@@ -0,0 +1,33 @@
+package dynhist
+
+import "math"
+
+// EpsilonMode defines how Collector.Epsilon is applied.
+type EpsilonMode int
+
+// EpsilonMode values.
+const (
+	// AbsoluteEpsilon uses Epsilon as is.
+	AbsoluteEpsilon EpsilonMode = iota
+
+	// RelativeEpsilon multiplies Epsilon by absolute value being compared.
+	RelativeEpsilon
+)
+
+// tolerance returns comparison tolerance for value.
+func (c *Collector) tolerance(v float64) float64 {
+	if c.Epsilon == 0 {
+		return 0
+	}
+
+	if c.EpsilonMode == RelativeEpsilon {
+		return c.Epsilon * math.Abs(v)
+	}
+
+	return c.Epsilon
+}
+
+// nearDuplicate checks whether gap between adjacent buckets is within tolerance.
+func (c *Collector) nearDuplicate(b1, b2 Bucket) bool {
+	return b2.Min-b1.Max <= c.tolerance(b1.Max)
+}
@@ -0,0 +1,74 @@
+package dynhist_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vearutop/dynhist-go"
+)
+
+func TestCollector_Epsilon(t *testing.T) {
+	c := dynhist.Collector{Epsilon: 0.01}
+
+	c.Add(1)
+	c.Add(1.01)  // At tolerance.
+	c.Add(0.99)  // At tolerance.
+	c.Add(1.011) // Outside of tolerance.
+	c.Add(0.989) // Outside of tolerance.
+
+	require.Len(t, c.Buckets, 3)
+	assert.Equal(t, dynhist.Bucket{Min: 0.989, Max: 0.989, Count: 1, Sum: 0.989}, c.Buckets[0])
+	assert.Equal(t, dynhist.Bucket{Min: 1, Max: 1, Count: 3, Sum: 3}, c.Buckets[1])
+	assert.Equal(t, dynhist.Bucket{Min: 1.011, Max: 1.011, Count: 1, Sum: 1.011}, c.Buckets[2])
+}
+
+func TestCollector_Epsilon_relative(t *testing.T) {
+	c := dynhist.Collector{Epsilon: 0.01, EpsilonMode: dynhist.RelativeEpsilon}
+
+	c.Add(1000)
+	c.Add(1009)
+	c.Add(1011)
+	c.Add(1)
+	c.Add(1.009)
+
+	require.Len(t, c.Buckets, 3)
+	assert.Equal(t, 2, c.Buckets[0].Count)
+	assert.Equal(t, 2, c.Buckets[1].Count)
+	assert.Equal(t, 1, c.Buckets[2].Count)
+}
+
+func TestCollector_Epsilon_nearDuplicates(t *testing.T) {
+	a, b, c := 0.1, 0.2, 0.4
+	values := []float64{a, b, a + b - b, 0.3, a * 3, 0.7 - c}
+
+	exact := dynhist.Collector{BucketsLimit: 3}
+	tolerant := dynhist.Collector{BucketsLimit: 3, Epsilon: 1e-9}
+
+	for _, v := range values {
+		exact.Add(v)
+		tolerant.Add(v)
+	}
+
+	// Float arithmetic produces separate buckets next to 0.1 and 0.3 and forces merges.
+	assert.Equal(t, 3, exact.Merges)
+	assert.Equal(t, 0, tolerant.Merges)
+	require.Len(t, tolerant.Buckets, 3)
+	assert.Equal(t, []int{2, 1, 3}, []int{tolerant.Buckets[0].Count, tolerant.Buckets[1].Count, tolerant.Buckets[2].Count})
+	assert.Equal(t, 0.3, tolerant.Buckets[2].Min)
+}
+
+func TestCollector_LoadBuckets_epsilon(t *testing.T) {
+	c := dynhist.Collector{Epsilon: 1e-9}
+
+	require.NoError(t, c.LoadBuckets([]dynhist.Bucket{
+		{Min: 0.09999999999999998, Max: 0.09999999999999998, Count: 1, Sum: 0.09999999999999998},
+		{Min: 0.1, Max: 0.1, Count: 2, Sum: 0.2},
+		{Min: 0.3, Max: 0.4, Count: 1, Sum: 0.35},
+	}))
+
+	require.Len(t, c.Buckets, 2)
+	assert.Equal(t, 3, c.Buckets[0].Count)
+	assert.Equal(t, 0.1, c.Buckets[0].Max)
+	assert.Equal(t, 4, c.Count)
+}
@@ -0,0 +1,166 @@
+package dynhist
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+var (
+	// ErrUnsortedBuckets is returned when buckets are not sorted by boundaries in ascending order.
+	ErrUnsortedBuckets = errors.New("buckets are not sorted")
+
+	// ErrOverlappingBuckets is returned when boundaries of adjacent buckets intersect.
+	ErrOverlappingBuckets = errors.New("buckets overlap")
+
+	// ErrCountMismatch is returned when total count does not match the sum of bucket counts.
+	ErrCountMismatch = errors.New("count mismatch")
+
+	// ErrInvalidBucket is returned for a bucket with invalid boundaries or count.
+	ErrInvalidBucket = errors.New("invalid bucket")
+
+	// ErrNilCollector is returned by methods that load or export data when called on a nil *Collector.
+	ErrNilCollector = errors.New("nil collector")
+)
+
+// BucketError describes a problem with a particular bucket.
+type BucketError struct {
+	// Index is the position of offending bucket.
+	Index int
+
+	// Err is one of ErrUnsortedBuckets, ErrOverlappingBuckets, ErrInvalidBucket.
+	Err error
+}
+
+// Error implements error.
+func (e BucketError) Error() string {
+	return fmt.Sprintf("bucket %d: %v", e.Index, e.Err)
+}
+
+// Unwrap returns underlying error.
+func (e BucketError) Unwrap() error {
+	return e.Err
+}
+
+// ValidateBuckets checks that buckets are well-formed, sorted and do not overlap.
+//
+// Adjacent buckets of non-zero width may share a boundary, for example [1 2] [2 4]
+// of contiguous histograms, a zero-width bucket must not touch another bucket.
+func ValidateBuckets(buckets []Bucket) error {
+	for i, b := range buckets {
+		if math.IsNaN(b.Min) || math.IsNaN(b.Max) || math.IsNaN(b.Sum) || b.Min > b.Max || b.Count < 0 {
+			return BucketError{Index: i, Err: ErrInvalidBucket}
+		}
+
+		if i == 0 {
+			continue
+		}
+
+		prev := buckets[i-1]
+
+		if b.Min < prev.Min {
+			return BucketError{Index: i, Err: ErrUnsortedBuckets}
+		}
+
+		if b.Min < prev.Max || (b.Min == prev.Max && (prev.Min == prev.Max || b.Min == b.Max)) {
+			return BucketError{Index: i, Err: ErrOverlappingBuckets}
+		}
+	}
+
+	return nil
+}
+
+// Validate checks consistency of buckets and totals.
+func (c *Collector) Validate() error {
+	if c == nil {
+		return ErrNilCollector
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	return validate(c.Bucket, c.Buckets)
+}
+
+func validate(total Bucket, buckets []Bucket) error {
+	if err := ValidateBuckets(buckets); err != nil {
+		return err
+	}
+
+	cnt := 0
+	for _, b := range buckets {
+		cnt += b.Count
+	}
+
+	if cnt != total.Count {
+		return fmt.Errorf("%w: total %d, buckets %d", ErrCountMismatch, total.Count, cnt)
+	}
+
+	return nil
+}
+
+// LoadBuckets replaces existing buckets with a validated copy of provided buckets.
+//
+// Totals are recalculated from buckets, collector is left unmodified if buckets are malformed.
+// Adjacent buckets within Epsilon of each other are combined.
+func (c *Collector) LoadBuckets(buckets []Bucket) error {
+	if c == nil {
+		return ErrNilCollector
+	}
+
+	if err := ValidateBuckets(buckets); err != nil {
+		return err
+	}
+
+	var total Bucket
+
+	for i, b := range buckets {
+		if i == 0 {
+			total.Min = b.Min
+		}
+
+		total.Max = b.Max
+		total.Count += b.Count
+		total.Sum += b.Sum
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	buckets = append([]Bucket(nil), buckets...)
+	c.Transform.inward(&total, buckets)
+
+	c.generation++
+	c.Bucket = total
+	c.Buckets = make([]Bucket, 0, len(buckets))
+	c.labels = nil
+	c.seen = nil
+	c.resetTop()
+	c.rawPartial = true
+
+	for _, b := range buckets {
+		if n := len(c.Buckets); n > 0 && c.Epsilon > 0 && c.nearDuplicate(c.Buckets[n-1], b) {
+			c.Buckets[n-1].Max = b.Max
+			c.Buckets[n-1].Count += b.Count
+			c.Buckets[n-1].Sum += b.Sum
+
+			continue
+		}
+
+		c.Buckets = append(c.Buckets, b)
+	}
+
+	if c.TrackIDs {
+		c.resetIDs()
+	}
+
+	if c.BucketsLimit < len(buckets) {
+		c.BucketsLimit = len(buckets)
+	}
+
+	if c.WeightFunc == nil {
+		c.WeightFunc = AvgWidth
+	}
+
+	return nil
+}
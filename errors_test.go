@@ -0,0 +1,168 @@
+package dynhist_test
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"runtime/metrics"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vearutop/dynhist-go"
+)
+
+func TestValidateBuckets(t *testing.T) {
+	valid := []dynhist.Bucket{
+		{Min: 1, Max: 2, Count: 2, Sum: 3},
+		{Min: 3, Max: 3, Count: 1, Sum: 3},
+		{Min: 4, Max: 6, Count: 2, Sum: 10},
+	}
+
+	for _, tc := range []struct {
+		name    string
+		buckets []dynhist.Bucket
+		err     error
+		index   int
+	}{
+		{
+			name:    "unsorted",
+			buckets: []dynhist.Bucket{valid[0], valid[2], valid[1]},
+			err:     dynhist.ErrUnsortedBuckets,
+			index:   2,
+		},
+		{
+			name:    "overlapping",
+			buckets: []dynhist.Bucket{valid[0], {Min: 1.5, Max: 3, Count: 1, Sum: 2}},
+			err:     dynhist.ErrOverlappingBuckets,
+			index:   1,
+		},
+		{
+			name:    "point at boundary",
+			buckets: []dynhist.Bucket{valid[0], {Min: 2, Max: 2, Count: 1, Sum: 2}},
+			err:     dynhist.ErrOverlappingBuckets,
+			index:   1,
+		},
+		{
+			name:    "repeated point",
+			buckets: []dynhist.Bucket{valid[1], valid[1]},
+			err:     dynhist.ErrOverlappingBuckets,
+			index:   1,
+		},
+		{
+			name:    "inverted",
+			buckets: []dynhist.Bucket{valid[0], {Min: 5, Max: 4, Count: 1, Sum: 5}},
+			err:     dynhist.ErrInvalidBucket,
+			index:   1,
+		},
+		{
+			name:    "negative count",
+			buckets: []dynhist.Bucket{{Min: 1, Max: 2, Count: -1}},
+			err:     dynhist.ErrInvalidBucket,
+			index:   0,
+		},
+		{
+			name:    "nan",
+			buckets: []dynhist.Bucket{valid[0], {Min: math.NaN(), Max: 4, Count: 1}},
+			err:     dynhist.ErrInvalidBucket,
+			index:   1,
+		},
+	} {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			err := dynhist.ValidateBuckets(tc.buckets)
+			assert.True(t, errors.Is(err, tc.err), err)
+
+			var be dynhist.BucketError
+
+			require.True(t, errors.As(err, &be))
+			assert.Equal(t, tc.index, be.Index)
+
+			c := dynhist.Collector{}
+			c.Add(10)
+			c.Add(20)
+
+			before := c.String()
+
+			err = c.LoadBuckets(tc.buckets)
+			assert.True(t, errors.Is(err, tc.err), err)
+			assert.Equal(t, before, c.String(), "collector modified on failure")
+
+			c.Buckets = tc.buckets
+			err = c.Validate()
+			assert.True(t, errors.Is(err, tc.err), err)
+		})
+	}
+
+	assert.NoError(t, dynhist.ValidateBuckets(valid))
+	assert.NoError(t, dynhist.ValidateBuckets([]dynhist.Bucket{
+		{Min: 1, Max: 2, Count: 2, Sum: 3},
+		{Min: 2, Max: 4, Count: 2, Sum: 6},
+	}), "shared boundary")
+}
+
+func TestValidateBuckets_contiguous(t *testing.T) {
+	r := &dynhist.Collector{}
+	require.NoError(t, r.LoadFromRuntimeMetrics(&metrics.Float64Histogram{
+		Buckets: []float64{0, 1, 2, 4, 8},
+		Counts:  []uint64{3, 0, 5, 2},
+	}))
+
+	s := &dynhist.Collector{}
+	for i := 0; i < 100; i++ {
+		s.Add(float64(i))
+	}
+
+	resampled := &dynhist.Collector{}
+	require.NoError(t, resampled.LoadBuckets(s.Resample([]float64{0, 10, 50, 99})))
+
+	for name, c := range map[string]*dynhist.Collector{"runtime": r, "resample": resampled} {
+		require.NoError(t, c.Validate(), name)
+
+		j, err := json.Marshal(c)
+		require.NoError(t, err, name)
+
+		var decoded dynhist.Collector
+
+		require.NoError(t, json.Unmarshal(j, &decoded), name)
+		assert.Equal(t, c.Buckets, decoded.Buckets, name)
+
+		u, err := dynhist.DecodeURL(c.EncodeURL())
+		require.NoError(t, err, name)
+		assert.Equal(t, c.Buckets, u.Buckets, name)
+	}
+}
+
+func TestCollector_Validate(t *testing.T) {
+	c := dynhist.Collector{}
+
+	for i := 0; i < 100; i++ {
+		c.Add(float64(i))
+	}
+
+	assert.NoError(t, c.Validate())
+
+	c.Count++
+
+	assert.True(t, errors.Is(c.Validate(), dynhist.ErrCountMismatch))
+}
+
+func TestCollector_LoadBuckets(t *testing.T) {
+	c := dynhist.Collector{}
+
+	require.NoError(t, c.LoadBuckets([]dynhist.Bucket{
+		{Min: 1, Max: 2, Count: 2, Sum: 3},
+		{Min: 4, Max: 6, Count: 2, Sum: 10},
+	}))
+
+	assert.Equal(t, 4, c.Count)
+	assert.Equal(t, 13.0, c.Sum)
+	assert.Equal(t, 1.0, c.Min)
+	assert.Equal(t, 6.0, c.Max)
+	assert.NoError(t, c.Validate())
+
+	c.Add(3)
+	assert.Equal(t, 5, c.Count)
+	assert.NoError(t, c.Validate())
+}
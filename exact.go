@@ -0,0 +1,105 @@
+package dynhist
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+)
+
+// ErrTooManyValues is returned by ExactFromValues when number of values exceeds MaxExactValues.
+var ErrTooManyValues = errors.New("too many values for exact histogram")
+
+// MaxExactValues limits number of values accepted by ExactFromValues.
+//
+// Exact histogram keeps a sorted copy of all values, so memory usage is 8 bytes per value.
+// Change it before ExactFromValues is used.
+var MaxExactValues = 10000000
+
+// ExactFromValues returns a collector of values with equal-width buckets for comparison with approximation.
+//
+// Range of values is divided into the number of equal intervals, every non-empty interval becomes
+// a bucket with exact count and sum, and boundaries at the smallest and the largest value within
+// interval, so that buckets do not overlap. A sorted copy of values is kept in RawValues,
+// see AccuracyAgainst. More than MaxExactValues values result in ErrTooManyValues,
+// NaN or infinite values in ErrInvalidBucket.
+func ExactFromValues(vs []float64, buckets int) (*Collector, error) {
+	if len(vs) > MaxExactValues {
+		return nil, fmt.Errorf("%w: %d values, limit is %d", ErrTooManyValues, len(vs), MaxExactValues)
+	}
+
+	buckets = clampBucketsLimit(buckets)
+	c := &Collector{BucketsLimit: buckets}
+
+	sorted := append(make([]float64, 0, len(vs)), vs...)
+	sort.Float64s(sorted)
+
+	for i, v := range sorted {
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return nil, fmt.Errorf("%w: value %v at %d", ErrInvalidBucket, v, i)
+		}
+	}
+
+	if len(sorted) == 0 {
+		return c, nil
+	}
+
+	min, max := sorted[0], sorted[len(sorted)-1]
+	width := (max - min) / float64(buckets)
+	res := make([]Bucket, 0, buckets)
+	idx := -1
+
+	for _, v := range sorted {
+		i := buckets - 1
+
+		if width > 0 && v < max {
+			i = int((v - min) / width)
+			if i >= buckets {
+				i = buckets - 1
+			}
+		}
+
+		if i != idx {
+			res = append(res, Bucket{Min: v})
+			idx = i
+		}
+
+		b := &res[len(res)-1]
+		b.Max = v
+		b.Count++
+		b.Sum += v
+	}
+
+	if err := c.LoadBuckets(res); err != nil {
+		return nil, err
+	}
+
+	c.RawValues = sorted
+
+	return c, nil
+}
+
+// AccuracyAgainst compares percentiles estimated from buckets with exact values from RawValues of exact,
+// for example a collector made with ExactFromValues of the same values.
+//
+// It returns nil if exact is nil or its RawValues are not enabled or empty.
+func (c *Collector) AccuracyAgainst(exact *Collector, ps []float64) []AccuracyEntry {
+	if c == nil || exact == nil {
+		return nil
+	}
+
+	exact.Lock()
+	sorted := append([]float64(nil), exact.RawValues...)
+	exact.Unlock()
+
+	if len(sorted) == 0 {
+		return nil
+	}
+
+	sort.Float64s(sorted)
+
+	c.Lock()
+	defer c.Unlock()
+
+	return c.accuracy(sorted, ps)
+}
@@ -0,0 +1,75 @@
+package dynhist_test
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vearutop/dynhist-go"
+)
+
+func TestExactFromValues(t *testing.T) {
+	values := []float64{100, 3, 1, 2, 4, 5, 6, 7, 8, 9, 10, 30, 60}
+
+	exact, err := dynhist.ExactFromValues(values, 4)
+	require.NoError(t, err)
+
+	// Width of intervals is 24.75.
+	assert.Equal(t, []dynhist.Bucket{
+		{Min: 1, Max: 10, Count: 10, Sum: 55},
+		{Min: 30, Max: 30, Count: 1, Sum: 30},
+		{Min: 60, Max: 60, Count: 1, Sum: 60},
+		{Min: 100, Max: 100, Count: 1, Sum: 100},
+	}, exact.Buckets)
+	assert.Equal(t, 13, exact.Count)
+	assert.Equal(t, 245.0, exact.Sum)
+	assert.Equal(t, []float64{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 30, 60, 100}, exact.RawValues)
+	assert.Equal(t, 100.0, values[0], "input is not changed")
+
+	approx := dynhist.Collector{BucketsLimit: 2}
+	for _, v := range values {
+		approx.Add(v)
+	}
+
+	report := approx.AccuracyAgainst(exact, []float64{50, 90, 100})
+	require.Len(t, report, 3)
+
+	for i, e := range report {
+		assert.Equal(t, []float64{7, 60, 100}[i], e.Exact, e.Percentile)
+		assert.Equal(t, approx.Percentile(e.Percentile), e.Estimated, e.Percentile)
+		assert.InDelta(t, math.Abs(e.Estimated-e.Exact), e.AbsError, 1e-9, e.Percentile)
+		assert.InDelta(t, e.AbsError/e.Exact, e.RelError, 1e-9, e.Percentile)
+	}
+
+	assert.InDelta(t, 11.14, report[0].AbsError, 0.01)
+	assert.InDelta(t, 14, report[1].AbsError, 1e-6)
+	assert.InDelta(t, 0, report[2].AbsError, 1e-6)
+	assert.Nil(t, approx.AccuracyAgainst(&approx, []float64{50}), "RawValues are not enabled")
+}
+
+func TestExactFromValues_errors(t *testing.T) {
+	c, err := dynhist.ExactFromValues(nil, 10)
+	require.NoError(t, err)
+	assert.Equal(t, 0, c.Count)
+
+	c, err = dynhist.ExactFromValues([]float64{5, 5, 5}, 10)
+	require.NoError(t, err)
+	assert.Equal(t, []dynhist.Bucket{{Min: 5, Max: 5, Count: 3, Sum: 15}}, c.Buckets)
+
+	_, err = dynhist.ExactFromValues([]float64{1, math.NaN()}, 10)
+	assert.True(t, errors.Is(err, dynhist.ErrInvalidBucket), err)
+
+	_, err = dynhist.ExactFromValues([]float64{1, math.Inf(1)}, 10)
+	assert.True(t, errors.Is(err, dynhist.ErrInvalidBucket), err)
+
+	prev := dynhist.MaxExactValues
+	dynhist.MaxExactValues = 3
+
+	defer func() { dynhist.MaxExactValues = prev }()
+
+	_, err = dynhist.ExactFromValues([]float64{1, 2, 3, 4}, 10)
+	assert.True(t, errors.Is(err, dynhist.ErrTooManyValues), err)
+	assert.EqualError(t, err, "too many values for exact histogram: 4 values, limit is 3")
+}
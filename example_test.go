@@ -2,60 +2,79 @@ package dynhist_test
 
 import (
 	"fmt"
-	"math/rand"
+	"math"
 
 	"github.com/vearutop/dynhist-go"
 )
 
 func ExampleAvgWidth() {
-	c := dynhist.Collector{
-		BucketsLimit: 10,
+	// Uniformly distributed values in mixed order.
+	values := make([]float64, 10000)
+	for i := range values {
+		values[i] = float64(i*7919%10000) / 10000
 	}
-	src := rand.NewSource(1)
-	r := rand.New(src)
 
-	for i := 0; i < 10000; i++ {
-		c.Add(r.Float64())
-	}
+	c := dynhist.BuildDeterministic(values, 10, dynhist.AvgWidth)
 
 	fmt.Println(c.String())
 	// Output:
 	// [ min  max]   cnt total% (10000 events)
-	// [0.00 0.11]  1099 10.99% ..........
-	// [0.11 0.22]  1093 10.93% ..........
-	// [0.22 0.33]  1127 11.27% ...........
-	// [0.33 0.44]  1121 11.21% ...........
-	// [0.44 0.54]   999  9.99% .........
-	// [0.54 0.63]   964  9.64% .........
-	// [0.63 0.73]   953  9.53% .........
-	// [0.73 0.81]   841  8.41% ........
-	// [0.81 0.90]   797  7.97% .......
-	// [0.90 1.00]  1006 10.06% ..........
+	// [0.00 0.10]   972  9.72% .........
+	// [0.10 0.20]   986  9.86% .........
+	// [0.20 0.30]  1000 10.00% ..........
+	// [0.30 0.40]  1001 10.01% ..........
+	// [0.40 0.50]  1001 10.01% ..........
+	// [0.50 0.60]  1025 10.25% ..........
+	// [0.60 0.70]  1032 10.32% ..........
+	// [0.70 0.80]  1032 10.32% ..........
+	// [0.80 0.90]   973  9.73% .........
+	// [0.90 1.00]   978  9.78% .........
 }
 
 func ExampleExpWidth() {
-	c := dynhist.Collector{
-		BucketsLimit: 10,
-		WeightFunc:   dynhist.ExpWidth(1.2, 0.9),
+	// Exponentially distributed values.
+	values := make([]float64, 100000)
+	for i := range values {
+		values[i] = math.Log(100000 / float64(100000-i*7919%100000))
 	}
-	src := rand.NewSource(1)
-	r := rand.New(src)
 
-	for i := 0; i < 100000; i++ {
-		c.Add(r.ExpFloat64())
-	}
+	c := dynhist.BuildDeterministic(values, 10, dynhist.ExpWidth(1.2, 0.9))
 
 	fmt.Println(c.String())
 	// Output:
 	// [  min   max]    cnt total% (100000 events)
-	// [ 0.00  0.07]   6577  6.58% ......
-	// [ 0.07  0.22]  13380 13.38% .............
-	// [ 0.22  0.45]  16002 16.00% ................
-	// [ 0.45  1.11]  31072 31.07% ...............................
-	// [ 1.11  1.77]  15975 15.97% ...............
-	// [ 1.77  2.78]  10737 10.74% ..........
-	// [ 2.78  4.37]   4993  4.99% ....
-	// [ 4.37  6.50]   1121  1.12% .
-	// [ 6.51  8.96]    134  0.13%
-	// [ 9.03 10.80]      9  0.01%
+	// [ 0.00  0.05]   4606  4.61% ....
+	// [ 0.05  0.16]  10527 10.53% ..........
+	// [ 0.16  0.58]  28830 28.83% ............................
+	// [ 0.58  1.30]  28851 28.85% ............................
+	// [ 1.30  1.98]  13392 13.39% .............
+	// [ 1.98  2.88]   8175  8.18% ........
+	// [ 2.88  4.17]   4074  4.07% ....
+	// [ 4.17  5.87]   1263  1.26% .
+	// [ 5.87  8.33]    259  0.26%
+	// [ 8.38 11.51]     23  0.02%
+}
+
+func ExampleBySum() {
+	// Response sizes in KB: many small responses and a few large downloads.
+	values := make([]float64, 1000)
+	for i := range values {
+		switch {
+		case i%200 == 0:
+			values[i] = 5000
+		case i%10 == 0:
+			values[i] = float64(100 + i%7)
+		default:
+			values[i] = float64(1 + i%5)
+		}
+	}
+
+	c := dynhist.BuildDeterministic(values, 3, nil)
+
+	fmt.Println(c.Render(dynhist.RenderOptions{Weighting: dynhist.BySum}))
+	// Output:
+	// [    min     max]  cnt   sum% (1000 events, sum 37685.00)
+	// [   1.00    5.00]  900  7.70% .......
+	// [ 100.00  106.00]   95 25.97% .........................
+	// [5000.00 5000.00]    5 66.34% ..................................................................
 }
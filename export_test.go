@@ -0,0 +1,45 @@
+package dynhist
+
+import "time"
+
+// SetTicker replaces ticker constructor and returns a function to restore it.
+func SetTicker(f func(d time.Duration) (<-chan time.Time, func())) func() {
+	prev := newTicker
+	newTicker = f
+
+	return func() {
+		newTicker = prev
+	}
+}
+
+// SetNow replaces clock and returns a function to restore it.
+func SetNow(f func() time.Time) func() {
+	prev := now
+	now = f
+
+	return func() {
+		now = prev
+	}
+}
+
+// MergeDown merges buckets down to limit with a heap of weighted pairs.
+func (c *Collector) MergeDown(limit int) {
+	c.mergeDown(limit)
+}
+
+// MergeDownNaive merges buckets down to limit reweighing all pairs for every merge.
+func (c *Collector) MergeDownNaive(limit int) {
+	for len(c.Buckets) > limit {
+		c.mergeOnce()
+	}
+}
+
+// SetAllocBuckets replaces allocation of buckets and returns a function to restore it.
+func SetAllocBuckets(f func(length, capacity int) []Bucket) func() {
+	prev := allocBuckets
+	allocBuckets = f
+
+	return func() {
+		allocBuckets = prev
+	}
+}
@@ -0,0 +1,102 @@
+package dynhist
+
+import "time"
+
+// Freshness returns age of the newest value in the bucket that determines percentile (0-100).
+//
+// It requires TrackFreshness, 0 is returned if it is disabled, if there are no values,
+// or if the time of the bucket is unknown (buckets loaded with LoadBuckets, Merge, etc.).
+// A stale tail percentile means that recent values did not reach the tail, for example
+// after a burst followed by silence p99 is stale while p50 is fresh if idle traffic continues.
+func (c *Collector) Freshness(percent float64) time.Duration {
+	if c == nil {
+		return 0
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	return c.freshness(percent, now())
+}
+
+func (c *Collector) freshness(percent float64, at time.Time) time.Duration {
+	if !c.TrackFreshness || c.Count == 0 || len(c.seen) != len(c.Buckets) {
+		return 0
+	}
+
+	cur := cdfCursor{m: c.Buckets}
+	cur.value(percent * float64(c.Count) / 100)
+
+	i := cur.i
+	if i >= len(c.Buckets) {
+		i = len(c.Buckets) - 1
+	}
+
+	if c.seen[i] == 0 {
+		return 0
+	}
+
+	return at.Sub(time.Unix(0, c.seen[i]))
+}
+
+// touchSeen sets time of the newest value of bucket at index i.
+func (c *Collector) touchSeen(i int) {
+	if len(c.seen) != len(c.Buckets) {
+		c.seen = make([]int64, len(c.Buckets))
+	}
+
+	c.seen[i] = now().UnixNano()
+}
+
+// insertSeen adds unknown time for a new bucket at index i.
+func (c *Collector) insertSeen(i int) {
+	if len(c.seen) != len(c.Buckets)-1 {
+		c.seen = make([]int64, len(c.Buckets))
+
+		return
+	}
+
+	c.seen = append(c.seen, 0)
+	copy(c.seen[i+1:], c.seen[i:])
+	c.seen[i] = 0
+}
+
+// mergeSeen keeps the newest time of buckets merged at mergePoint-1.
+func (c *Collector) mergeSeen(mergePoint int) {
+	if len(c.seen) != len(c.Buckets)+1 {
+		c.seen = make([]int64, len(c.Buckets))
+
+		return
+	}
+
+	newest := c.seen[mergePoint-1]
+	if c.seen[mergePoint] > newest {
+		newest = c.seen[mergePoint]
+	}
+
+	c.seen = append(c.seen[:mergePoint-1], c.seen[mergePoint:]...)
+	c.seen[mergePoint-1] = newest
+}
+
+// percentileLine is a rendered percentile with age of its bucket.
+type percentileLine struct {
+	percent float64
+	value   float64
+	age     time.Duration
+}
+
+// percentileLines computes percentiles for the footer of Render.
+func (c *Collector) percentileLines(percents []float64) []percentileLine {
+	if len(percents) == 0 || c.Count == 0 {
+		return nil
+	}
+
+	at := now()
+	res := make([]percentileLine, 0, len(percents))
+
+	for _, p := range percents {
+		res = append(res, percentileLine{percent: p, value: c.percentile(p), age: c.freshness(p, at)})
+	}
+
+	return res
+}
@@ -0,0 +1,82 @@
+package dynhist_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vearutop/dynhist-go"
+	"github.com/vearutop/dynhist-go/internal/dataset"
+)
+
+func TestCollector_Freshness(t *testing.T) {
+	ts := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	defer dynhist.SetNow(func() time.Time { return ts })()
+
+	r := dataset.New(1)
+	c := &dynhist.Collector{
+		TrackFreshness:   true,
+		PrintPercentiles: []float64{50, 90, 99},
+		StaleAfter:       5 * time.Minute,
+	}
+
+	assert.Equal(t, time.Duration(0), c.Freshness(50))
+
+	// Burst of values with a long tail.
+	for i := 0; i < 10000; i++ {
+		c.Add(r.ExpFloat64() * 100)
+	}
+
+	assert.Equal(t, time.Duration(0), c.Freshness(99))
+	assert.NotContains(t, c.String(), "stale")
+
+	// Idle traffic of small values only.
+	for m := 1; m <= 10; m++ {
+		ts = ts.Add(time.Minute)
+
+		for i := 0; i < 10; i++ {
+			c.Add(r.Float64() * 100)
+		}
+
+		p50, p90, p99 := c.Freshness(50), c.Freshness(90), c.Freshness(99)
+
+		assert.LessOrEqual(t, int64(p50), int64(p90), m)
+		assert.LessOrEqual(t, int64(p90), int64(p99), m)
+		assert.Equal(t, time.Duration(0), p50, m)
+		assert.Equal(t, time.Duration(m)*time.Minute, p99, m)
+	}
+
+	s := c.String()
+	assert.Contains(t, s, "(stale, last value 10m0s ago)")
+
+	for _, line := range strings.Split(s, "\n") {
+		switch {
+		case strings.HasPrefix(line, "p50: "):
+			assert.NotContains(t, line, "stale")
+		case strings.HasPrefix(line, "p99: "):
+			assert.Contains(t, line, "stale")
+		}
+	}
+
+	hidden := c.Render(dynhist.RenderOptions{Percentiles: []float64{50, 99}, StaleAfter: time.Minute, HideStale: true})
+	assert.Contains(t, hidden, "p50: ")
+	assert.NotContains(t, hidden, "p99: ")
+
+	// Silence makes all percentiles stale.
+	ts = ts.Add(time.Hour)
+
+	assert.Equal(t, time.Hour, c.Freshness(50))
+	assert.Equal(t, 70*time.Minute, c.Freshness(99))
+}
+
+func TestCollector_Freshness_untracked(t *testing.T) {
+	c := &dynhist.Collector{}
+	c.Add(1)
+
+	assert.Equal(t, time.Duration(0), c.Freshness(50))
+
+	l := &dynhist.Collector{TrackFreshness: true}
+	assert.NoError(t, l.LoadBuckets([]dynhist.Bucket{{Min: 1, Max: 2, Count: 1, Sum: 1.5}}))
+	assert.Equal(t, time.Duration(0), l.Freshness(50))
+}
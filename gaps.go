@@ -0,0 +1,41 @@
+package dynhist
+
+// Coverage returns total width of buckets and width of value range [Min, Max].
+//
+// Widths are in original units, a difference is a width of gaps between buckets.
+// Both are zero if there are no values or all values are equal.
+func (c *Collector) Coverage() (coveredWidth, totalWidth float64) {
+	_, buckets := c.snapshotBuckets()
+
+	return coverage(buckets)
+}
+
+// GapFraction returns a share (0-1) of value range not covered by buckets.
+//
+// A large share indicates clustered or multimodal data. It returns 0 if value range is empty.
+func (c *Collector) GapFraction() float64 {
+	_, buckets := c.snapshotBuckets()
+
+	return gapFraction(buckets)
+}
+
+func coverage(buckets []Bucket) (covered, total float64) {
+	if len(buckets) == 0 {
+		return 0, 0
+	}
+
+	for _, b := range buckets {
+		covered += b.Max - b.Min
+	}
+
+	return covered, buckets[len(buckets)-1].Max - buckets[0].Min
+}
+
+func gapFraction(buckets []Bucket) float64 {
+	covered, total := coverage(buckets)
+	if total <= 0 {
+		return 0
+	}
+
+	return 1 - covered/total
+}
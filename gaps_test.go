@@ -0,0 +1,61 @@
+package dynhist_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vearutop/dynhist-go"
+	"github.com/vearutop/dynhist-go/internal/dataset"
+)
+
+func TestCollector_GapFraction(t *testing.T) {
+	c := dynhist.Collector{BucketsLimit: 10}
+	r := dataset.New(1)
+
+	for i := 0; i < 10000; i++ {
+		c.Add(r.Float64())
+	}
+
+	assert.Less(t, c.GapFraction(), 0.01)
+
+	c = dynhist.Collector{BucketsLimit: 10}
+
+	for i := 0; i < 10000; i++ {
+		v := 10 + r.Float64()
+		if i%2 == 0 {
+			v += 1000
+		}
+
+		c.Add(v)
+	}
+
+	covered, total := c.Coverage()
+	assert.InDelta(t, 1001, total, 0.01)
+	assert.Less(t, covered, 2.0)
+	assert.Greater(t, c.GapFraction(), 0.99)
+
+	c = dynhist.Collector{}
+	assert.Equal(t, 0.0, c.GapFraction())
+
+	c.Add(5)
+	c.Add(5)
+
+	covered, total = c.Coverage()
+	assert.Equal(t, 0.0, covered)
+	assert.Equal(t, 0.0, total)
+	assert.Equal(t, 0.0, c.GapFraction())
+}
+
+func TestCollector_Render_printGaps(t *testing.T) {
+	c := dynhist.Collector{BucketsLimit: 2, PrintGaps: true}
+
+	for _, v := range []float64{1, 2, 8, 9} {
+		c.Add(v)
+	}
+
+	assert.Equal(t, `[ min  max] cnt total% (4 events)
+[1.00 2.00] 2 50.00% ..................................................
+[8.00 9.00] 2 50.00% ..................................................
+gaps: 75.0% of value range
+`, c.String())
+}
@@ -0,0 +1,93 @@
+package dynhist
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+// GoString returns a Go composite literal that reconstructs collected data, it implements fmt.GoStringer.
+//
+// Literal is formatted with gofmt rules and can be used as a test fixture, for example
+// &dynhist.Collector{BucketsLimit: 20, Bucket: dynhist.Bucket{...}, Buckets: []dynhist.Bucket{...}}.
+// Only data and metadata are included, configuration like WeightFunc or Transform must be set separately.
+// Boundaries are represented exactly, non-finite values require "math" import.
+func (c *Collector) GoString() string {
+	if c == nil {
+		return "(*dynhist.Collector)(nil)"
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	var res strings.Builder
+
+	res.WriteString("&dynhist.Collector{\n")
+
+	fields := [][2]string{
+		{"Name", strconv.Quote(c.Name)},
+		{"Unit", strconv.Quote(c.Unit)},
+		{"Help", strconv.Quote(c.Help)},
+		{"BucketsLimit", strconv.Itoa(c.BucketsLimit)},
+		{"Bucket", "dynhist.Bucket" + goBucket(c.Bucket)},
+	}
+
+	width := 0
+
+	for _, f := range fields {
+		if f[1] != `""` && len(f[0]) > width {
+			width = len(f[0])
+		}
+	}
+
+	for _, f := range fields {
+		if f[1] == `""` {
+			continue
+		}
+
+		res.WriteString("\t")
+		res.WriteString(f[0])
+		res.WriteString(":")
+		res.WriteString(strings.Repeat(" ", width-len(f[0])+1))
+		res.WriteString(f[1])
+		res.WriteString(",\n")
+	}
+
+	if c.Buckets != nil {
+		res.WriteString("\tBuckets: []dynhist.Bucket{\n")
+
+		for _, b := range c.Buckets {
+			res.WriteString("\t\t")
+			res.WriteString(goBucket(b))
+			res.WriteString(",\n")
+		}
+
+		res.WriteString("\t},\n")
+	}
+
+	res.WriteString("}")
+
+	return res.String()
+}
+
+// goBucket returns bucket fields as a Go composite literal without type.
+func goBucket(b Bucket) string {
+	return "{Min: " + goFloat(b.Min) + ", Max: " + goFloat(b.Max) +
+		", Count: " + strconv.Itoa(b.Count) + ", Sum: " + goFloat(b.Sum) + "}"
+}
+
+// goFloat returns an exact Go expression of v.
+func goFloat(v float64) string {
+	switch {
+	case math.IsNaN(v):
+		return "math.NaN()"
+	case math.IsInf(v, 1):
+		return "math.Inf(1)"
+	case math.IsInf(v, -1):
+		return "math.Inf(-1)"
+	case v == 0 && math.Signbit(v):
+		return "math.Copysign(0, -1)"
+	}
+
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
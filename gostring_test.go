@@ -0,0 +1,61 @@
+package dynhist_test
+
+import (
+	"go/format"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vearutop/dynhist-go"
+	"github.com/vearutop/dynhist-go/internal/dataset"
+	"github.com/vearutop/dynhist-go/internal/golden"
+)
+
+// fixtureCollector returns a collector that is frozen in gostring_fixture_test.go.
+func fixtureCollector() *dynhist.Collector {
+	c := randomCollector(dataset.New(1))
+	c.Name = "latency"
+	c.Help = `Request "latency".`
+
+	return c
+}
+
+func TestCollector_GoString(t *testing.T) {
+	src := "// Code generated by TestCollector_GoString, DO NOT EDIT.\n\n" +
+		"package dynhist_test\n\n" +
+		"import \"github.com/vearutop/dynhist-go\"\n\n" +
+		"var goStringFixture = " + fixtureCollector().GoString() + "\n"
+
+	formatted, err := format.Source([]byte(src))
+	require.NoError(t, err)
+	assert.Equal(t, string(formatted), src)
+
+	golden.AssertFile(t, "gostring_fixture_test.go", src)
+}
+
+func TestCollector_GoString_fixture(t *testing.T) {
+	c := fixtureCollector()
+
+	assert.Equal(t, c.Name, goStringFixture.Name)
+	assert.Equal(t, c.Help, goStringFixture.Help)
+	assert.Equal(t, c.BucketsLimit, goStringFixture.BucketsLimit)
+	assert.Equal(t, c.Bucket, goStringFixture.Bucket)
+	assert.Equal(t, c.Buckets, goStringFixture.Buckets)
+	assert.Equal(t, c.String(), goStringFixture.String())
+}
+
+func TestCollector_GoString_nonFinite(t *testing.T) {
+	c := dynhist.Collector{}
+	c.Add(math.Copysign(0, -1))
+	c.Add(math.Inf(1))
+
+	assert.Equal(t, `&dynhist.Collector{
+	BucketsLimit: 20,
+	Bucket:       dynhist.Bucket{Min: math.Copysign(0, -1), Max: math.Inf(1), Count: 2, Sum: math.Inf(1)},
+	Buckets: []dynhist.Bucket{
+		{Min: math.Copysign(0, -1), Max: math.Copysign(0, -1), Count: 1, Sum: math.Copysign(0, -1)},
+		{Min: math.Inf(1), Max: math.Inf(1), Count: 1, Sum: math.Inf(1)},
+	},
+}`, c.GoString())
+}
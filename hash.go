@@ -0,0 +1,47 @@
+package dynhist
+
+import (
+	"hash/fnv"
+	"math"
+)
+
+// Hash returns a 64-bit FNV-1a digest of collected data for deduplication and change detection.
+//
+// Digest covers totals (count, sum, minimum and maximum) and boundaries, counts and sums of buckets
+// in original units, taken under the lock. Collectors with identical data have equal hashes
+// regardless of how data was collected, configuration and metadata (Name, BucketsLimit, etc.)
+// are not included. Unlike generation of CachedString, the hash is comparable across processes,
+// for example to skip shipping an unchanged snapshot. It is not a cryptographic hash.
+func (c *Collector) Hash() uint64 {
+	total, buckets := c.snapshotBuckets()
+
+	data := make([]byte, 0, 8*(4+4*len(buckets)))
+	data = appendUint64(data, uint64(total.Count))
+	data = appendUint64(data, hashBits(total.Sum))
+	data = appendUint64(data, hashBits(total.Min))
+	data = appendUint64(data, hashBits(total.Max))
+
+	for _, b := range buckets {
+		data = appendUint64(data, hashBits(b.Min))
+		data = appendUint64(data, hashBits(b.Max))
+		data = appendUint64(data, uint64(b.Count))
+		data = appendUint64(data, hashBits(b.Sum))
+	}
+
+	h := fnv.New64a()
+	_, _ = h.Write(data) //nolint:errcheck // Hash does not fail.
+
+	return h.Sum64()
+}
+
+// hashBits returns canonical bits of a value, so that zeros of both signs and all NaNs are equal.
+func hashBits(v float64) uint64 {
+	switch {
+	case v == 0:
+		return 0
+	case math.IsNaN(v):
+		return math.Float64bits(math.NaN())
+	default:
+		return math.Float64bits(v)
+	}
+}
@@ -0,0 +1,78 @@
+package dynhist_test
+
+import (
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vearutop/dynhist-go"
+)
+
+func TestCollector_Hash(t *testing.T) {
+	values := []float64{1, 5, 2, 8, 3, 3, 13, 21, 1, 34}
+
+	a := &dynhist.Collector{BucketsLimit: 100}
+	b := &dynhist.Collector{BucketsLimit: 100, Name: "reversed"}
+
+	for i := range values {
+		a.Add(values[i])
+		b.Add(values[len(values)-1-i])
+	}
+
+	assert.Equal(t, a.Hash(), b.Hash())
+	assert.Equal(t, a.Hash(), a.Clone().Hash())
+
+	l := &dynhist.Collector{}
+	require.NoError(t, l.LoadBuckets(a.Buckets))
+	assert.Equal(t, a.Hash(), l.Hash())
+
+	b.Add(1)
+	assert.NotEqual(t, a.Hash(), b.Hash())
+
+	assert.Equal(t, (&dynhist.Collector{}).Hash(), (*dynhist.Collector)(nil).Hash())
+
+	a.Reset()
+	assert.Equal(t, (&dynhist.Collector{}).Hash(), a.Hash())
+}
+
+func TestCollector_Hash_perturbation(t *testing.T) {
+	base := []dynhist.Bucket{
+		{Min: 0, Max: 1, Count: 10, Sum: 5},
+		{Min: 2, Max: 2, Count: 3, Sum: 6},
+		{Min: 4, Max: 8, Count: 7, Sum: 40},
+	}
+
+	hash := func(buckets []dynhist.Bucket) uint64 {
+		c := &dynhist.Collector{}
+		require.NoError(t, c.LoadBuckets(buckets))
+
+		return c.Hash()
+	}
+
+	seen := map[uint64]string{hash(base): "base"}
+
+	for i := range base {
+		for name, perturb := range map[string]func(b *dynhist.Bucket){
+			"count": func(b *dynhist.Bucket) { b.Count++ },
+			"sum":   func(b *dynhist.Bucket) { b.Sum = math.Nextafter(b.Sum, math.Inf(1)) },
+			"min":   func(b *dynhist.Bucket) { b.Min = math.Nextafter(b.Min, math.Inf(-1)) },
+			"max":   func(b *dynhist.Bucket) { b.Max = math.Nextafter(b.Max, math.Inf(1)) },
+		} {
+			buckets := append([]dynhist.Bucket(nil), base...)
+			perturb(&buckets[i])
+
+			h := hash(buckets)
+			name = fmt.Sprintf("%s of bucket %d", name, i)
+
+			assert.NotContains(t, seen, h, name)
+			seen[h] = name
+		}
+	}
+
+	// Negative zero is the same value.
+	zero := append([]dynhist.Bucket(nil), base...)
+	zero[0].Min = math.Copysign(0, -1)
+	assert.Equal(t, hash(base), hash(zero))
+}
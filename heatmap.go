@@ -0,0 +1,91 @@
+package dynhist
+
+import (
+	"errors"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// ErrSlotsMismatch is returned when numbers of slots and timestamps differ.
+var ErrSlotsMismatch = errors.New("numbers of slots and timestamps mismatch")
+
+// WriteGrafanaHeatmap writes collectors of time slots as heatmap JSON for Grafana.
+//
+// Output is an array of rows with slot time in Unix milliseconds and a map of "le" bounds
+// to counts of values in (previous bound, bound], for example
+// [{"time":1700000000000,"buckets":{"0.1":3,"0.5":1}}].
+// Bounds are a union of upper boundaries of all slots, so that every row has the same keys
+// in ascending order. Slot buckets are redistributed to unified bounds assuming uniform
+// distribution within a bucket. Empty or nil slots produce rows of zero counts.
+func WriteGrafanaHeatmap(w io.Writer, slots []*Collector, timestamps []time.Time) error {
+	if len(slots) != len(timestamps) {
+		return ErrSlotsMismatch
+	}
+
+	data := make([][]Bucket, len(slots))
+	points := make(map[float64]bool)
+
+	for i, c := range slots {
+		if c == nil {
+			continue
+		}
+
+		_, data[i] = c.snapshotBuckets()
+
+		for _, b := range data[i] {
+			points[b.Max] = true
+		}
+	}
+
+	bounds := make([]float64, 0, len(points))
+	for p := range points {
+		bounds = append(bounds, p)
+	}
+
+	sort.Float64s(bounds)
+
+	keys := make([][]byte, len(bounds))
+	for i, b := range bounds {
+		keys[i] = appendJSONString(nil, formatRaw(b))
+	}
+
+	dst := []byte{'['}
+
+	for i, buckets := range data {
+		if i > 0 {
+			dst = append(dst, ',')
+		}
+
+		dst = append(dst, `{"time":`...)
+		dst = strconv.AppendInt(dst, timestamps[i].UnixNano()/1e6, 10)
+		dst = append(dst, `,"buckets":{`...)
+
+		m := cdf(buckets)
+		prev := 0
+
+		for j, b := range bounds {
+			if j > 0 {
+				dst = append(dst, ',')
+			}
+
+			// Cumulative counts are rounded to conserve total count.
+			cum := int(math.Round(m.count(b)))
+
+			dst = append(dst, keys[j]...)
+			dst = append(dst, ':')
+			dst = strconv.AppendInt(dst, int64(cum-prev), 10)
+			prev = cum
+		}
+
+		dst = append(dst, "}}"...)
+	}
+
+	dst = append(dst, "]\n"...)
+
+	_, err := w.Write(dst)
+
+	return err
+}
@@ -0,0 +1,54 @@
+package dynhist_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vearutop/dynhist-go"
+	"github.com/vearutop/dynhist-go/internal/golden"
+)
+
+func TestWriteGrafanaHeatmap(t *testing.T) {
+	s1 := &dynhist.Collector{BucketsLimit: 3}
+	s3 := &dynhist.Collector{BucketsLimit: 3}
+
+	for i := 1; i <= 9; i++ {
+		s1.Add(float64(i) / 10)
+		s3.Add(float64(i) / 4)
+	}
+
+	s3.Add(5)
+
+	start := time.Date(2023, 11, 14, 22, 13, 20, 0, time.UTC)
+	ts := []time.Time{start, start.Add(time.Minute), start.Add(2 * time.Minute)}
+	buf := bytes.NewBuffer(nil)
+
+	require.NoError(t, dynhist.WriteGrafanaHeatmap(buf, []*dynhist.Collector{s1, {}, s3}, ts))
+	golden.Assert(t, "grafana_heatmap", buf.String())
+
+	var rows []struct {
+		Time    int64          `json:"time"`
+		Buckets map[string]int `json:"buckets"`
+	}
+
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &rows))
+	require.Len(t, rows, 3)
+
+	for i, c := range []*dynhist.Collector{s1, {}, s3} {
+		assert.Equal(t, ts[i].UnixNano()/1e6, rows[i].Time)
+		assert.Len(t, rows[i].Buckets, len(rows[0].Buckets), "bounds are unified")
+
+		cnt := 0
+		for _, n := range rows[i].Buckets {
+			cnt += n
+		}
+
+		assert.Equal(t, c.Count, cnt)
+	}
+
+	assert.Equal(t, dynhist.ErrSlotsMismatch, dynhist.WriteGrafanaHeatmap(buf, []*dynhist.Collector{s1}, ts))
+}
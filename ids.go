@@ -0,0 +1,67 @@
+package dynhist
+
+// BucketIDs returns identifiers of buckets if TrackIDs is enabled, nil otherwise.
+//
+// Identifiers are assigned from a monotonically increasing sequence when a bucket is created,
+// a merged bucket receives a new identifier, see OnMerge.
+func (c *Collector) BucketIDs() []uint64 {
+	if c == nil {
+		return nil
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	if !c.TrackIDs {
+		return nil
+	}
+
+	if len(c.ids) != len(c.Buckets) {
+		c.resetIDs()
+	}
+
+	return append([]uint64(nil), c.ids...)
+}
+
+// insertID assigns identifier to a new bucket at index i.
+func (c *Collector) insertID(i int) {
+	if len(c.ids) != len(c.Buckets)-1 {
+		c.resetIDs()
+
+		return
+	}
+
+	c.lastID++
+	c.ids = append(c.ids, 0)
+	copy(c.ids[i+1:], c.ids[i:])
+	c.ids[i] = c.lastID
+}
+
+// mergeIDs assigns identifier to a bucket merged at mergePoint-1.
+func (c *Collector) mergeIDs(mergePoint int) {
+	if len(c.ids) != len(c.Buckets)+1 {
+		c.resetIDs()
+
+		return
+	}
+
+	id1, id2 := c.ids[mergePoint-1], c.ids[mergePoint]
+
+	c.lastID++
+	c.ids = append(c.ids[:mergePoint-1], c.ids[mergePoint:]...)
+	c.ids[mergePoint-1] = c.lastID
+
+	if c.OnMerge != nil {
+		c.OnMerge(id1, id2, c.lastID)
+	}
+}
+
+// resetIDs assigns new identifiers to all buckets.
+func (c *Collector) resetIDs() {
+	c.ids = c.ids[:0]
+
+	for range c.Buckets {
+		c.lastID++
+		c.ids = append(c.ids, c.lastID)
+	}
+}
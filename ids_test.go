@@ -0,0 +1,66 @@
+package dynhist_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vearutop/dynhist-go"
+)
+
+func TestCollector_BucketIDs(t *testing.T) {
+	journal := map[uint64]uint64{}
+	merges := 0
+
+	c := dynhist.Collector{
+		BucketsLimit: 4,
+		TrackIDs:     true,
+		OnMerge: func(id1, id2, merged uint64) {
+			journal[id1] = merged
+			journal[id2] = merged
+			merges++
+		},
+	}
+
+	for _, v := range []float64{1, 9, 5} {
+		c.Add(v)
+	}
+
+	assert.Equal(t, []uint64{1, 3, 2}, c.BucketIDs())
+
+	// Annotation of a bucket with value 5.
+	idx, _ := c.AddClassified(5)
+	annotated := c.BucketIDs()[idx]
+
+	for i := 0; i < 20; i++ {
+		c.Add(float64(i % 13))
+	}
+
+	require.Greater(t, merges, 3)
+
+	for {
+		next, ok := journal[annotated]
+		if !ok {
+			break
+		}
+
+		annotated = next
+	}
+
+	idx, _ = c.AddClassified(5)
+	assert.Equal(t, c.BucketIDs()[idx], annotated)
+
+	ids := c.BucketIDs()
+	assert.Len(t, ids, len(c.Buckets))
+
+	for i := 1; i < len(ids); i++ {
+		assert.NotEqual(t, ids[i-1], ids[i])
+	}
+}
+
+func TestCollector_BucketIDs_disabled(t *testing.T) {
+	c := dynhist.Collector{}
+	c.Add(1)
+
+	assert.Nil(t, c.BucketIDs())
+}
@@ -0,0 +1,228 @@
+package dynhist
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+)
+
+// ErrIndexedFormat is returned by OpenIndexed for malformed data.
+var ErrIndexedFormat = errors.New("invalid indexed histogram")
+
+// indexedMagic starts indexed histogram, it is followed by a byte of SchemaVersion.
+var indexedMagic = []byte("DHIX")
+
+// Layout of indexed histogram, all values are little-endian and 8-byte aligned.
+//
+//	offset 0:  magic "DHIX", version byte, 3 zero bytes
+//	offset 8:  uint64 number of buckets n
+//	offset 16: uint64 total count, float64 sum, min and max
+//	offset 48: [n]float64 mins, [n]float64 maxs, [n]float64 sums, [n]uint64 cumulative counts
+const (
+	indexedHeaderLen = 48
+	indexedBucketLen = 32
+)
+
+// WriteIndexed writes collected data in a read-only binary format, see OpenIndexed.
+//
+// Boundaries are written in original units if Transform is set.
+// ErrNilCollector is returned for a nil collector.
+func (c *Collector) WriteIndexed(w io.Writer) error {
+	if c == nil {
+		return ErrNilCollector
+	}
+
+	total, buckets := c.snapshotBuckets()
+	n := len(buckets)
+	data := make([]byte, indexedHeaderLen+indexedBucketLen*n)
+
+	copy(data, indexedMagic)
+	data[len(indexedMagic)] = SchemaVersion
+
+	le := binary.LittleEndian
+	le.PutUint64(data[8:], uint64(n))
+	le.PutUint64(data[16:], uint64(total.Count))
+	le.PutUint64(data[24:], math.Float64bits(total.Sum))
+	le.PutUint64(data[32:], math.Float64bits(total.Min))
+	le.PutUint64(data[40:], math.Float64bits(total.Max))
+
+	cum := uint64(0)
+
+	for i, b := range buckets {
+		cum += uint64(b.Count)
+
+		le.PutUint64(data[indexedHeaderLen+8*i:], math.Float64bits(b.Min))
+		le.PutUint64(data[indexedHeaderLen+8*(n+i):], math.Float64bits(b.Max))
+		le.PutUint64(data[indexedHeaderLen+8*(2*n+i):], math.Float64bits(b.Sum))
+		le.PutUint64(data[indexedHeaderLen+8*(3*n+i):], cum)
+	}
+
+	_, err := w.Write(data)
+
+	return err
+}
+
+// ReadOnlyHist is a histogram that reads data written by WriteIndexed in place.
+//
+// It is safe for concurrent use.
+type ReadOnlyHist struct {
+	data []byte
+	n    int
+}
+
+// OpenIndexed opens data written by WriteIndexed without copying, data can be memory-mapped.
+//
+// Data must not be modified while ReadOnlyHist is used. Buckets are checked once on open,
+// ErrIndexedFormat is returned if cumulative counts decrease or a bucket has min above max.
+func OpenIndexed(data []byte) (*ReadOnlyHist, error) {
+	if len(data) < indexedHeaderLen {
+		return nil, fmt.Errorf("%w: %d bytes is too short", ErrIndexedFormat, len(data))
+	}
+
+	if string(data[:len(indexedMagic)]) != string(indexedMagic) {
+		return nil, fmt.Errorf("%w: unexpected magic %q", ErrIndexedFormat, data[:len(indexedMagic)])
+	}
+
+	if err := checkVersion(int(data[len(indexedMagic)])); err != nil {
+		return nil, err
+	}
+
+	n := binary.LittleEndian.Uint64(data[8:])
+
+	if n > uint64(len(data)-indexedHeaderLen)/indexedBucketLen ||
+		len(data) != indexedHeaderLen+indexedBucketLen*int(n) {
+		return nil, fmt.Errorf("%w: %d bytes for %d buckets", ErrIndexedFormat, len(data), n)
+	}
+
+	h := &ReadOnlyHist{data: data, n: int(n)}
+
+	for i := 0; i < h.n; i++ {
+		if h.cum(i) < h.cum(i-1) {
+			return nil, fmt.Errorf("%w: cumulative count decreases at bucket %d", ErrIndexedFormat, i)
+		}
+
+		if !(h.min(i) <= h.max(i)) {
+			return nil, fmt.Errorf("%w: bucket %d min %v is above max %v", ErrIndexedFormat, i, h.min(i), h.max(i))
+		}
+	}
+
+	if h.n > 0 && h.cum(h.n-1) != uint64(h.Count()) {
+		return nil, fmt.Errorf("%w: total count %d, sum of buckets %d", ErrCountMismatch, h.Count(), h.cum(h.n-1))
+	}
+
+	return h, nil
+}
+
+func (h *ReadOnlyHist) uint64At(i int) uint64 {
+	return binary.LittleEndian.Uint64(h.data[i:])
+}
+
+func (h *ReadOnlyHist) floatAt(i int) float64 {
+	return math.Float64frombits(h.uint64At(i))
+}
+
+func (h *ReadOnlyHist) min(i int) float64 {
+	return h.floatAt(indexedHeaderLen + 8*i)
+}
+
+func (h *ReadOnlyHist) max(i int) float64 {
+	return h.floatAt(indexedHeaderLen + 8*(h.n+i))
+}
+
+func (h *ReadOnlyHist) cum(i int) uint64 {
+	if i < 0 {
+		return 0
+	}
+
+	return h.uint64At(indexedHeaderLen + 8*(3*h.n+i))
+}
+
+// Count returns number of values.
+func (h *ReadOnlyHist) Count() int {
+	return int(h.uint64At(16))
+}
+
+// Len returns number of buckets.
+func (h *ReadOnlyHist) Len() int {
+	return h.n
+}
+
+// Buckets returns a copy of buckets and totals.
+func (h *ReadOnlyHist) Buckets() (total Bucket, buckets []Bucket) {
+	total = Bucket{Count: h.Count(), Sum: h.floatAt(24), Min: h.floatAt(32), Max: h.floatAt(40)}
+	buckets = make([]Bucket, h.n)
+
+	for i := range buckets {
+		buckets[i] = Bucket{
+			Min:   h.min(i),
+			Max:   h.max(i),
+			Count: int(h.cum(i) - h.cum(i-1)),
+			Sum:   h.floatAt(indexedHeaderLen + 8*(2*h.n+i)),
+		}
+	}
+
+	return total, buckets
+}
+
+// Percentile returns a value below or equal to which a percent (0-100) of values fall, see Collector.Percentile.
+func (h *ReadOnlyHist) Percentile(percent float64) float64 {
+	if h.n == 0 {
+		return 0
+	}
+
+	if percent >= 100 {
+		return h.max(h.n - 1)
+	}
+
+	target := percent * float64(h.Count()) / 100
+	adj := target - cdfEpsilon*target
+
+	i := sort.Search(h.n, func(i int) bool { return float64(h.cum(i)) >= adj })
+	if i == h.n {
+		return h.max(h.n - 1)
+	}
+
+	prev := float64(h.cum(i - 1))
+	n := float64(h.cum(i)) - prev
+	lo, hi := h.min(i), h.max(i)
+
+	if lo == hi || n == 0 {
+		return lo
+	}
+
+	return lo + math.Min(math.Max((target-prev)/n, 0), 1)*(hi-lo)
+}
+
+// Rank returns a percent (0-100) of values below or equal to v, see Collector.Rank.
+func (h *ReadOnlyHist) Rank(v float64) float64 {
+	if h.Count() == 0 {
+		return 0
+	}
+
+	i := sort.Search(h.n, func(i int) bool { return h.max(i) >= v })
+	if i == h.n {
+		return 100
+	}
+
+	prev := float64(h.cum(i - 1))
+	cnt := prev
+
+	if lo, hi := h.min(i), h.max(i); v >= hi {
+		cnt = float64(h.cum(i))
+	} else if v > lo {
+		cnt += (float64(h.cum(i)) - prev) * (v - lo) / (hi - lo)
+	}
+
+	return 100 * cnt / float64(h.Count())
+}
+
+// String renders buckets like Collector.String.
+func (h *ReadOnlyHist) String() string {
+	c := Collector{}
+	c.Bucket, c.Buckets = h.Buckets()
+
+	return c.String()
+}
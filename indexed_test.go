@@ -0,0 +1,144 @@
+package dynhist_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vearutop/dynhist-go"
+	"github.com/vearutop/dynhist-go/internal/dataset"
+)
+
+func TestCollector_WriteIndexed_layout(t *testing.T) {
+	c := dynhist.Collector{}
+	c.Add(1)
+	c.Add(2)
+	c.Add(2)
+
+	var buf bytes.Buffer
+
+	require.NoError(t, c.WriteIndexed(&buf))
+
+	data := buf.Bytes()
+	require.Len(t, data, 48+2*32)
+
+	le := binary.LittleEndian
+	f := func(off int) float64 { return math.Float64frombits(le.Uint64(data[off:])) }
+
+	assert.Equal(t, []byte{'D', 'H', 'I', 'X', 1, 0, 0, 0}, data[:8])
+	assert.Equal(t, uint64(2), le.Uint64(data[8:]))
+	assert.Equal(t, uint64(3), le.Uint64(data[16:]))
+	assert.Equal(t, []float64{5, 1, 2}, []float64{f(24), f(32), f(40)})
+	assert.Equal(t, []float64{1, 2}, []float64{f(48), f(56)}, "mins")
+	assert.Equal(t, []float64{1, 2}, []float64{f(64), f(72)}, "maxs")
+	assert.Equal(t, []float64{1, 4}, []float64{f(80), f(88)}, "sums")
+	assert.Equal(t, []uint64{1, 3}, []uint64{le.Uint64(data[96:]), le.Uint64(data[104:])}, "cumulative counts")
+}
+
+func TestOpenIndexed(t *testing.T) {
+	r := dataset.New(1)
+
+	for k := 0; k < 50; k++ {
+		c := randomCollector(r)
+
+		var buf bytes.Buffer
+
+		require.NoError(t, c.WriteIndexed(&buf))
+
+		h, err := dynhist.OpenIndexed(buf.Bytes())
+		require.NoError(t, err)
+
+		assert.Equal(t, c.Count, h.Count())
+		assert.Equal(t, len(c.Buckets), h.Len())
+		assert.Equal(t, c.String(), h.String())
+
+		total, buckets := h.Buckets()
+		assert.Equal(t, c.Bucket, total)
+		assert.Equal(t, c.Buckets, buckets)
+
+		for i := 0; i <= 100; i++ {
+			p := float64(i)
+			assert.Equal(t, c.Percentile(p), h.Percentile(p), "%d p%v", k, p)
+
+			v := c.Min - 1 + float64(i)/100*(c.Max-c.Min+2)
+			assert.InDelta(t, c.Rank(v), h.Rank(v), 1e-9, "%d %v", k, v)
+		}
+	}
+}
+
+func TestOpenIndexed_empty(t *testing.T) {
+	var (
+		c   dynhist.Collector
+		buf bytes.Buffer
+	)
+
+	require.NoError(t, c.WriteIndexed(&buf))
+
+	h, err := dynhist.OpenIndexed(buf.Bytes())
+	require.NoError(t, err)
+
+	assert.Equal(t, 0, h.Len())
+	assert.Equal(t, 0.0, h.Percentile(50))
+	assert.Equal(t, 0.0, h.Rank(1))
+	assert.Equal(t, "", h.String())
+}
+
+func TestOpenIndexed_invalid(t *testing.T) {
+	c := dynhist.Collector{}
+
+	for i := 0; i < 100; i++ {
+		c.Add(float64(i))
+	}
+
+	var buf bytes.Buffer
+
+	require.NoError(t, c.WriteIndexed(&buf))
+
+	data := buf.Bytes()
+
+	for l := 0; l < len(data); l++ {
+		_, err := dynhist.OpenIndexed(data[:l])
+		assert.True(t, errors.Is(err, dynhist.ErrIndexedFormat), l)
+	}
+
+	_, err := dynhist.OpenIndexed(append(append([]byte(nil), data...), 0))
+	assert.True(t, errors.Is(err, dynhist.ErrIndexedFormat), err)
+
+	bad := append([]byte(nil), data...)
+	bad[0] = 'X'
+	_, err = dynhist.OpenIndexed(bad)
+	assert.EqualError(t, err, `invalid indexed histogram: unexpected magic "XHIX"`)
+
+	bad = append([]byte(nil), data...)
+	bad[4] = 9
+	_, err = dynhist.OpenIndexed(bad)
+	assert.True(t, errors.Is(err, dynhist.ErrUnsupportedVersion), err)
+
+	bad = append([]byte(nil), data...)
+	binary.LittleEndian.PutUint64(bad[8:], math.MaxUint64)
+	_, err = dynhist.OpenIndexed(bad)
+	assert.True(t, errors.Is(err, dynhist.ErrIndexedFormat), err)
+
+	bad = append([]byte(nil), data...)
+	binary.LittleEndian.PutUint64(bad[16:], 99)
+	_, err = dynhist.OpenIndexed(bad)
+	assert.True(t, errors.Is(err, dynhist.ErrCountMismatch), err)
+
+	n := len(c.Buckets)
+
+	bad = append([]byte(nil), data...)
+	binary.LittleEndian.PutUint64(bad[48+8*3*n:], 100)
+	_, err = dynhist.OpenIndexed(bad)
+	assert.EqualError(t, err, "invalid indexed histogram: cumulative count decreases at bucket 1")
+
+	for _, v := range []float64{1000, math.NaN()} {
+		bad = append([]byte(nil), data...)
+		binary.LittleEndian.PutUint64(bad[48:], math.Float64bits(v))
+		_, err = dynhist.OpenIndexed(bad)
+		assert.True(t, errors.Is(err, dynhist.ErrIndexedFormat), err)
+	}
+}
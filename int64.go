@@ -0,0 +1,79 @@
+package dynhist
+
+import "math"
+
+// AddInt64 collects int64 value relative to Offset.
+//
+// Values are stored as float64(v - Offset), so that large int64 values that are close to each other
+// (for example time.Time.UnixNano() timestamps) do not lose precision. Differences that overflow int64
+// are computed in float64 instead. If Offset is zero when
+// the first value is added, it is set to that value.
+// Boundaries and percentiles of buckets are relative to Offset, see PercentileInt64.
+func (c *Collector) AddInt64(v int64) {
+	if c == nil {
+		return
+	}
+
+	c.Lock()
+	defer c.unlockAndNotify()
+
+	if c.Offset == 0 && c.Count == 0 {
+		c.Offset = v
+	}
+
+	c.add(offsetDiff(v, c.Offset))
+	c.mergeOverLimit()
+}
+
+// PercentileInt64 returns percentile of values added with AddInt64, see Percentile.
+func (c *Collector) PercentileInt64(percent float64) int64 {
+	if c == nil {
+		return 0
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	return offsetAdd(c.Offset, c.percentile(percent))
+}
+
+// offsetDiff returns v - offset, it is exact unless int64 subtraction overflows,
+// in which case the difference is computed in float64.
+func offsetDiff(v, offset int64) float64 {
+	d := v - offset
+
+	if (v < 0) != (offset < 0) && (d < 0) != (v < 0) {
+		return float64(v) - float64(offset)
+	}
+
+	return float64(d)
+}
+
+// offsetAdd returns offset + d saturated to int64 range.
+func offsetAdd(offset int64, d float64) int64 {
+	// float64(math.MaxInt64) is 2^63 and does not fit in int64.
+	if d >= -float64(math.MinInt64) || d < float64(math.MinInt64) {
+		r := float64(offset) + d
+
+		switch {
+		case r >= -float64(math.MinInt64):
+			return math.MaxInt64
+		case r <= float64(math.MinInt64):
+			return math.MinInt64
+		default:
+			return int64(r)
+		}
+	}
+
+	i := int64(d)
+	r := offset + i
+
+	switch {
+	case i > 0 && r < offset:
+		return math.MaxInt64
+	case i < 0 && r > offset:
+		return math.MinInt64
+	default:
+		return r
+	}
+}
@@ -0,0 +1,70 @@
+package dynhist_test
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vearutop/dynhist-go"
+)
+
+func TestCollector_AddInt64(t *testing.T) {
+	base := time.Date(2023, 5, 6, 7, 8, 9, 123456789, time.UTC)
+
+	c := dynhist.Collector{BucketsLimit: 100}
+	naive := dynhist.Collector{BucketsLimit: 100}
+
+	// Timestamps with microsecond steps spanning 3 seconds.
+	for i := 0; i < 100; i++ {
+		ts := base.Add(time.Duration(i) * 30 * time.Millisecond).Add(time.Duration(i%7) * time.Microsecond)
+
+		c.AddInt64(ts.UnixNano())
+		naive.Add(float64(ts.UnixNano()))
+	}
+
+	assert.Equal(t, base.UnixNano(), c.Offset)
+	assert.Len(t, c.Buckets, 100)
+
+	naiveExact := true
+
+	for i, b := range c.Buckets {
+		expected := float64(time.Duration(i)*30*time.Millisecond + time.Duration(i%7)*time.Microsecond)
+
+		assert.Equal(t, expected, b.Min)
+		assert.Equal(t, expected, b.Max)
+
+		if naive.Buckets[i].Min-naive.Buckets[0].Min != expected {
+			naiveExact = false
+		}
+	}
+
+	assert.False(t, naiveExact, "float64 path is expected to lose precision")
+
+	assert.Equal(t, base.Add(1470*time.Millisecond).UnixNano(), c.PercentileInt64(50))
+}
+
+func TestCollector_AddInt64_overflow(t *testing.T) {
+	c := dynhist.Collector{BucketsLimit: 10}
+
+	c.AddInt64(math.MinInt64)
+	c.AddInt64(math.MaxInt64)
+
+	assert.Equal(t, int64(math.MinInt64), c.Offset)
+	assert.Equal(t, 2, c.Count)
+	assert.Equal(t, 0.0, c.Min)
+	assert.Equal(t, math.Exp2(64), c.Max)
+	assert.Equal(t, int64(math.MinInt64), c.PercentileInt64(0))
+	assert.Equal(t, int64(math.MaxInt64), c.PercentileInt64(100))
+
+	c = dynhist.Collector{BucketsLimit: 10}
+
+	c.AddInt64(math.MaxInt64)
+	c.AddInt64(math.MinInt64)
+
+	assert.Equal(t, int64(math.MaxInt64), c.Offset)
+	assert.Equal(t, -math.Exp2(64), c.Min)
+	assert.Equal(t, 0.0, c.Max)
+	assert.Equal(t, int64(math.MinInt64), c.PercentileInt64(0))
+	assert.Equal(t, int64(math.MaxInt64), c.PercentileInt64(100))
+}
@@ -0,0 +1,58 @@
+// Package dataset provides deterministic pseudo-random values for tests and reproducible layouts.
+//
+// Unlike math/rand, the sequence of values is defined by this package and does not
+// change with Go versions, so that test expectations remain stable.
+package dataset
+
+import "math"
+
+// Source generates pseudo-random values with SplitMix64 algorithm.
+type Source struct {
+	state uint64
+}
+
+// New creates a source with a seed.
+func New(seed uint64) *Source {
+	return &Source{state: seed}
+}
+
+// Uint64 returns next pseudo-random value.
+func (s *Source) Uint64() uint64 {
+	s.state += 0x9e3779b97f4a7c15
+
+	z := s.state
+	z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+	z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+
+	return z ^ (z >> 31)
+}
+
+// Float64 returns uniformly distributed value in [0, 1).
+func (s *Source) Float64() float64 {
+	return float64(s.Uint64()>>11) / (1 << 53)
+}
+
+// Intn returns uniformly distributed value in [0, n).
+func (s *Source) Intn(n int) int {
+	return int(s.Float64() * float64(n))
+}
+
+// ExpFloat64 returns exponentially distributed value with rate 1.
+func (s *Source) ExpFloat64() float64 {
+	return -math.Log(1 - s.Float64())
+}
+
+// NormFloat64 returns normally distributed value with mean 0 and standard deviation 1.
+func (s *Source) NormFloat64() float64 {
+	u1 := 1 - s.Float64()
+	u2 := s.Float64()
+
+	return math.Sqrt(-2*math.Log(u1)) * math.Cos(2*math.Pi*u2)
+}
+
+// Shuffle pseudo-randomizes the order of elements.
+func (s *Source) Shuffle(n int, swap func(i, j int)) {
+	for i := n - 1; i > 0; i-- {
+		swap(i, s.Intn(i+1))
+	}
+}
@@ -0,0 +1,23 @@
+package dataset_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vearutop/dynhist-go/internal/dataset"
+)
+
+func TestSource(t *testing.T) {
+	s := dataset.New(1)
+
+	// Sequence must never change, golden files depend on it.
+	assert.Equal(t, uint64(0x910a2dec89025cc1), s.Uint64())
+	assert.Equal(t, uint64(0xbeeb8da1658eec67), s.Uint64())
+
+	for i := 0; i < 1000; i++ {
+		v := s.Float64()
+		assert.True(t, v >= 0 && v < 1)
+		assert.GreaterOrEqual(t, s.ExpFloat64(), 0.0)
+		assert.Less(t, s.Intn(10), 10)
+	}
+}
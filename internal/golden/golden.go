@@ -0,0 +1,42 @@
+// Package golden provides assertions against expected output stored in files.
+package golden
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var update = flag.Bool("update", false, "update golden files")
+
+// Assert checks that actual value matches the content of testdata/<name>.golden.
+//
+// Run tests with -update flag to write actual value to the file.
+func Assert(t testing.TB, name, actual string) {
+	t.Helper()
+
+	AssertFile(t, filepath.Join("testdata", name+".golden"), actual)
+}
+
+// AssertFile checks that actual value matches the content of a file, for example a generated Go fixture.
+//
+// Run tests with -update flag to write actual value to the file.
+func AssertFile(t testing.TB, fn, actual string) {
+	t.Helper()
+
+	if *update {
+		require.NoError(t, os.MkdirAll(filepath.Dir(fn), 0o750))
+		require.NoError(t, os.WriteFile(fn, []byte(actual), 0o600))
+
+		return
+	}
+
+	expected, err := os.ReadFile(fn) //nolint:gosec // Test file name.
+	require.NoError(t, err, "run tests with -update to create golden file")
+
+	assert.Equal(t, string(expected), actual, "golden file %s mismatch, run tests with -update to refresh", fn)
+}
@@ -0,0 +1,195 @@
+package dynhist
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+)
+
+// collectorJSON is a canonical JSON representation of collector data.
+type collectorJSON struct {
+	Version      int           `json:"version"`
+	Name         string        `json:"name,omitempty"`
+	Unit         string        `json:"unit,omitempty"`
+	Help         string        `json:"help,omitempty"`
+	Count        int           `json:"count"`
+	Sum          float64       `json:"sum"`
+	SumEstimated bool          `json:"sum_estimated,omitempty"`
+	Min          jsonBound     `json:"min"`
+	Max          jsonBound     `json:"max"`
+	Buckets      []bucketJSON  `json:"buckets"`
+	Labels       []bucketLabel `json:"labels,omitempty"`
+}
+
+// bucketJSON is a JSON representation of Bucket with boundaries that may be infinite.
+type bucketJSON struct {
+	Min   jsonBound `json:"min"`
+	Max   jsonBound `json:"max"`
+	Count int       `json:"count"`
+	Sum   float64   `json:"sum"`
+}
+
+// jsonBound is a boundary encoded as a number, infinite boundaries (for example edges
+// of runtime/metrics histograms) are encoded as strings "+Inf" and "-Inf" like in Prometheus.
+type jsonBound float64
+
+// MarshalJSON implements json.Marshaler.
+func (b jsonBound) MarshalJSON() ([]byte, error) {
+	if err := finite(boundValue(float64(b))); err != nil {
+		return nil, err
+	}
+
+	return appendJSONBound(nil, float64(b)), nil
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (b *jsonBound) UnmarshalJSON(data []byte) error {
+	switch string(data) {
+	case `"+Inf"`:
+		*b = jsonBound(math.Inf(1))
+	case `"-Inf"`:
+		*b = jsonBound(math.Inf(-1))
+	default:
+		return json.Unmarshal(data, (*float64)(b))
+	}
+
+	return nil
+}
+
+// bucketLabel is a label of bucket with index in Buckets.
+type bucketLabel struct {
+	Bucket int    `json:"bucket"`
+	Label  string `json:"label"`
+}
+
+func (c *Collector) snapshotJSON() collectorJSON {
+	total, buckets := c.Bucket, append(make([]Bucket, 0, len(c.Buckets)), c.Buckets...)
+	c.Transform.outward(&total, buckets)
+
+	var labels []bucketLabel
+
+	if len(c.labels) == len(c.Buckets) {
+		for i, l := range c.labels {
+			if l != "" {
+				labels = append(labels, bucketLabel{Bucket: i, Label: l})
+			}
+		}
+	}
+
+	bj := make([]bucketJSON, len(buckets))
+
+	for i, b := range buckets {
+		bj[i] = bucketJSON{Min: jsonBound(b.Min), Max: jsonBound(b.Max), Count: b.Count, Sum: b.Sum}
+	}
+
+	return collectorJSON{
+		Version:      SchemaVersion,
+		Name:         c.Name,
+		Unit:         c.Unit,
+		Help:         c.Help,
+		Count:        total.Count,
+		Sum:          total.Sum,
+		SumEstimated: c.SumEstimated,
+		Min:          jsonBound(total.Min),
+		Max:          jsonBound(total.Max),
+		Buckets:      bj,
+		Labels:       labels,
+	}
+}
+
+// MarshalJSON encodes collector data as JSON.
+//
+// Infinite boundaries (for example edges of runtime/metrics histograms) are encoded as strings
+// "+Inf" and "-Inf", other non-finite values result in an error. Configuration fields (BucketsLimit, WeightFunc, etc.) are not encoded.
+// ErrNilCollector is returned for a nil collector.
+func (c *Collector) MarshalJSON() ([]byte, error) {
+	if c == nil {
+		return nil, ErrNilCollector
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	if err := c.checkFinite(); err != nil {
+		return nil, err
+	}
+
+	return c.appendJSON(nil), nil
+}
+
+// UnmarshalJSON replaces collector data with decoded JSON.
+//
+// Data of unsupported schema version results in VersionError.
+// Malformed buckets or totals result in errors from ValidateBuckets or ErrCountMismatch,
+// collector is left unmodified on failure.
+func (c *Collector) UnmarshalJSON(data []byte) error {
+	if c == nil {
+		return ErrNilCollector
+	}
+
+	var cj collectorJSON
+
+	if err := json.Unmarshal(data, &cj); err != nil {
+		return err
+	}
+
+	if err := checkVersion(cj.Version); err != nil {
+		return err
+	}
+
+	total := Bucket{Min: float64(cj.Min), Max: float64(cj.Max), Count: cj.Count, Sum: cj.Sum}
+	buckets := make([]Bucket, len(cj.Buckets))
+
+	for i, b := range cj.Buckets {
+		buckets[i] = Bucket{Min: float64(b.Min), Max: float64(b.Max), Count: b.Count, Sum: b.Sum}
+	}
+
+	if err := validate(total, buckets); err != nil {
+		return err
+	}
+
+	var labels []string
+
+	for _, l := range cj.Labels {
+		if l.Bucket < 0 || l.Bucket >= len(cj.Buckets) {
+			return fmt.Errorf("%w: label of bucket %d out of %d", ErrInvalidBucket, l.Bucket, len(cj.Buckets))
+		}
+
+		if labels == nil {
+			labels = make([]string, len(cj.Buckets))
+		}
+
+		labels[l.Bucket] = joinLabels(labels[l.Bucket], l.Label)
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	c.Transform.inward(&total, buckets)
+
+	c.generation++
+	c.Bucket = total
+	c.Buckets = buckets
+	c.labels = labels
+	c.seen = nil
+	c.resetTop()
+	c.rawPartial = true
+	c.SumEstimated = cj.SumEstimated
+	c.Name = cj.Name
+	c.Unit = cj.Unit
+	c.Help = cj.Help
+
+	if c.TrackIDs {
+		c.resetIDs()
+	}
+
+	if c.BucketsLimit < len(c.Buckets) {
+		c.BucketsLimit = len(c.Buckets)
+	}
+
+	if c.WeightFunc == nil {
+		c.WeightFunc = AvgWidth
+	}
+
+	return nil
+}
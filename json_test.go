@@ -0,0 +1,83 @@
+package dynhist_test
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vearutop/dynhist-go"
+	"github.com/vearutop/dynhist-go/internal/dataset"
+)
+
+func TestCollector_MarshalJSON(t *testing.T) {
+	c := dynhist.Collector{BucketsLimit: 3}
+
+	for i := 0; i < 10; i++ {
+		c.Add(float64(i))
+	}
+
+	j, err := json.Marshal(&c)
+	require.NoError(t, err)
+	assert.Equal(t, `{"version":1,"count":10,"sum":45,"min":0,"max":9,"buckets":[`+
+		`{"min":0,"max":3,"count":4,"sum":6},{"min":4,"max":6,"count":3,"sum":15},{"min":7,"max":9,"count":3,"sum":24}]}`,
+		string(j))
+
+	c2 := dynhist.Collector{}
+	require.NoError(t, json.Unmarshal(j, &c2))
+	assert.Equal(t, c.String(), c2.String())
+
+	c2.Add(10)
+	assert.Equal(t, 11, c2.Count)
+	assert.Len(t, c2.Buckets, 3)
+}
+
+func TestCollector_MarshalJSON_roundTrip(t *testing.T) {
+	r := dataset.New(1)
+
+	for k := 0; k < 100; k++ {
+		c := randomCollector(r)
+
+		j, err := json.Marshal(c)
+		require.NoError(t, err)
+
+		c2 := dynhist.Collector{}
+		require.NoError(t, json.Unmarshal(j, &c2))
+		assert.Empty(t, dynhist.DiffString(c, &c2))
+	}
+}
+
+func TestCollector_UnmarshalJSON_invalid(t *testing.T) {
+	c := dynhist.Collector{}
+	c.Add(1)
+
+	err := json.Unmarshal([]byte(`{"count":3,"buckets":[{"min":0,"max":1,"count":1}]}`), &c)
+	assert.True(t, errors.Is(err, dynhist.ErrCountMismatch), err)
+
+	err = json.Unmarshal([]byte(`{"count":2,"buckets":[{"min":2,"max":3,"count":1},{"min":0,"max":1,"count":1}]}`), &c)
+	assert.True(t, errors.Is(err, dynhist.ErrUnsortedBuckets), err)
+
+	assert.Equal(t, 1, c.Count)
+}
+
+func TestCollector_MarshalJSON_meta(t *testing.T) {
+	c := dynhist.Collector{Name: "request_duration_seconds", Unit: "seconds", Help: "Duration of request."}
+	c.Add(1)
+
+	j, err := json.Marshal(&c)
+	require.NoError(t, err)
+	assert.Equal(t, `{"version":1,"name":"request_duration_seconds","unit":"seconds","help":"Duration of request.",`+
+		`"count":1,"sum":1,"min":1,"max":1,"buckets":[{"min":1,"max":1,"count":1,"sum":1}]}`, string(j))
+
+	c2 := dynhist.Collector{}
+	require.NoError(t, json.Unmarshal(j, &c2))
+	assert.Equal(t, c.Name, c2.Name)
+	assert.Equal(t, c.Unit, c2.Unit)
+	assert.Equal(t, c.Help, c2.Help)
+
+	assert.Equal(t, `request_duration_seconds (seconds): Duration of request.
+[ min  max] cnt  total% (1 events)
+[1.00 1.00] 1 100.00% ....................................................................................................
+`, c.String())
+}
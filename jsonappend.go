@@ -0,0 +1,220 @@
+package dynhist
+
+import (
+	"encoding/json"
+	"math"
+	"reflect"
+	"strconv"
+	"unicode/utf8"
+)
+
+// AppendJSON appends JSON encoding of collector data to dst and returns the extended buffer.
+//
+// Output is the same as of MarshalJSON, except that values that MarshalJSON fails on
+// are encoded as null. Data is encoded under the lock without intermediate allocations.
+func (c *Collector) AppendJSON(dst []byte) []byte {
+	if c == nil {
+		return dst
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	return c.appendJSON(dst)
+}
+
+func (c *Collector) appendJSON(dst []byte) []byte {
+	t := c.Transform
+
+	dst = append(dst, `{"version":`...)
+	dst = strconv.AppendInt(dst, SchemaVersion, 10)
+
+	if c.Name != "" {
+		dst = append(dst, `,"name":`...)
+		dst = appendJSONString(dst, c.Name)
+	}
+
+	if c.Unit != "" {
+		dst = append(dst, `,"unit":`...)
+		dst = appendJSONString(dst, c.Unit)
+	}
+
+	if c.Help != "" {
+		dst = append(dst, `,"help":`...)
+		dst = appendJSONString(dst, c.Help)
+	}
+
+	dst = append(dst, `,"count":`...)
+	dst = strconv.AppendInt(dst, int64(c.Count), 10)
+	dst = append(dst, `,"sum":`...)
+	dst = appendJSONFloat(dst, c.Sum)
+
+	if c.SumEstimated {
+		dst = append(dst, `,"sum_estimated":true`...)
+	}
+
+	dst = append(dst, `,"min":`...)
+	dst = appendJSONBound(dst, t.inv(c.Min))
+	dst = append(dst, `,"max":`...)
+	dst = appendJSONBound(dst, t.inv(c.Max))
+	dst = append(dst, `,"buckets":[`...)
+
+	for i, b := range c.Buckets {
+		if i > 0 {
+			dst = append(dst, ',')
+		}
+
+		dst = append(dst, `{"min":`...)
+		dst = appendJSONBound(dst, t.inv(b.Min))
+		dst = append(dst, `,"max":`...)
+		dst = appendJSONBound(dst, t.inv(b.Max))
+		dst = append(dst, `,"count":`...)
+		dst = strconv.AppendInt(dst, int64(b.Count), 10)
+		dst = append(dst, `,"sum":`...)
+		dst = appendJSONFloat(dst, b.Sum)
+		dst = append(dst, '}')
+	}
+
+	dst = append(dst, ']')
+
+	if len(c.labels) == len(c.Buckets) {
+		sep := `,"labels":[`
+
+		for i, l := range c.labels {
+			if l == "" {
+				continue
+			}
+
+			dst = append(dst, sep...)
+			dst = append(dst, `{"bucket":`...)
+			dst = strconv.AppendInt(dst, int64(i), 10)
+			dst = append(dst, `,"label":`...)
+			dst = appendJSONString(dst, l)
+			dst = append(dst, '}')
+			sep = ","
+		}
+
+		if sep == "," {
+			dst = append(dst, ']')
+		}
+	}
+
+	return append(dst, '}')
+}
+
+// checkFinite returns an error of encoding/json for a non-finite value of collector.
+func (c *Collector) checkFinite() error {
+	t := c.Transform
+
+	if err := finite(c.Sum, boundValue(t.inv(c.Min)), boundValue(t.inv(c.Max))); err != nil {
+		return err
+	}
+
+	for _, b := range c.Buckets {
+		if err := finite(boundValue(t.inv(b.Min)), boundValue(t.inv(b.Max)), b.Sum); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func finite(values ...float64) error {
+	for _, v := range values {
+		if math.IsInf(v, 0) || math.IsNaN(v) {
+			return &json.UnsupportedValueError{Value: reflect.ValueOf(v), Str: strconv.FormatFloat(v, 'g', -1, 64)}
+		}
+	}
+
+	return nil
+}
+
+// boundValue replaces infinite boundary with zero for finite check, as it is encoded as a string.
+func boundValue(v float64) float64 {
+	if math.IsInf(v, 0) {
+		return 0
+	}
+
+	return v
+}
+
+// appendJSONBound appends a boundary, infinities are appended as strings "+Inf" and "-Inf".
+func appendJSONBound(dst []byte, v float64) []byte {
+	switch {
+	case math.IsInf(v, 1):
+		return append(dst, `"+Inf"`...)
+	case math.IsInf(v, -1):
+		return append(dst, `"-Inf"`...)
+	default:
+		return appendJSONFloat(dst, v)
+	}
+}
+
+// appendJSONFloat appends a number formatted like encoding/json does.
+func appendJSONFloat(dst []byte, v float64) []byte {
+	if math.IsInf(v, 0) || math.IsNaN(v) {
+		return append(dst, "null"...)
+	}
+
+	format := byte('f')
+	if abs := math.Abs(v); abs != 0 && (abs < 1e-6 || abs >= 1e21) {
+		format = 'e'
+	}
+
+	dst = strconv.AppendFloat(dst, v, format, -1, 64)
+
+	if format == 'e' {
+		// Clean up e-09 to e-9.
+		if n := len(dst); n >= 4 && dst[n-4] == 'e' && dst[n-3] == '-' && dst[n-2] == '0' {
+			dst[n-2] = dst[n-1]
+			dst = dst[:n-1]
+		}
+	}
+
+	return dst
+}
+
+const hexDigits = "0123456789abcdef"
+
+// appendJSONString appends a string quoted and escaped like encoding/json does.
+func appendJSONString(dst []byte, s string) []byte {
+	dst = append(dst, '"')
+
+	for i := 0; i < len(s); {
+		if b := s[i]; b < utf8.RuneSelf {
+			switch {
+			case b == '"' || b == '\\':
+				dst = append(dst, '\\', b)
+			case b == '\n':
+				dst = append(dst, '\\', 'n')
+			case b == '\r':
+				dst = append(dst, '\\', 'r')
+			case b == '\t':
+				dst = append(dst, '\\', 't')
+			case b < 0x20 || b == '<' || b == '>' || b == '&':
+				dst = append(dst, '\\', 'u', '0', '0', hexDigits[b>>4], hexDigits[b&0xF])
+			default:
+				dst = append(dst, b)
+			}
+
+			i++
+
+			continue
+		}
+
+		r, size := utf8.DecodeRuneInString(s[i:])
+
+		switch {
+		case r == utf8.RuneError && size == 1:
+			dst = append(dst, `�`...)
+		case r == ' ' || r == ' ':
+			dst = append(dst, '\\', 'u', '2', '0', '2', hexDigits[r&0xF])
+		default:
+			dst = append(dst, s[i:i+size]...)
+		}
+
+		i += size
+	}
+
+	return append(dst, '"')
+}
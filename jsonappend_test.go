@@ -0,0 +1,121 @@
+package dynhist_test
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vearutop/dynhist-go"
+	"github.com/vearutop/dynhist-go/internal/dataset"
+)
+
+func BenchmarkCollector_AppendJSON(b *testing.B) {
+	c := dynhist.Collector{BucketsLimit: 100}
+	r := dataset.New(1)
+
+	for i := 0; i < 10000; i++ {
+		c.Add(r.ExpFloat64())
+	}
+
+	buf := make([]byte, 0, 10000)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		buf = c.AppendJSON(buf[:0])
+	}
+}
+
+// collectorJSON mirrors encoding of MarshalJSON for reflection-based reference.
+type collectorJSON struct {
+	Version int              `json:"version"`
+	Name    string           `json:"name,omitempty"`
+	Unit    string           `json:"unit,omitempty"`
+	Help    string           `json:"help,omitempty"`
+	Count   int              `json:"count"`
+	Sum     float64          `json:"sum"`
+	SumEst  bool             `json:"sum_estimated,omitempty"`
+	Min     float64          `json:"min"`
+	Max     float64          `json:"max"`
+	Buckets []dynhist.Bucket `json:"buckets"`
+	Labels  []bucketLabel    `json:"labels,omitempty"`
+}
+
+type bucketLabel struct {
+	Bucket int    `json:"bucket"`
+	Label  string `json:"label"`
+}
+
+func TestCollector_AppendJSON(t *testing.T) {
+	r := dataset.New(1)
+	names := []string{"", "plain", `quo"te\back`, "<tag> & \n\t\x01", "юникод   ", "bad \xff utf"}
+
+	for k := 0; k < 100; k++ {
+		c := randomCollector(r)
+		c.Name = names[k%len(names)]
+		c.Help = names[(k+1)%len(names)]
+		c.SumEstimated = k%2 == 0
+
+		// Tiny and huge values use exponent format.
+		c.Add(1e-9 * r.Float64())
+		c.Add(1e22 * r.Float64())
+
+		var labels []bucketLabel
+
+		if k%3 == 0 {
+			c.Annotate(c.Buckets[0].Min, names[k%len(names)]+"first")
+			c.Annotate(c.Buckets[len(c.Buckets)-1].Max, "last")
+
+			labels = []bucketLabel{{Bucket: 0, Label: names[k%len(names)] + "first"}, {Bucket: len(c.Buckets) - 1, Label: "last"}}
+		}
+
+		expected, err := json.Marshal(collectorJSON{
+			Version: dynhist.SchemaVersion,
+			Name:    c.Name,
+			Unit:    c.Unit,
+			Help:    c.Help,
+			Count:   c.Count,
+			Sum:     c.Sum,
+			SumEst:  c.SumEstimated,
+			Min:     c.Min,
+			Max:     c.Max,
+			Buckets: c.Buckets,
+			Labels:  labels,
+		})
+		require.NoError(t, err)
+
+		assert.Equal(t, string(expected), string(c.AppendJSON(nil)))
+
+		j, err := json.Marshal(c)
+		require.NoError(t, err)
+		assert.Equal(t, string(expected), string(j))
+	}
+}
+
+func TestCollector_AppendJSON_allocs(t *testing.T) {
+	c := dynhist.Collector{BucketsLimit: 100, Name: "test"}
+
+	for i := 0; i < 1000; i++ {
+		c.Add(float64(i))
+	}
+
+	buf := make([]byte, 0, 10000)
+
+	assert.Equal(t, 0.0, testing.AllocsPerRun(100, func() {
+		buf = c.AppendJSON(buf[:0])
+	}))
+}
+
+func TestCollector_MarshalJSON_nonFinite(t *testing.T) {
+	c := dynhist.Collector{}
+	c.Add(math.Inf(1))
+
+	_, err := json.Marshal(&c)
+	assert.EqualError(t, err, "json: error calling MarshalJSON for type *dynhist.Collector: json: unsupported value: +Inf")
+
+	assert.Equal(t, `{"version":1,"count":1,"sum":null,"min":"+Inf","max":"+Inf",`+
+		`"buckets":[{"min":"+Inf","max":"+Inf","count":1,"sum":null}]}`, string(c.AppendJSON(nil)))
+}
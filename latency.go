@@ -0,0 +1,21 @@
+package dynhist
+
+import "time"
+
+// LatencyBucketsLimit is a number of buckets used by NewLatency.
+const LatencyBucketsLimit = 40
+
+// NewLatency creates a collector of latencies in seconds, for example with AddDuration.
+//
+// It is configured for latencies from microseconds to seconds: LatencyBucketsLimit buckets
+// with LatencyWidth keep narrow buckets for fast responses and for the slow tail,
+// String renders boundaries as durations. On a mixture of fast path and slow tail
+// relative errors of p50, p99 and p99.9 are expected to be within 5%.
+func NewLatency() *Collector {
+	return &Collector{
+		Unit:           "seconds",
+		BucketsLimit:   LatencyBucketsLimit,
+		WeightFunc:     LatencyWidth,
+		ValueFormatter: DurationFormatter(time.Second),
+	}
+}
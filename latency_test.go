@@ -0,0 +1,53 @@
+package dynhist_test
+
+import (
+	"math"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vearutop/dynhist-go"
+	"github.com/vearutop/dynhist-go/internal/dataset"
+)
+
+func TestNewLatency(t *testing.T) {
+	for seed := uint64(1); seed <= 5; seed++ {
+		c := dynhist.NewLatency()
+		r := dataset.New(seed)
+		values := make([]float64, 0, 100000)
+
+		for i := 0; i < 100000; i++ {
+			// Fast path around 200µs with 3% slow tail around 50ms.
+			d := time.Duration(200e3 * math.Exp(0.5*r.NormFloat64()))
+			if r.Float64() < 0.03 {
+				d = time.Duration(50e6 * math.Exp(r.NormFloat64()))
+			}
+
+			values = append(values, d.Seconds())
+			c.AddDuration(d)
+		}
+
+		sort.Float64s(values)
+
+		for _, p := range []float64{50, 99, 99.9} {
+			exact := values[int(math.Ceil(p*float64(len(values))/100))-1]
+
+			assert.Less(t, math.Abs(c.Percentile(p)-exact)/exact, 0.05, "seed %d p%v", seed, p)
+		}
+
+		assert.Len(t, c.Buckets, dynhist.LatencyBucketsLimit)
+	}
+}
+
+func TestNewLatency_String(t *testing.T) {
+	c := dynhist.NewLatency()
+
+	c.AddDuration(150 * time.Microsecond)
+	c.AddDuration(2 * time.Second)
+
+	assert.Equal(t, `[  min   max] cnt total% (2 events)
+[150µs 150µs] 1 50.00% ..................................................
+[   2s    2s] 1 50.00% ..................................................
+`, c.String())
+}
@@ -0,0 +1,186 @@
+package dynhist_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vearutop/dynhist-go"
+)
+
+func TestCollector_smallBucketsLimit(t *testing.T) {
+	build := func(limit int) *dynhist.Collector {
+		c := &dynhist.Collector{BucketsLimit: limit, RawValues: []float64{}}
+
+		for i := 1; i <= 100; i++ {
+			c.Add(float64(i))
+		}
+
+		return c
+	}
+
+	twoBuckets := `[   min    max] cnt total% (100 events)
+[  1.00   2.00]   2  2.00% ..
+[  3.00 100.00]  98 98.00% ..................................................................................................
+`
+
+	for _, tc := range []struct {
+		limit       int
+		effective   int
+		p50, p99    float64
+		p50Within   float64
+		accuracyP50 float64
+		rendered    string
+	}{
+		// Limit 1 is clamped to 2, Percentile is interpolated within a bucket.
+		{limit: 1, effective: 2, p50: 50.51, p99: 99.01, p50Within: 50.5, accuracyP50: 0.51, rendered: twoBuckets},
+		{limit: 2, effective: 2, p50: 50.51, p99: 99.01, p50Within: 50.5, accuracyP50: 0.51, rendered: twoBuckets},
+		{
+			limit: 3, effective: 3, p50: 50.98, p99: 99.33, p50Within: 51, accuracyP50: 0.98,
+			rendered: `[   min    max] cnt total% (100 events)
+[  1.00  49.00]  49 49.00% .................................................
+[ 50.00  97.00]  48 48.00% ................................................
+[ 98.00 100.00]   3  3.00% ...
+`,
+		},
+	} {
+		c := build(tc.limit)
+
+		assert.Equal(t, tc.effective, c.BucketsLimit, tc.limit)
+		assert.Len(t, c.Buckets, tc.effective, tc.limit)
+		assert.InDelta(t, tc.p50, c.Percentile(50), 0.01, tc.limit)
+		assert.InDelta(t, tc.p99, c.Percentile(99), 0.01, tc.limit)
+		assert.InDelta(t, tc.p50Within, c.PercentileWithin(1, 100, 50), 1, tc.limit)
+		assert.InDelta(t, tc.accuracyP50, c.AccuracyReport([]float64{50})[0].AbsError, 0.01, tc.limit)
+		assert.Equal(t, tc.rendered, c.String(), tc.limit)
+		assert.Equal(t, 100, c.Resample([]float64{0, 50, 101})[0].Count+c.Resample([]float64{0, 50, 101})[1].Count)
+	}
+}
+
+func TestCollector_defaultBucketsLimit(t *testing.T) {
+	c := dynhist.Collector{}
+	c.Add(1)
+
+	assert.Equal(t, dynhist.DefaultBucketsLimit, c.BucketsLimit)
+}
+
+func TestMaxBucketsHardLimit(t *testing.T) {
+	defer func(prev int) { dynhist.MaxBucketsHardLimit = prev }(dynhist.MaxBucketsHardLimit)
+
+	dynhist.MaxBucketsHardLimit = 50
+
+	c := dynhist.Collector{BucketsLimit: 1e6}
+	assert.Equal(t, 50, c.EffectiveBucketsLimit())
+	assert.Equal(t, 1e6, float64(c.BucketsLimit))
+
+	c.Add(0)
+
+	// Preallocation is bounded.
+	assert.LessOrEqual(t, cap(c.Buckets), 50)
+	assert.Equal(t, 50, c.BucketsLimit)
+
+	for i := 1; i < 1000; i++ {
+		c.Add(float64(i))
+	}
+
+	assert.Len(t, c.Buckets, 50)
+
+	a := dynhist.Collector{BucketsLimit: 20, AutoLimit: true, MaxBucketShare: 0.001}
+
+	for i := 0; i < 1000; i++ {
+		a.Add(float64(i))
+	}
+
+	assert.Len(t, a.Buckets, 50)
+
+	assert.Equal(t, dynhist.DefaultBucketsLimit, (&dynhist.Collector{}).EffectiveBucketsLimit())
+	assert.Equal(t, dynhist.MinBucketsLimit, (&dynhist.Collector{BucketsLimit: -1}).EffectiveBucketsLimit())
+}
+
+func TestCollector_largeBucketsLimit(t *testing.T) {
+	c := dynhist.Collector{BucketsLimit: 1e5}
+	c.Add(1)
+
+	// Huge limit does not preallocate all buckets.
+	assert.LessOrEqual(t, cap(c.Buckets), 256)
+}
+
+func TestCollector_LimitPressure(t *testing.T) {
+	for _, tc := range []struct {
+		name      string
+		c         *dynhist.Collector
+		values    func(i int) float64
+		buckets   int
+		forcedAll bool
+	}{
+		{
+			name:    "no conflict",
+			c:       &dynhist.Collector{BucketsLimit: 5, PreserveSpikes: 2},
+			values:  func(i int) float64 { return float64(i % 50) },
+			buckets: 5,
+		},
+		{
+			// Values are added twice, so every bucket is a protected spike.
+			name:    "all protected",
+			c:       &dynhist.Collector{BucketsLimit: 3, PreserveSpikes: 100},
+			values:  func(i int) float64 { return float64(i % 10) },
+			buckets: 3,
+		},
+		{
+			// More spikes than buckets.
+			name: "spikes over limit",
+			c:    &dynhist.Collector{BucketsLimit: 4, PreserveSpikes: 6},
+			values: func(i int) float64 {
+				if i%2 == 0 {
+					return float64(i % 12)
+				}
+
+				return float64(i) / 7
+			},
+			buckets: 4,
+		},
+		{
+			// No merge satisfies width constraint, hard limit of 10x BucketsLimit wins.
+			name:      "max width",
+			c:         &dynhist.Collector{BucketsLimit: 3, AutoLimit: true, MaxRelativeWidth: 1e-6},
+			values:    func(i int) float64 { return float64(i) },
+			buckets:   30,
+			forcedAll: true,
+		},
+	} {
+		c := tc.c
+		done := make(chan struct{})
+
+		go func() {
+			for i := 0; i < 1000; i++ {
+				c.AddN(tc.values(i), 2)
+			}
+
+			close(done)
+		}()
+
+		select {
+		case <-done:
+		case <-time.After(5 * time.Second):
+			t.Fatal(tc.name, "Add does not terminate")
+		}
+
+		st := c.MergeStats()
+
+		assert.Len(t, c.Buckets, tc.buckets, tc.name)
+		assert.Equal(t, 2000, c.Count, tc.name)
+
+		if tc.name == "no conflict" {
+			assert.Equal(t, 0, st.LimitPressure, tc.name)
+
+			continue
+		}
+
+		assert.Greater(t, st.LimitPressure, 0, tc.name)
+		assert.LessOrEqual(t, st.LimitPressure, st.Merges, tc.name)
+
+		if tc.forcedAll {
+			assert.Equal(t, st.Merges, st.LimitPressure, tc.name)
+		}
+	}
+}
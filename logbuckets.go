@@ -0,0 +1,164 @@
+package dynhist
+
+import (
+	"math"
+	"sort"
+)
+
+// ToLogBuckets converts collector to DDSketch-style logarithmic buckets.
+//
+// Bucket with index k holds values in (gamma^(k-1), gamma^k], counts of dynamic buckets are
+// distributed to logarithmic buckets proportionally to overlap.
+// Values that are not positive are counted in zeroCount, negative values are not supported
+// separately (there is no negative store). Buckets with infinite boundaries are dropped.
+// Gamma must be finite and greater than 1, otherwise empty counts are returned.
+func (c *Collector) ToLogBuckets(gamma float64) (counts map[int]int, zeroCount int) {
+	if c == nil {
+		return nil, 0
+	}
+
+	if !validGamma(gamma) {
+		return map[int]int{}, 0
+	}
+
+	_, buckets := c.snapshotBuckets()
+	counts = make(map[int]int)
+
+	var bounds []float64
+
+	minPos, max := math.Inf(1), math.Inf(-1)
+	min := math.Inf(1)
+
+	for _, b := range buckets {
+		if math.IsInf(b.Min, 0) || math.IsInf(b.Max, 0) {
+			continue
+		}
+
+		min = math.Min(min, b.Min)
+		max = math.Max(max, b.Max)
+
+		for _, v := range []float64{b.Min, b.Max} {
+			if v > 0 && v < minPos {
+				minPos = v
+			}
+		}
+	}
+
+	if math.IsInf(min, 0) {
+		return counts, 0
+	}
+
+	if min <= 0 {
+		if min < 0 {
+			bounds = append(bounds, min)
+		}
+
+		bounds = append(bounds, 0, 0)
+	}
+
+	lg := math.Log(gamma)
+
+	if max > 0 {
+		first := logIndex(minPos, gamma, lg)
+		last := logIndex(max, gamma, lg)
+
+		for k := first - 1; k <= last; k++ {
+			bounds = append(bounds, math.Pow(gamma, float64(k)))
+		}
+	}
+
+	// Point masses are assigned directly, since resample uses [lo, hi) ranges for them.
+	wide := make([]Bucket, 0, len(buckets))
+
+	for _, b := range buckets {
+		switch {
+		case b.Min != b.Max:
+			wide = append(wide, b)
+		case b.Min <= 0:
+			zeroCount += b.Count
+		case !math.IsInf(b.Min, 0):
+			counts[logIndex(b.Min, gamma, lg)] += b.Count
+		}
+	}
+
+	for _, b := range resample(wide, bounds) {
+		if b.Max <= 0 {
+			zeroCount += b.Count
+
+			continue
+		}
+
+		if b.Count > 0 {
+			counts[logIndex(b.Max, gamma, lg)] += b.Count
+		}
+	}
+
+	return counts, zeroCount
+}
+
+func validGamma(gamma float64) bool {
+	return gamma > 1 && !math.IsInf(gamma, 1)
+}
+
+// logIndex returns k such that v is in (gamma^(k-1), gamma^k].
+func logIndex(v, gamma, lg float64) int {
+	k := int(math.Ceil(math.Log(v) / lg))
+
+	// Compensate floating point errors.
+	for math.Pow(gamma, float64(k)) < v {
+		k++
+	}
+
+	for k > math.MinInt32 && math.Pow(gamma, float64(k-1)) >= v {
+		k--
+	}
+
+	return k
+}
+
+// FromLogBuckets creates collector from DDSketch-style logarithmic buckets, see ToLogBuckets.
+//
+// Sum of every bucket is estimated with DDSketch value representative 2*gamma^k/(gamma+1).
+// An empty collector is returned if gamma is not finite and greater than 1 or if bucket
+// boundaries of indexes are beyond float64 range.
+func FromLogBuckets(gamma float64, counts map[int]int, zeroCount int) *Collector {
+	c := &Collector{}
+
+	if !validGamma(gamma) {
+		return c
+	}
+
+	keys := make([]int, 0, len(counts))
+
+	for k, cnt := range counts {
+		if cnt > 0 {
+			keys = append(keys, k)
+		}
+	}
+
+	sort.Ints(keys)
+
+	buckets := make([]Bucket, 0, len(keys)+1)
+
+	if zeroCount > 0 {
+		buckets = append(buckets, Bucket{Count: zeroCount})
+	}
+
+	for _, k := range keys {
+		upper := math.Pow(gamma, float64(k))
+		b := Bucket{
+			Min:   math.Nextafter(math.Pow(gamma, float64(k-1)), math.Inf(1)),
+			Max:   upper,
+			Count: counts[k],
+		}
+		b.Sum = float64(b.Count) * 2 * upper / (gamma + 1)
+
+		buckets = append(buckets, b)
+	}
+
+	if err := c.LoadBuckets(buckets); err != nil {
+		return &Collector{}
+	}
+
+	return c
+}
@@ -0,0 +1,92 @@
+package dynhist_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vearutop/dynhist-go"
+	"github.com/vearutop/dynhist-go/internal/dataset"
+)
+
+func TestCollector_ToLogBuckets(t *testing.T) {
+	const alpha = 0.01 // 1% relative error.
+
+	gamma := (1 + alpha) / (1 - alpha)
+
+	c := dynhist.Collector{
+		BucketsLimit: 500,
+		WeightFunc:   dynhist.LatencyWidth,
+		RawValues:    []float64{},
+	}
+	r := dataset.New(1)
+
+	for i := 0; i < 100000; i++ {
+		c.Add(math.Exp(r.NormFloat64() * 2))
+	}
+
+	for i := 0; i < 1000; i++ {
+		c.Add(0)
+	}
+
+	counts, zeroCount := c.ToLogBuckets(gamma)
+	assert.Equal(t, 1000, zeroCount)
+
+	total := zeroCount
+	for _, cnt := range counts {
+		total += cnt
+	}
+
+	assert.Equal(t, c.Count, total)
+
+	restored := dynhist.FromLogBuckets(gamma, counts, zeroCount)
+	assert.Equal(t, c.Count, restored.Count)
+	assert.NoError(t, restored.Validate())
+
+	for _, e := range c.AccuracyReport([]float64{25, 50, 90, 99, 99.9}) {
+		p := restored.Percentile(e.Percentile)
+
+		assert.InDelta(t, e.Exact, p, 0.05*e.Exact, e.Percentile)
+	}
+
+	// Log buckets survive round trip exactly.
+	counts2, zeroCount2 := restored.ToLogBuckets(gamma)
+	assert.Equal(t, counts, counts2)
+	assert.Equal(t, zeroCount, zeroCount2)
+}
+
+func TestCollector_ToLogBuckets_negative(t *testing.T) {
+	c := dynhist.Collector{}
+
+	for _, v := range []float64{-3, -1, 0, 1, 1, 10} {
+		c.Add(v)
+	}
+
+	counts, zeroCount := c.ToLogBuckets(2)
+	assert.Equal(t, 3, zeroCount)
+	assert.Equal(t, map[int]int{0: 2, 4: 1}, counts)
+}
+
+func TestCollector_ToLogBuckets_invalidGamma(t *testing.T) {
+	c := &dynhist.Collector{}
+	c.Add(1)
+	c.Add(10)
+
+	for _, gamma := range []float64{1, 0.5, 0, -2, math.NaN(), math.Inf(1)} {
+		counts, zeroCount := c.ToLogBuckets(gamma)
+		assert.Empty(t, counts, gamma)
+		assert.Equal(t, 0, zeroCount, gamma)
+
+		assert.NotPanics(t, func() {
+			assert.Equal(t, 0, dynhist.FromLogBuckets(gamma, map[int]int{1: 2}, 1).TotalCount())
+		}, gamma)
+	}
+
+	// Collector is not left locked.
+	c.Add(5)
+	assert.Equal(t, 3, c.TotalCount())
+
+	assert.NotPanics(t, func() {
+		assert.Equal(t, 0, dynhist.FromLogBuckets(2, map[int]int{2000: 1, 2001: 1}, 0).TotalCount())
+	})
+}
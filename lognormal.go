@@ -0,0 +1,69 @@
+package dynhist
+
+import "math"
+
+// LogNormalWidth creates a weight function for lognormally distributed data.
+//
+// Weight of a pair is the probability mass of merged range under lognormal distribution
+// with parameters mu and sigma of the underlying normal distribution (see FitLogNormal),
+// so that buckets tend to hold roughly equal shares of values. Mass is scaled up towards
+// the upper tail to keep buckets narrow enough for high percentiles.
+//
+// Where distribution function saturates (non-positive values or far tails beyond float64 precision),
+// mass is zero and weight falls back to width of merged range relative to total range, so that
+// outliers are not merged in arbitrary order.
+func LogNormalWidth(mu, sigma float64) func(b1, b2, bTot Bucket) float64 {
+	return func(b1, b2, bTot Bucket) float64 {
+		lo, hi := logNormalCDF(b1.Min, mu, sigma), logNormalCDF(b2.Max, mu, sigma)
+
+		if hi == lo {
+			if bTot.Max == bTot.Min {
+				return 0
+			}
+
+			return (b2.Max - b1.Min) / (bTot.Max - bTot.Min)
+		}
+
+		return (hi - lo) / math.Pow(1-lo, 0.9)
+	}
+}
+
+func logNormalCDF(v, mu, sigma float64) float64 {
+	if v <= 0 {
+		return 0
+	}
+
+	return 0.5 * math.Erfc(-(math.Log(v)-mu)/(sigma*math.Sqrt2))
+}
+
+// FitLogNormal estimates parameters of lognormal distribution from a sample.
+//
+// It returns mean and standard deviation of logarithms of positive values,
+// non-positive values are ignored. Zeros are returned for a sample without positive values.
+func FitLogNormal(sample []float64) (mu, sigma float64) {
+	n := 0
+
+	for _, v := range sample {
+		if v > 0 {
+			mu += math.Log(v)
+			n++
+		}
+	}
+
+	if n == 0 {
+		return 0, 0
+	}
+
+	mu /= float64(n)
+
+	for _, v := range sample {
+		if v > 0 {
+			d := math.Log(v) - mu
+			sigma += d * d
+		}
+	}
+
+	sigma = math.Sqrt(sigma / float64(n))
+
+	return mu, sigma
+}
@@ -0,0 +1,88 @@
+package dynhist_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vearutop/dynhist-go"
+	"github.com/vearutop/dynhist-go/internal/dataset"
+)
+
+func TestFitLogNormal(t *testing.T) {
+	r := dataset.New(1)
+	sample := make([]float64, 0, 10000)
+
+	for i := 0; i < 10000; i++ {
+		sample = append(sample, math.Exp(1+0.8*r.NormFloat64()))
+	}
+
+	sample = append(sample, 0, -1) // Non-positive values are ignored.
+
+	mu, sigma := dynhist.FitLogNormal(sample)
+	assert.InDelta(t, 1, mu, 0.03)
+	assert.InDelta(t, 0.8, sigma, 0.03)
+
+	mu, sigma = dynhist.FitLogNormal(nil)
+	assert.Equal(t, 0.0, mu)
+	assert.Equal(t, 0.0, sigma)
+}
+
+func TestLogNormalWidth(t *testing.T) {
+	ps := []float64{50, 95, 99}
+
+	relErrors := func(limit int, wf func(b1, b2, bTot dynhist.Bucket) float64) []float64 {
+		res := make([]float64, len(ps))
+
+		for seed := uint64(1); seed <= 10; seed++ {
+			c := dynhist.Collector{BucketsLimit: limit, WeightFunc: wf, RawValues: []float64{}}
+			r := dataset.New(seed)
+
+			for i := 0; i < 10000; i++ {
+				c.Add(math.Exp(1 + 0.8*r.NormFloat64()))
+			}
+
+			for i, e := range c.AccuracyReport(ps) {
+				res[i] += e.RelError / 10
+			}
+		}
+
+		return res
+	}
+
+	for _, limit := range []int{20, 50} {
+		exp := relErrors(limit, dynhist.ExpWidth(1.2, 1))
+		ln := relErrors(limit, dynhist.LogNormalWidth(1, 0.8))
+
+		lnMean, expMean := 0.0, 0.0
+
+		// Tail percentiles are more accurate, median may be slightly worse with interpolation.
+		for i, p := range ps {
+			if p > 50 {
+				assert.Less(t, ln[i], exp[i], "limit %d, p%v", limit, p)
+			}
+
+			lnMean += ln[i] / float64(len(ps))
+			expMean += exp[i] / float64(len(ps))
+		}
+
+		assert.Less(t, lnMean, expMean, "limit %d", limit)
+	}
+}
+
+func TestLogNormalWidth_saturated(t *testing.T) {
+	wf := dynhist.LogNormalWidth(0, 0.1)
+	tot := dynhist.Bucket{Min: -10, Max: 1e6}
+
+	// Far tail and non-positive values are weighted by width.
+	narrow := wf(dynhist.Bucket{Min: 1e3, Max: 1e3}, dynhist.Bucket{Min: 1.1e3, Max: 1.1e3}, tot)
+	wide := wf(dynhist.Bucket{Min: 1e3, Max: 1e3}, dynhist.Bucket{Min: 1e6, Max: 1e6}, tot)
+
+	assert.Greater(t, narrow, 0.0)
+	assert.Less(t, narrow, wide)
+
+	neg := wf(dynhist.Bucket{Min: -10, Max: -10}, dynhist.Bucket{Min: -5, Max: -5}, tot)
+	assert.Greater(t, neg, 0.0)
+
+	assert.Equal(t, 0.0, wf(dynhist.Bucket{Min: 5, Max: 5}, dynhist.Bucket{Min: 5, Max: 5}, dynhist.Bucket{Min: 5, Max: 5}))
+}
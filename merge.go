@@ -0,0 +1,199 @@
+package dynhist
+
+import "math"
+
+// Merge adds data of other collector.
+//
+// Buckets of both collectors are resampled to the union of their boundaries and summed,
+// then merged down to BucketsLimit. Counts are conserved, distribution within a bucket is
+// assumed uniform when bucket layouts differ. Merging collectors with equal layouts is exact.
+// Merging a nil collector is a no-op.
+func (c *Collector) Merge(other *Collector) {
+	if c == nil || other == nil {
+		return
+	}
+
+	src := other.mergeSource()
+
+	c.Lock()
+	defer c.Unlock()
+
+	c.SumEstimated = c.SumEstimated || src.estimated && len(src.buckets) > 0
+	c.mergeBuckets(src.total, src.buckets)
+	c.mergeTop(src.top, src.topCount, src.keepTopK)
+}
+
+// mergeSource is data of other collector for Merge.
+type mergeSource struct {
+	total     Bucket
+	buckets   []Bucket
+	estimated bool
+	top       []float64
+	topCount  int
+	keepTopK  int
+}
+
+// mergeSource copies data in original units in a single critical section,
+// so that concurrent Add does not tear totals from buckets.
+func (c *Collector) mergeSource() mergeSource {
+	c.Lock()
+	defer c.Unlock()
+
+	s := mergeSource{
+		total:     c.Bucket,
+		buckets:   append([]Bucket(nil), c.Buckets...),
+		estimated: c.SumEstimated,
+		top:       append([]float64(nil), c.top...),
+		topCount:  c.topCount,
+		keepTopK:  c.KeepTopK,
+	}
+
+	c.Transform.outward(&s.total, s.buckets)
+
+	return s
+}
+
+// Reset removes collected data and keeps configuration.
+func (c *Collector) Reset() {
+	if c == nil {
+		return
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	c.reset()
+}
+
+func (c *Collector) reset() {
+	c.generation++
+	c.Bucket = Bucket{}
+	c.Buckets = nil
+	c.SumEstimated = false
+	c.quantiles = nil
+	c.ids = nil
+	c.labels = nil
+	c.seen = nil
+	c.resetTop()
+	c.rawPartial = false
+
+	for i := range c.thresholds {
+		c.thresholds[i].count = 0
+	}
+
+	for _, w := range c.watchers {
+		w.q = newP2Quantile(w.q.percent)
+	}
+	c.Merges = 0
+	c.LastMergeWidth = 0
+	c.MergedWidth = 0
+	c.LimitPressure = 0
+
+	if c.RawValues != nil {
+		c.RawValues = c.RawValues[:0]
+	}
+}
+
+// drain returns data in original units and resets collector.
+func (c *Collector) drain() (Bucket, []Bucket) {
+	c.Lock()
+	defer c.Unlock()
+
+	total, buckets := c.Bucket, c.Buckets
+	c.Transform.outward(&total, buckets)
+	c.reset()
+
+	return total, buckets
+}
+
+func (c *Collector) mergeBuckets(total Bucket, buckets []Bucket) {
+	if len(buckets) == 0 {
+		return
+	}
+
+	c.setDefaults()
+	c.Transform.inward(&total, buckets)
+	c.generation++
+	c.rawPartial = true
+	c.labels = nil
+	c.seen = nil
+
+	if len(c.Buckets) == 0 {
+		c.Bucket = total
+		c.Buckets = append(make([]Bucket, 0, len(buckets)), buckets...)
+
+		if c.TrackIDs {
+			c.resetIDs()
+		}
+
+		c.mergeOverLimit()
+
+		return
+	}
+
+	ra, rb := align(c.Buckets, buckets)
+
+	for i := range ra {
+		ra[i].Count += rb[i].Count
+		ra[i].Sum += rb[i].Sum
+	}
+
+	c.Buckets = collapse(ra)
+	c.Count += total.Count
+	c.Sum += total.Sum
+	c.Min = math.Min(c.Min, total.Min)
+	c.Max = math.Max(c.Max, total.Max)
+
+	if c.TrackIDs {
+		c.resetIDs()
+	}
+
+	c.mergeOverLimit()
+}
+
+// collapse drops empty ranges of resampled buckets in place and separates shared boundaries.
+//
+// A sum of dropped range (rounded out parts of resampled buckets) goes to the next bucket.
+func collapse(buckets []Bucket) []Bucket {
+	res := buckets[:0]
+	pendingSum := 0.0
+
+	for _, b := range buckets {
+		b.Sum += pendingSum
+		pendingSum = 0
+
+		if b.Count == 0 {
+			pendingSum = b.Sum
+
+			continue
+		}
+
+		res = append(res, b)
+	}
+
+	if len(res) > 0 {
+		res[len(res)-1].Sum += pendingSum
+	}
+
+	return separate(res)
+}
+
+// separate adjusts shared boundaries of adjacent resampled buckets so that buckets do not overlap,
+// zero-width buckets keep their value.
+func separate(buckets []Bucket) []Bucket {
+	for i := 1; i < len(buckets); i++ {
+		prev, b := &buckets[i-1], &buckets[i]
+
+		if b.Min > prev.Max {
+			continue
+		}
+
+		if b.Min == b.Max {
+			prev.Max = math.Nextafter(prev.Max, math.Inf(-1))
+		} else {
+			b.Min = math.Nextafter(b.Min, math.Inf(1))
+		}
+	}
+
+	return buckets
+}
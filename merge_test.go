@@ -0,0 +1,187 @@
+package dynhist_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vearutop/dynhist-go"
+	"github.com/vearutop/dynhist-go/internal/dataset"
+)
+
+func TestCollector_Merge(t *testing.T) {
+	r := dataset.New(1)
+
+	for k := 0; k < 100; k++ {
+		a, b := randomCollector(r), randomCollector(r)
+		count, sum := a.Count+b.Count, a.Sum+b.Sum
+		min, max := a.Min, a.Max
+
+		if b.Min < min {
+			min = b.Min
+		}
+
+		if b.Max > max {
+			max = b.Max
+		}
+
+		a.Merge(b)
+
+		require.NoError(t, a.Validate())
+		assert.LessOrEqual(t, len(a.Buckets), a.BucketsLimit)
+		assert.Equal(t, count, a.Count)
+		assert.InDelta(t, sum, a.Sum, 1e-9*(1+sum))
+		assert.Equal(t, min, a.Min)
+		assert.Equal(t, max, a.Max)
+
+		bucketsSum := 0.0
+		for _, bk := range a.Buckets {
+			bucketsSum += bk.Sum
+		}
+
+		assert.InDelta(t, sum, bucketsSum, 1e-6*(1+sum))
+	}
+}
+
+func TestCollector_Merge_sameLayout(t *testing.T) {
+	a := dynhist.Collector{BucketsLimit: 3}
+	b := dynhist.Collector{BucketsLimit: 3}
+	e := dynhist.Collector{}
+
+	for i := 0; i < 9; i++ {
+		a.Add(float64(i))
+		b.Add(float64(i))
+	}
+
+	a.Merge(&b)
+	a.Merge(&e)
+
+	assert.Equal(t, []dynhist.Bucket{
+		{Min: 0, Max: 3, Count: 8, Sum: 12},
+		{Min: 4, Max: 6, Count: 6, Sum: 30},
+		{Min: 7, Max: 8, Count: 4, Sum: 30},
+	}, a.Buckets)
+
+	e.Merge(&b)
+	assert.Empty(t, dynhist.DiffString(&b, &e))
+}
+
+func TestCollector_Reset(t *testing.T) {
+	c := dynhist.Collector{BucketsLimit: 3, Name: "test"}
+
+	for i := 0; i < 9; i++ {
+		c.Add(float64(i))
+	}
+
+	c.Reset()
+
+	assert.Equal(t, 0, c.Count)
+	assert.Empty(t, c.Buckets)
+	assert.Equal(t, 0, c.Merges)
+	assert.Equal(t, 3, c.BucketsLimit)
+	assert.Equal(t, "test", c.Name)
+
+	c.Add(5)
+	assert.Equal(t, 5.0, c.Min)
+	assert.Equal(t, 5.0, c.Max)
+}
+
+func TestCollector_MergeDown(t *testing.T) {
+	for name, cfg := range map[string]*dynhist.Collector{
+		"avg":     {},
+		"latency": {WeightFunc: dynhist.LatencyWidth},
+		"exp":     {WeightFunc: dynhist.ExpWidth(1.2, 0.9)},
+		"epsilon": {Epsilon: 0.05},
+		"ids":     {TrackIDs: true},
+		"spikes":  {PreserveSpikes: 3},
+		"snap":    {SnapBoundaries: 0.5, WeightFunc: dynhist.LatencyWidth},
+		"ties": {WeightFunc: func(b1, b2, bTot dynhist.Bucket) float64 {
+			return float64((b1.Count + b2.Count) % 3)
+		}},
+	} {
+		cfg := cfg
+
+		t.Run(name, func(t *testing.T) {
+			c := cfg.Clone()
+			c.BucketsLimit = 5000
+			r := dataset.New(1)
+
+			for i := 0; i < 5000; i++ {
+				c.Add(float64(r.Intn(3000)) / 10)
+			}
+
+			naive := c.Clone()
+			naive.MergeDownNaive(20)
+			c.MergeDown(20)
+
+			require.Len(t, c.Buckets, 20)
+			assert.Equal(t, naive.Buckets, c.Buckets)
+			assert.Equal(t, naive.BucketIDs(), c.BucketIDs())
+			assert.Equal(t, naive.MergeStats(), c.MergeStats())
+		})
+	}
+}
+
+func BenchmarkCollector_MergeDown(b *testing.B) {
+	src := dynhist.Collector{BucketsLimit: 5000, WeightFunc: dynhist.ExpWidth(1.2, 0.9)}
+	r := dataset.New(1)
+
+	for src.Count < 5000 || len(src.Buckets) < 5000 {
+		src.Add(r.ExpFloat64())
+	}
+
+	for _, bc := range []struct {
+		name  string
+		merge func(c *dynhist.Collector, limit int)
+	}{
+		{name: "heap", merge: (*dynhist.Collector).MergeDown},
+		{name: "naive", merge: (*dynhist.Collector).MergeDownNaive},
+	} {
+		bc := bc
+
+		b.Run(bc.name, func(b *testing.B) {
+			b.ReportAllocs()
+
+			for i := 0; i < b.N; i++ {
+				b.StopTimer()
+				c := src.Clone()
+				b.StartTimer()
+
+				bc.merge(c, 20)
+			}
+		})
+	}
+}
+
+func TestCollector_Merge_concurrent(t *testing.T) {
+	src := &dynhist.Collector{BucketsLimit: 10}
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		for i := 0; i < 10000; i++ {
+			src.Add(float64(i % 100))
+		}
+	}()
+
+	for {
+		select {
+		case <-done:
+			return
+		default:
+		}
+
+		dst := &dynhist.Collector{BucketsLimit: 10}
+		dst.Merge(src)
+
+		require.NoError(t, dst.Validate())
+
+		count := 0
+		for _, b := range dst.Buckets {
+			count += b.Count
+		}
+
+		require.Equal(t, dst.Count, count)
+	}
+}
@@ -0,0 +1,101 @@
+package dynhist_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vearutop/dynhist-go"
+)
+
+func TestCollector_nil_allMethods(t *testing.T) {
+	var c *dynhist.Collector
+
+	v := reflect.ValueOf(c)
+	typ := v.Type()
+
+	for i := 0; i < typ.NumMethod(); i++ {
+		m := typ.Method(i)
+
+		switch m.Name {
+		case "Lock", "Unlock", "TryLock":
+			// Promoted from embedded mutex.
+			continue
+		case "MergeDown", "MergeDownNaive":
+			// Test helpers of export_test.go.
+			continue
+		}
+
+		args := make([]reflect.Value, m.Type.NumIn()-1)
+		for j := range args {
+			args[j] = reflect.Zero(m.Type.In(j + 1))
+		}
+
+		assert.NotPanics(t, func() {
+			out := v.Method(i).Call(args)
+
+			for _, o := range out {
+				if err, ok := o.Interface().(error); ok && err != nil {
+					assert.True(t, errors.Is(err, dynhist.ErrNilCollector), "%s: %v", m.Name, err)
+				}
+			}
+		}, m.Name)
+	}
+}
+
+func TestCollector_nil(t *testing.T) {
+	var c *dynhist.Collector
+
+	c.Add(1)
+	c.AddN(1, 10)
+	c.AddDuration(time.Second)
+	c.AddInt64(1)
+
+	assert.Equal(t, "", c.String())
+	assert.True(t, math.IsNaN(c.Percentile(50)))
+	assert.Equal(t, 0, c.TotalCount())
+	assert.Nil(t, c.Clone())
+	assert.Equal(t, []byte("x"), c.AppendJSON([]byte("x")))
+	assert.NoError(t, c.Close())
+
+	_, err := c.MarshalJSON()
+	assert.True(t, errors.Is(err, dynhist.ErrNilCollector))
+
+	var buf bytes.Buffer
+
+	assert.True(t, errors.Is(c.WritePrometheus(&buf, "m"), dynhist.ErrNilCollector))
+	assert.True(t, errors.Is(c.WriteIndexed(&buf), dynhist.ErrNilCollector))
+	assert.True(t, errors.Is(c.StreamJSON(context.Background(), &buf, time.Second), dynhist.ErrNilCollector))
+	assert.True(t, errors.Is(c.LoadBuckets([]dynhist.Bucket{{Min: 1, Max: 1, Count: 1, Sum: 1}}), dynhist.ErrNilCollector))
+	assert.Equal(t, 0, buf.Len())
+
+	n, err := c.ReadLines(context.Background(), strings.NewReader("1\n2\n"), nil)
+	assert.Equal(t, 0, n)
+	assert.True(t, errors.Is(err, dynhist.ErrNilCollector))
+
+	stop := c.WatchPercentile(50, 1, 0, func(bool, float64) {})
+	stop()
+
+	// Nil collector is an empty operand of binary operations.
+	other := &dynhist.Collector{}
+	other.Add(1)
+	other.Merge(c)
+	assert.Equal(t, 1, other.TotalCount())
+
+	assert.Equal(t, 2, dynhist.WeightedMerge(c, other, 1, 2).TotalCount())
+	assert.Equal(t, 1, dynhist.WeightedMerge(other, c, 1, 2).TotalCount())
+	assert.Equal(t, 0, dynhist.WeightedMerge(c, c, 1, 1).TotalCount())
+	assert.Nil(t, other.AccuracyAgainst(c, []float64{50}))
+
+	var tee *dynhist.TeeAdder
+
+	tee.Add(1)
+	tee.AddN(1, 10)
+	tee.AddDuration(time.Second)
+}
@@ -0,0 +1,242 @@
+package dynhist
+
+import (
+	"math"
+	"sort"
+)
+
+// p2Quantile is a state of P² (Jain-Chlamtac) estimator of a single quantile.
+type p2Quantile struct {
+	percent float64
+	n       int        // Number of observations.
+	q       [5]float64 // Marker heights.
+	pos     [5]float64 // Actual marker positions.
+	desired [5]float64 // Desired marker positions.
+	inc     [5]float64 // Increments of desired positions.
+}
+
+func newP2Quantile(percent float64) p2Quantile {
+	p := percent / 100
+
+	return p2Quantile{
+		percent: percent,
+		pos:     [5]float64{0, 1, 2, 3, 4},
+		desired: [5]float64{0, 2 * p, 4 * p, 2 + 2*p, 4},
+		inc:     [5]float64{0, p / 2, p, (1 + p) / 2, 1},
+	}
+}
+
+// p2ExactWeight is the largest weight that is added to P² estimator value by value.
+const p2ExactWeight = 64
+
+// addN adds value n times.
+//
+// Small weights are added value by value, so that the estimate is the same as of repeated
+// additions. Larger weights are added at once and markers are moved by up to n positions,
+// so that time does not depend on n.
+func (e *p2Quantile) addN(v float64, n int) {
+	for ; n > 0 && (e.n < 5 || n <= p2ExactWeight); n-- {
+		e.add(v, 1)
+	}
+
+	if n > 0 {
+		e.add(v, n)
+	}
+}
+
+func (e *p2Quantile) add(v float64, n int) {
+	if e.n < 5 {
+		e.q[e.n] = v
+		e.n++
+
+		if e.n == 5 {
+			sort.Float64s(e.q[:])
+		}
+
+		return
+	}
+
+	e.n += n
+
+	var k int
+
+	switch {
+	case v < e.q[0]:
+		e.q[0] = v
+		k = 0
+	case v < e.q[1]:
+		k = 0
+	case v < e.q[2]:
+		k = 1
+	case v < e.q[3]:
+		k = 2
+	case v <= e.q[4]:
+		k = 3
+	default:
+		e.q[4] = v
+		k = 3
+	}
+
+	w := float64(n)
+
+	// Copies of a weighted value take ranks after old values of cell k below v.
+	run := p2Run{first: e.pos[k] + 1, value: v}
+	if old := e.pos[k+1] - e.pos[k] - 1; n > 1 && old > 0 && e.q[k+1] > e.q[k] {
+		run.first += math.Round(old * (v - e.q[k]) / (e.q[k+1] - e.q[k]))
+	}
+
+	run.last = run.first + w - 1
+
+	for i := k + 1; i < 5; i++ {
+		e.pos[i] += w
+	}
+
+	for i := range e.desired {
+		e.desired[i] += w * e.inc[i]
+	}
+
+	if n == 1 {
+		e.adjust()
+
+		return
+	}
+
+	// Markers may need a few passes to make room for each other.
+	for passes := 0; passes < 10 && e.adjustWeighted(run, w); passes++ {
+	}
+}
+
+// adjust moves markers by one position towards desired positions.
+func (e *p2Quantile) adjust() {
+	for i := 1; i < 4; i++ {
+		d := e.desired[i] - e.pos[i]
+
+		if (d >= 1 && e.pos[i+1]-e.pos[i] > 1) || (d <= -1 && e.pos[i-1]-e.pos[i] < -1) {
+			s := 1.0
+			if d < 0 {
+				s = -1
+			}
+
+			q := e.parabolic(i, s)
+			if e.q[i-1] < q && q < e.q[i+1] {
+				e.q[i] = q
+			} else {
+				j := i + int(s)
+				e.q[i] += s * (e.q[j] - e.q[i]) / (e.pos[j] - e.pos[i])
+			}
+
+			e.pos[i] += s
+		}
+	}
+}
+
+// p2Run is a range of ranks taken by copies of a weighted value.
+type p2Run struct {
+	first, last float64
+	value       float64
+}
+
+// adjustWeighted moves markers by up to w positions towards desired positions,
+// it returns true if any marker moved.
+//
+// Height at a new position is interpolated linearly between neighboring markers
+// and ends of the run of weighted value copies.
+func (e *p2Quantile) adjustWeighted(run p2Run, w float64) bool {
+	moved := false
+
+	for i := 1; i < 4; i++ {
+		d := e.desired[i] - e.pos[i]
+
+		if (d >= 1 && e.pos[i+1]-e.pos[i] > 1) || (d <= -1 && e.pos[i-1]-e.pos[i] < -1) {
+			// Step is a whole number of positions keeping marker between its neighbors.
+			s := math.Trunc(d)
+			s = math.Max(math.Min(s, math.Min(w, e.pos[i+1]-e.pos[i]-1)), math.Max(-w, e.pos[i-1]-e.pos[i]+1))
+
+			e.q[i] = e.height(i, e.pos[i]+s, run)
+			e.pos[i] += s
+			moved = true
+		}
+	}
+
+	return moved
+}
+
+// height returns interpolated value at rank p between neighbors of marker i.
+func (e *p2Quantile) height(i int, p float64, run p2Run) float64 {
+	if p >= run.first && p <= run.last {
+		return run.value
+	}
+
+	// Closest known points below and above p.
+	loPos, loQ := e.pos[i-1], e.q[i-1]
+	hiPos, hiQ := e.pos[i+1], e.q[i+1]
+
+	if e.pos[i] <= p {
+		loPos, loQ = e.pos[i], e.q[i]
+	} else {
+		hiPos, hiQ = e.pos[i], e.q[i]
+	}
+
+	if run.last < p && run.last > loPos {
+		loPos, loQ = run.last, run.value
+	}
+
+	if run.first > p && run.first < hiPos {
+		hiPos, hiQ = run.first, run.value
+	}
+
+	return loQ + (hiQ-loQ)*(p-loPos)/(hiPos-loPos)
+}
+
+func (e *p2Quantile) parabolic(i int, s float64) float64 {
+	q, n := e.q, e.pos
+
+	return q[i] + s/(n[i+1]-n[i-1])*
+		((n[i]-n[i-1]+s)*(q[i+1]-q[i])/(n[i+1]-n[i])+(n[i+1]-n[i]-s)*(q[i]-q[i-1])/(n[i]-n[i-1]))
+}
+
+// value returns current estimate, exact nearest-rank value is used for less than 5 observations.
+func (e *p2Quantile) value() float64 {
+	if e.n >= 5 {
+		return e.q[2]
+	}
+
+	sorted := append([]float64(nil), e.q[:e.n]...)
+	sort.Float64s(sorted)
+
+	return exactPercentile(sorted, e.percent)
+}
+
+func (c *Collector) trackQuantiles(v float64, n int) {
+	if c.quantiles == nil {
+		c.quantiles = make([]p2Quantile, 0, len(c.TrackQuantiles))
+
+		for _, p := range c.TrackQuantiles {
+			c.quantiles = append(c.quantiles, newP2Quantile(p))
+		}
+	}
+
+	for i := range c.quantiles {
+		c.quantiles[i].addN(v, n)
+	}
+}
+
+// QuickPercentile returns P² estimate of a percent from TrackQuantiles.
+//
+// It returns false if percent is not tracked or there are no values yet.
+func (c *Collector) QuickPercentile(percent float64) (float64, bool) {
+	if c == nil {
+		return 0, false
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	for i := range c.quantiles {
+		if c.quantiles[i].percent == percent && c.quantiles[i].n > 0 {
+			return c.quantiles[i].value(), true
+		}
+	}
+
+	return 0, false
+}
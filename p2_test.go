@@ -0,0 +1,126 @@
+package dynhist_test
+
+import (
+	"math"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vearutop/dynhist-go"
+	"github.com/vearutop/dynhist-go/internal/dataset"
+)
+
+func BenchmarkCollector_Add_trackQuantiles(b *testing.B) {
+	b.ReportAllocs()
+
+	c := dynhist.Collector{PrintSum: true, TrackQuantiles: []float64{50, 90, 99}}
+
+	for i := 0; i < b.N; i++ {
+		c.Add(float64(i))
+		c.Add(float64(b.N - i))
+	}
+}
+
+func TestCollector_QuickPercentile(t *testing.T) {
+	ps := []float64{50, 90, 99}
+
+	for name, gen := range map[string]func(r *dataset.Source) float64{
+		"uniform":     func(r *dataset.Source) float64 { return r.Float64() * 100 },
+		"exponential": func(r *dataset.Source) float64 { return r.ExpFloat64() },
+		"lognormal":   func(r *dataset.Source) float64 { return math.Exp(1 + 0.8*r.NormFloat64()) },
+		"normal":      func(r *dataset.Source) float64 { return 50 + 10*r.NormFloat64() },
+	} {
+		c := dynhist.Collector{BucketsLimit: 20, TrackQuantiles: ps}
+		r := dataset.New(1)
+		values := make([]float64, 0, 100000)
+
+		for i := 0; i < 100000; i++ {
+			v := gen(r)
+			values = append(values, v)
+			c.Add(v)
+		}
+
+		sort.Float64s(values)
+
+		for _, p := range ps {
+			exact := values[int(math.Ceil(p*float64(len(values))/100))-1]
+
+			quick, ok := c.QuickPercentile(p)
+			assert.True(t, ok)
+
+			quickErr := math.Abs(quick-exact) / exact
+			bucketErr := math.Abs(c.Percentile(p)-exact) / exact
+
+			assert.Less(t, quickErr, 0.01, "%s p%v", name, p)
+			// Interpolation within buckets is nearly exact for uniform data.
+			if name != "uniform" {
+				assert.Less(t, quickErr, bucketErr, "%s p%v", name, p)
+			}
+		}
+	}
+}
+
+func TestCollector_QuickPercentile_few(t *testing.T) {
+	c := dynhist.Collector{TrackQuantiles: []float64{50}}
+
+	_, ok := c.QuickPercentile(50)
+	assert.False(t, ok)
+
+	c.Add(3)
+	c.Add(1)
+	c.Add(2)
+
+	v, ok := c.QuickPercentile(50)
+	assert.True(t, ok)
+	assert.Equal(t, 2.0, v)
+
+	_, ok = c.QuickPercentile(99)
+	assert.False(t, ok)
+}
+
+func TestCollector_QuickPercentile_addN(t *testing.T) {
+	r := dataset.New(1)
+	single := &dynhist.Collector{TrackQuantiles: []float64{50, 90}}
+	weighted := &dynhist.Collector{TrackQuantiles: []float64{50, 90}}
+
+	for i := 0; i < 2000; i++ {
+		v := r.NormFloat64()*10 + 50
+		n := 1 + r.Intn(20)
+
+		weighted.AddN(v, n)
+
+		for j := 0; j < n; j++ {
+			single.Add(v)
+		}
+	}
+
+	for _, p := range []float64{50, 90} {
+		want, _ := single.QuickPercentile(p)
+		got, ok := weighted.QuickPercentile(p)
+
+		assert.True(t, ok)
+		assert.Equal(t, want, got, p)
+	}
+
+	// Large weights are applied at once.
+	crossed := make(chan bool, 1)
+	big := &dynhist.Collector{TrackQuantiles: []float64{50}}
+	big.WatchPercentile(50, 5, 0, func(c bool, _ float64) { crossed <- c })
+
+	big.AddN(1, 10)
+	big.AddN(10, 5e9)
+
+	v, _ := big.QuickPercentile(50)
+	assert.Equal(t, 10.0, v)
+
+	// Weighted values spread over the range.
+	spread := &dynhist.Collector{TrackQuantiles: []float64{50}}
+
+	for i := 0; i < 1000; i++ {
+		spread.AddN(float64(i%100), 1000)
+	}
+
+	v, _ = spread.QuickPercentile(50)
+	assert.InDelta(t, 50, v, 5)
+	assert.True(t, <-crossed)
+}
@@ -0,0 +1,213 @@
+package dynhist
+
+// mergePair is a pair of adjacent buckets in pairHeap.
+type mergePair struct {
+	near    bool    // Pair is a near-duplicate with Epsilon.
+	weight  float64 // Weight of the pair, not used for near-duplicates.
+	left    int     // Stable position of the first bucket.
+	version int     // Version of the pair at left when the entry was pushed.
+}
+
+// pairHeap is a min-heap that orders pairs like mergePoint scans them: near-duplicates first
+// by position, then by weight and by position among equal weights.
+type pairHeap []mergePair
+
+func (h pairHeap) less(i, j int) bool {
+	a, b := h[i], h[j]
+
+	if a.near != b.near {
+		return a.near
+	}
+
+	if !a.near && a.weight != b.weight {
+		return a.weight < b.weight
+	}
+
+	return a.left < b.left
+}
+
+func (h *pairHeap) push(p mergePair) {
+	*h = append(*h, p)
+	h.up(len(*h) - 1)
+}
+
+func (h *pairHeap) pop() mergePair {
+	old := *h
+	p := old[0]
+	n := len(old) - 1
+
+	old[0] = old[n]
+	*h = old[:n]
+	h.down(0)
+
+	return p
+}
+
+func (h pairHeap) up(i int) {
+	for i > 0 {
+		parent := (i - 1) / 2
+		if !h.less(i, parent) {
+			return
+		}
+
+		h[i], h[parent] = h[parent], h[i]
+		i = parent
+	}
+}
+
+func (h pairHeap) down(i int) {
+	for {
+		least := i
+
+		for _, child := range []int{2*i + 1, 2*i + 2} {
+			if child < len(h) && h.less(child, least) {
+				least = child
+			}
+		}
+
+		if least == i {
+			return
+		}
+
+		h[i], h[least] = h[least], h[i]
+		i = least
+	}
+}
+
+// fenwick counts remaining buckets by stable position to find their current index.
+type fenwick []int
+
+func newFenwick(n int) fenwick {
+	f := make(fenwick, n+1)
+
+	for i := 1; i <= n; i++ {
+		f[i]++
+
+		if j := i + i&-i; j <= n {
+			f[j] += f[i]
+		}
+	}
+
+	return f
+}
+
+// remove removes position p.
+func (f fenwick) remove(p int) {
+	for i := p + 1; i < len(f); i += i & -i {
+		f[i]--
+	}
+}
+
+// index returns number of remaining positions before p.
+func (f fenwick) index(p int) int {
+	n := 0
+
+	for i := p; i > 0; i -= i & -i {
+		n += f[i]
+	}
+
+	return n
+}
+
+// pairMerger keeps pairs of buckets in a heap while merging down, so that a merge
+// takes O(log n) comparisons and only pairs changed by the merge are reweighed.
+//
+// Buckets are identified by stable positions (indexes before merging), a merged bucket
+// takes position of its first bucket. Entries of changed pairs are invalidated lazily by version.
+type pairMerger struct {
+	c        *Collector
+	h        pairHeap
+	next     []int
+	prev     []int
+	versions []int
+	alive    fenwick
+}
+
+func newPairMerger(c *Collector) *pairMerger {
+	n := len(c.Buckets)
+	m := &pairMerger{
+		c:        c,
+		h:        make(pairHeap, 0, n),
+		next:     make([]int, n),
+		prev:     make([]int, n),
+		versions: make([]int, n),
+		alive:    newFenwick(n),
+	}
+
+	for p := range m.next {
+		m.next[p] = p + 1
+		m.prev[p] = p - 1
+	}
+
+	m.next[n-1] = -1
+
+	for p := 0; p < n-1; p++ {
+		m.h = append(m.h, m.pair(p, p+1))
+	}
+
+	for i := len(m.h)/2 - 1; i >= 0; i-- {
+		m.h.down(i)
+	}
+
+	return m
+}
+
+// pair returns heap entry of a pair with the first bucket at position p and current index i.
+func (m *pairMerger) pair(p, i int) mergePair {
+	c := m.c
+
+	if c.Epsilon > 0 && c.nearDuplicate(c.Buckets[i-1], c.Buckets[i]) {
+		return mergePair{near: true, left: p, version: m.versions[p]}
+	}
+
+	return mergePair{weight: c.weight(i), left: p, version: m.versions[p]}
+}
+
+// reweigh replaces entry of a pair with the first bucket at position p.
+func (m *pairMerger) reweigh(p int) {
+	if p < 0 || m.next[p] < 0 {
+		return
+	}
+
+	m.versions[p]++
+	m.h.push(m.pair(p, m.alive.index(p)+1))
+}
+
+// mergeNext merges the pair with minimal weight.
+func (m *pairMerger) mergeNext() {
+	var top mergePair
+
+	for {
+		top = m.h.pop()
+		if top.version == m.versions[top.left] && m.next[top.left] >= 0 {
+			break
+		}
+	}
+
+	p := top.left
+	q := m.next[p]
+
+	m.c.mergeAt(m.alive.index(p) + 1)
+
+	m.alive.remove(q)
+	m.versions[q]++
+	m.next[p] = m.next[q]
+
+	if m.next[q] >= 0 {
+		m.prev[m.next[q]] = p
+	}
+
+	m.reweigh(m.prev[p])
+	m.reweigh(p)
+
+	// Snapping moves boundaries of neighbors, so pairs with them are reweighed too.
+	if m.c.SnapBoundaries > 0 {
+		if m.prev[p] >= 0 {
+			m.reweigh(m.prev[m.prev[p]])
+		}
+
+		if m.next[p] >= 0 {
+			m.reweigh(m.next[p])
+		}
+	}
+}
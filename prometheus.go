@@ -0,0 +1,65 @@
+package dynhist
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// ErrNoName is returned by exporters when metric name is not provided and Collector.Name is empty.
+var ErrNoName = errors.New("metric name is required")
+
+// WritePrometheus writes collector as a histogram in Prometheus text exposition format.
+//
+// If name is empty, Collector.Name is used, Collector.Help is used as HELP text.
+// Upper boundaries of buckets are used as "le" labels.
+// ErrNilCollector is returned for a nil collector.
+func (c *Collector) WritePrometheus(w io.Writer, name string) error {
+	if c == nil {
+		return ErrNilCollector
+	}
+
+	total, buckets := c.snapshotBuckets()
+	cumulative := cumulativeCounts(buckets, nil)
+
+	c.Lock()
+	help := c.Help
+
+	if name == "" {
+		name = c.Name
+	}
+	c.Unlock()
+
+	if name == "" {
+		return ErrNoName
+	}
+
+	bw := bufio.NewWriter(w)
+
+	if help != "" {
+		bw.WriteString("# HELP " + name + " " + escapeHelp(help) + "\n")
+	}
+
+	bw.WriteString("# TYPE " + name + " histogram\n")
+
+	for _, cc := range cumulative {
+		if math.IsInf(cc.LE, 1) {
+			continue
+		}
+
+		bw.WriteString(name + `_bucket{le="` + formatRaw(cc.LE) + `"} ` + strconv.Itoa(cc.Count) + "\n")
+	}
+
+	bw.WriteString(name + `_bucket{le="+Inf"} ` + strconv.Itoa(total.Count) + "\n")
+	bw.WriteString(name + "_sum " + formatRaw(total.Sum) + "\n")
+	bw.WriteString(name + "_count " + strconv.Itoa(total.Count) + "\n")
+
+	return bw.Flush()
+}
+
+func escapeHelp(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(s)
+}
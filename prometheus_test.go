@@ -0,0 +1,46 @@
+package dynhist_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vearutop/dynhist-go"
+)
+
+func TestCollector_WritePrometheus(t *testing.T) {
+	c := dynhist.Collector{BucketsLimit: 3}
+
+	for i := 1; i <= 10; i++ {
+		c.Add(float64(i) / 10)
+	}
+
+	buf := bytes.NewBuffer(nil)
+
+	assert.Equal(t, dynhist.ErrNoName, c.WritePrometheus(buf, ""))
+
+	require.NoError(t, c.WritePrometheus(buf, "latency_seconds"))
+	assert.Equal(t, `# TYPE latency_seconds histogram
+latency_seconds_bucket{le="0.3"} 3
+latency_seconds_bucket{le="0.6"} 6
+latency_seconds_bucket{le="1"} 10
+latency_seconds_bucket{le="+Inf"} 10
+latency_seconds_sum 5.5
+latency_seconds_count 10
+`, buf.String())
+
+	c.Name = "request_duration_seconds"
+	c.Help = "Duration of request."
+
+	buf.Reset()
+	require.NoError(t, c.WritePrometheus(buf, ""))
+	assert.Contains(t, buf.String(), "# HELP request_duration_seconds Duration of request.\n")
+	assert.Contains(t, buf.String(), `request_duration_seconds_bucket{le="0.3"} 3`)
+
+	// Explicit name overrides default.
+	buf.Reset()
+	require.NoError(t, c.WritePrometheus(buf, "other_seconds"))
+	assert.Contains(t, buf.String(), "# HELP other_seconds Duration of request.\n")
+	assert.Contains(t, buf.String(), `other_seconds_count 10`)
+}
@@ -0,0 +1,321 @@
+package dynhist
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ErrPrometheusText is returned for malformed lines of Prometheus text exposition format.
+var ErrPrometheusText = errors.New("malformed Prometheus text")
+
+// promSeries accumulates samples of a histogram with a particular label set.
+type promSeries struct {
+	les    []float64
+	counts []float64 // Cumulative counts by les.
+	sum    float64
+	hasSum bool
+	count  float64
+	hasCnt bool
+}
+
+// ParsePrometheusText reads histogram metricName from Prometheus text exposition format.
+//
+// Collectors are returned by label set without "le", formatted as `{a="1",b="2"}` with sorted
+// names, or an empty string for a histogram without labels. Cumulative "le" buckets are converted
+// to buckets with counts of values in (previous le, le], the first bucket starts at 0 (or at its
+// upper bound if it is not positive). Values above the last finite le are kept in a bucket that
+// is estimated from remaining _sum, bucket sums are estimated with midpoints and SumEstimated is set.
+// Missing _sum is estimated from buckets, missing +Inf bucket is taken from _count.
+// Label sets without buckets are skipped.
+func ParsePrometheusText(r io.Reader, metricName string) (map[string]*Collector, error) {
+	series := make(map[string]*promSeries)
+	help := ""
+	s := bufio.NewScanner(r)
+	line := 0
+
+	for s.Scan() {
+		line++
+		text := strings.TrimSpace(s.Text())
+
+		if text == "" {
+			continue
+		}
+
+		if strings.HasPrefix(text, "#") {
+			if h := strings.TrimPrefix(text, "# HELP "+metricName+" "); h != text {
+				help = unescapeHelp(h)
+			}
+
+			continue
+		}
+
+		if err := parsePromSample(series, text, metricName); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+	}
+
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+
+	res := make(map[string]*Collector, len(series))
+
+	for key, ps := range series {
+		total, buckets, err := ps.buckets()
+		if err != nil {
+			return nil, fmt.Errorf("%s%s: %w", metricName, key, err)
+		}
+
+		if len(buckets) == 0 {
+			continue
+		}
+
+		c := &Collector{Name: metricName, Help: help, BucketsLimit: DefaultBucketsLimit, SumEstimated: true}
+
+		if c.BucketsLimit < len(buckets) {
+			c.BucketsLimit = len(buckets)
+		}
+
+		c.mergeBuckets(total, buckets)
+		res[key] = c
+	}
+
+	return res, nil
+}
+
+// parsePromSample adds a sample of histogram metricName to series, other metrics are ignored.
+func parsePromSample(series map[string]*promSeries, text, metricName string) error {
+	name := text
+	labels := ""
+
+	if i := strings.IndexAny(text, "{ \t"); i >= 0 {
+		name = text[:i]
+		labels = text[i:]
+	}
+
+	suffix := strings.TrimPrefix(name, metricName)
+	if suffix == name || (suffix != "_bucket" && suffix != "_sum" && suffix != "_count") {
+		return nil
+	}
+
+	pairs, rest, err := parsePromLabels(labels)
+	if err != nil {
+		return err
+	}
+
+	fields := strings.Fields(rest)
+	if len(fields) == 0 || len(fields) > 2 {
+		return fmt.Errorf("%w: expected value and optional timestamp in %q", ErrPrometheusText, text)
+	}
+
+	v, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrPrometheusText, err)
+	}
+
+	le := ""
+	key := make([]string, 0, len(pairs))
+
+	for _, p := range pairs {
+		if p[0] == "le" {
+			le = p[1]
+
+			continue
+		}
+
+		key = append(key, p[0]+`="`+escapeLabel(p[1])+`"`)
+	}
+
+	sort.Strings(key)
+
+	k := ""
+	if len(key) > 0 {
+		k = "{" + strings.Join(key, ",") + "}"
+	}
+
+	ps := series[k]
+	if ps == nil {
+		ps = &promSeries{}
+		series[k] = ps
+	}
+
+	switch suffix {
+	case "_sum":
+		ps.sum, ps.hasSum = v, true
+	case "_count":
+		ps.count, ps.hasCnt = v, true
+	default:
+		bound, err := strconv.ParseFloat(le, 64)
+		if err != nil {
+			return fmt.Errorf("%w: invalid le %q", ErrPrometheusText, le)
+		}
+
+		ps.les = append(ps.les, bound)
+		ps.counts = append(ps.counts, v)
+	}
+
+	return nil
+}
+
+// parsePromLabels parses an optional label set in curly braces and returns the remainder of line.
+func parsePromLabels(s string) (pairs [][2]string, rest string, err error) {
+	s = strings.TrimLeft(s, " \t")
+	if !strings.HasPrefix(s, "{") {
+		return nil, s, nil
+	}
+
+	s = s[1:]
+
+	for {
+		s = strings.TrimLeft(s, " \t,")
+
+		if strings.HasPrefix(s, "}") {
+			return pairs, s[1:], nil
+		}
+
+		eq := strings.Index(s, `="`)
+		if eq <= 0 {
+			return nil, "", fmt.Errorf("%w: invalid label in %q", ErrPrometheusText, s)
+		}
+
+		name := strings.TrimSpace(s[:eq])
+		s = s[eq+2:]
+
+		var (
+			val     strings.Builder
+			closed  bool
+			escaped bool
+		)
+
+		for i := 0; i < len(s); i++ {
+			ch := s[i]
+
+			switch {
+			case escaped:
+				if ch == 'n' {
+					ch = '\n'
+				}
+
+				val.WriteByte(ch)
+
+				escaped = false
+			case ch == '\\':
+				escaped = true
+			case ch == '"':
+				s = s[i+1:]
+				closed = true
+			default:
+				val.WriteByte(ch)
+			}
+
+			if closed {
+				break
+			}
+		}
+
+		if !closed {
+			return nil, "", fmt.Errorf("%w: unterminated label value of %s", ErrPrometheusText, name)
+		}
+
+		pairs = append(pairs, [2]string{name, val.String()})
+	}
+}
+
+// buckets converts cumulative counts to buckets.
+func (ps *promSeries) buckets() (total Bucket, buckets []Bucket, err error) {
+	idx := make([]int, len(ps.les))
+	for i := range idx {
+		idx[i] = i
+	}
+
+	sort.SliceStable(idx, func(i, j int) bool { return ps.les[idx[i]] < ps.les[idx[j]] })
+
+	prevLe, prevCnt := math.Inf(-1), 0.0
+	overflow := math.NaN()
+	sum := 0.0
+
+	for _, i := range idx {
+		le, cnt := ps.les[i], ps.counts[i]
+
+		if le == prevLe {
+			continue
+		}
+
+		if cnt < prevCnt || cnt != math.Trunc(cnt) {
+			return total, nil, fmt.Errorf("%w: invalid cumulative count %v at le %v", ErrPrometheusText, cnt, le)
+		}
+
+		if math.IsInf(le, 1) {
+			overflow = cnt - prevCnt
+
+			break
+		}
+
+		n, lo := cnt-prevCnt, prevLe
+		if math.IsInf(lo, -1) {
+			lo = math.Min(0, le)
+		}
+
+		prevLe, prevCnt = le, cnt
+
+		if n == 0 {
+			continue
+		}
+
+		b := Bucket{Min: lo, Max: le, Count: int(n), Sum: n * (lo + le) / 2}
+		sum += b.Sum
+		buckets = append(buckets, b)
+	}
+
+	if math.IsNaN(overflow) && ps.hasCnt && ps.count >= prevCnt && !math.IsInf(prevLe, -1) {
+		overflow = ps.count - prevCnt
+	}
+
+	if overflow > 0 && !math.IsInf(prevLe, -1) {
+		b := Bucket{Min: prevLe, Max: prevLe, Count: int(overflow)}
+
+		// Values are assumed uniform in the overflow bucket, its upper bound is derived from the remaining sum.
+		if ps.hasSum {
+			if avg := (ps.sum - sum) / overflow; avg > b.Min {
+				b.Max = 2*avg - b.Min
+			}
+		}
+
+		b.Sum = overflow * (b.Min + b.Max) / 2
+		sum += b.Sum
+		buckets = append(buckets, b)
+	}
+
+	if len(buckets) == 0 {
+		return total, nil, nil
+	}
+
+	buckets = separate(buckets)
+	total.Min = buckets[0].Min
+	total.Max = buckets[len(buckets)-1].Max
+	total.Sum = sum
+
+	if ps.hasSum {
+		total.Sum = ps.sum
+	}
+
+	for _, b := range buckets {
+		total.Count += b.Count
+	}
+
+	return total, buckets, nil
+}
+
+func unescapeHelp(s string) string {
+	return strings.NewReplacer(`\\`, `\`, `\n`, "\n").Replace(s)
+}
+
+func escapeLabel(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`).Replace(s)
+}
@@ -0,0 +1,104 @@
+package dynhist_test
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vearutop/dynhist-go"
+	"github.com/vearutop/dynhist-go/internal/golden"
+)
+
+func TestParsePrometheusText(t *testing.T) {
+	f, err := os.Open("testdata/prometheus/http_request_duration.prom")
+	require.NoError(t, err)
+
+	defer func() {
+		require.NoError(t, f.Close())
+	}()
+
+	res, err := dynhist.ParsePrometheusText(f, "http_request_duration_seconds")
+	require.NoError(t, err)
+	require.Len(t, res, 2)
+
+	get := res[`{handler="/api/users",method="GET"}`]
+	require.NotNil(t, get)
+	assert.Equal(t, 6306, get.Count)
+	assert.InDelta(t, 88.317, get.Sum, 1e-3)
+	assert.Equal(t, "Duration of HTTP requests.", get.Help)
+	assert.Equal(t, "http_request_duration_seconds", get.Name)
+	assert.True(t, get.SumEstimated)
+	assert.InDelta(t, 0.01245, get.Percentile(50), 1e-4)
+
+	post := res[`{handler="/api/upload",method="POST"}`]
+	require.NotNil(t, post)
+	assert.Equal(t, 157, post.Count)
+	assert.InDelta(t, 183.752, post.Sum, 1e-3)
+
+	// Values above the last finite bucket are estimated from the remaining sum.
+	assert.Greater(t, post.Max, 10.0)
+
+	golden.Assert(t, "prometheus_text", get.String()+"\n"+post.String())
+}
+
+func TestParsePrometheusText_noSum(t *testing.T) {
+	f, err := os.Open("testdata/prometheus/no_sum.prom")
+	require.NoError(t, err)
+
+	defer func() {
+		require.NoError(t, f.Close())
+	}()
+
+	res, err := dynhist.ParsePrometheusText(f, "rpc_size_bytes")
+	require.NoError(t, err)
+	require.Len(t, res, 1)
+
+	c := res[""]
+	require.NotNil(t, c)
+	assert.Equal(t, 47, c.Count)
+	assert.Equal(t, 0.0, c.Min)
+	assert.Equal(t, 10000.0, c.Max, "values above last le are kept at the boundary without sum")
+	assert.InDelta(t, 12*50+28*550+5*5500+2*10000, c.Sum, 1)
+}
+
+func TestParsePrometheusText_roundTrip(t *testing.T) {
+	c := dynhist.Collector{BucketsLimit: 5}
+
+	for i := 1; i <= 100; i++ {
+		c.Add(float64(i))
+	}
+
+	buf := bytes.NewBuffer(nil)
+	require.NoError(t, c.WritePrometheus(buf, "values"))
+
+	res, err := dynhist.ParsePrometheusText(buf, "values")
+	require.NoError(t, err)
+	require.Len(t, res, 1)
+
+	assert.Equal(t, c.Count, res[""].Count)
+	assert.Equal(t, c.Sum, res[""].Sum)
+	assert.Equal(t, c.Max, res[""].Max)
+	assert.InDelta(t, c.Percentile(50), res[""].Percentile(50), 5)
+}
+
+func TestParsePrometheusText_errors(t *testing.T) {
+	for _, s := range []string{
+		`m_bucket{le="1"} 5` + "\n" + `m_bucket{le="2"} 3`,
+		`m_bucket{le="abc"} 5`,
+		`m_bucket{le="1} 5`,
+		`m_bucket{le="1"}`,
+		`m_bucket{le="1"} x`,
+		`m_bucket{le="1"} 1.5`,
+	} {
+		_, err := dynhist.ParsePrometheusText(strings.NewReader(s), "m")
+		assert.True(t, errors.Is(err, dynhist.ErrPrometheusText), s, err)
+	}
+
+	res, err := dynhist.ParsePrometheusText(strings.NewReader(`other_bucket{le="1"} 5`), "m")
+	require.NoError(t, err)
+	assert.Empty(t, res)
+}
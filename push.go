@@ -0,0 +1,345 @@
+package dynhist
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrPushFormat is returned by ListenAndAggregate handlers for malformed or oversized snapshots.
+	ErrPushFormat = errors.New("invalid pushed snapshot")
+
+	// ErrPushRejected is returned by PushTo when aggregator rejects a snapshot.
+	ErrPushRejected = errors.New("snapshot rejected by aggregator")
+)
+
+const (
+	// MaxPushSize is a maximum size of a pushed snapshot frame in bytes.
+	MaxPushSize = 1 << 20
+
+	// pushTimeout limits exchange of a single snapshot.
+	pushTimeout = 10 * time.Second
+
+	pushAccepted = 0
+	pushRejected = 1
+)
+
+// pushSource identifies pushing process.
+var pushSource = func() string {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "unknown"
+	}
+
+	return host + "/" + strconv.Itoa(os.Getpid())
+}()
+
+// PushTo sends collected data to ListenAndAggregate at addr and resets collector.
+//
+// Address is "host:port" for TCP or "unix:/path/to/socket" for a unix socket. Data is taken
+// and reset atomically, so periodic pushes send every value once. If sending fails or
+// the snapshot is rejected, data is merged back into collector with threshold counts, largest
+// values, raw values and P² estimates, and an error is returned. Delivery is at least once:
+// a snapshot merged by aggregator is sent again if the reply is lost.
+//
+// Snapshot is sent as a frame of 4-byte big-endian length, source identifier (host/pid)
+// and buckets in the format of EncodeURL (starting with SchemaVersion).
+// Aggregator replies with a byte of 0 for accepted snapshot.
+// ErrNilCollector is returned for a nil collector.
+func (c *Collector) PushTo(addr string) error {
+	if c == nil {
+		return ErrNilCollector
+	}
+
+	c.Lock()
+	name, unit, help := c.Name, c.Unit, c.Help
+	c.Unlock()
+
+	s := c.drainPush()
+
+	err := pushFrame(addr, encodeBinary(name, unit, help, s.buckets))
+	if err != nil {
+		c.restorePush(s)
+	}
+
+	return err
+}
+
+// pushState is a state discarded by reset that is restored if snapshot is not delivered.
+type pushState struct {
+	total        Bucket
+	buckets      []Bucket
+	sumEstimated bool
+	top          []float64
+	topCount     int
+	thresholds   []trackedThreshold
+	quantiles    []p2Quantile
+	watchers     map[*percentileWatcher]p2Quantile
+	rawValues    []float64
+	rawPartial   bool
+}
+
+// drainPush returns data in original units with the rest of discarded state and resets collector.
+func (c *Collector) drainPush() pushState {
+	c.Lock()
+	defer c.Unlock()
+
+	s := pushState{
+		total:        c.Bucket,
+		buckets:      c.Buckets,
+		sumEstimated: c.SumEstimated,
+		top:          append([]float64(nil), c.top...),
+		topCount:     c.topCount,
+		thresholds:   append([]trackedThreshold(nil), c.thresholds...),
+		quantiles:    c.quantiles,
+		rawValues:    append([]float64(nil), c.RawValues...),
+		rawPartial:   c.rawPartial,
+	}
+
+	if len(c.watchers) > 0 {
+		s.watchers = make(map[*percentileWatcher]p2Quantile, len(c.watchers))
+
+		for _, w := range c.watchers {
+			s.watchers[w] = w.q
+		}
+	}
+
+	c.Transform.outward(&s.total, s.buckets)
+	c.reset()
+
+	return s
+}
+
+// restorePush merges drained state back into collector that may have new values.
+//
+// P² estimates can not be merged, an estimate that has seen more values is kept.
+func (c *Collector) restorePush(s pushState) {
+	c.Lock()
+	defer c.Unlock()
+
+	c.SumEstimated = c.SumEstimated || s.sumEstimated && len(s.buckets) > 0
+	partial := c.rawPartial || s.rawPartial
+	c.mergeBuckets(s.total, s.buckets)
+	c.rawPartial = partial
+	c.mergeTop(s.top, s.topCount, c.KeepTopK)
+
+	for _, t := range s.thresholds {
+		for i := range c.thresholds {
+			if c.thresholds[i].name == t.name {
+				c.thresholds[i].count += t.count
+			}
+		}
+	}
+
+	if len(s.quantiles) > 0 && (len(c.quantiles) == 0 || c.quantiles[0].n < s.quantiles[0].n) {
+		c.quantiles = s.quantiles
+	}
+
+	for w, q := range s.watchers {
+		if w.q.n < q.n {
+			w.q = q
+		}
+	}
+
+	if c.RawValues != nil {
+		c.RawValues = append(s.rawValues, c.RawValues...)
+	}
+}
+
+func pushFrame(addr string, snapshot []byte) error {
+	frame := make([]byte, 4, 4+binary.MaxVarintLen64+len(pushSource)+len(snapshot))
+	frame = appendUvarint(frame, uint64(len(pushSource)))
+	frame = append(frame, pushSource...)
+	frame = append(frame, snapshot...)
+
+	if len(frame)-4 > MaxPushSize {
+		return fmt.Errorf("%w: %d bytes", ErrPushFormat, len(frame)-4)
+	}
+
+	binary.BigEndian.PutUint32(frame, uint32(len(frame)-4))
+
+	network, address := pushNetwork(addr)
+
+	conn, err := net.DialTimeout(network, address, pushTimeout)
+	if err != nil {
+		return err
+	}
+
+	defer conn.Close() //nolint:errcheck // Result is known from reply.
+
+	if err := conn.SetDeadline(time.Now().Add(pushTimeout)); err != nil {
+		return err
+	}
+
+	if _, err := conn.Write(frame); err != nil {
+		return err
+	}
+
+	var reply [1]byte
+
+	if _, err := io.ReadFull(conn, reply[:]); err != nil {
+		return err
+	}
+
+	if reply[0] != pushAccepted {
+		return ErrPushRejected
+	}
+
+	return nil
+}
+
+// ListenAndAggregate accepts snapshots sent with PushTo at addr and merges them into collector.
+//
+// Address is "host:port" for TCP or "unix:/path/to/socket" for a unix socket. Malformed or
+// oversized snapshots (over MaxPushSize) are rejected without changing collector.
+// Time of the last accepted snapshot of every source is available with Sources.
+// It returns ctx.Err() when ctx is done, after pending snapshots are handled.
+// ErrNilCollector is returned for a nil collector.
+func ListenAndAggregate(ctx context.Context, addr string, into *Collector) error {
+	if into == nil {
+		return ErrNilCollector
+	}
+
+	network, address := pushNetwork(addr)
+
+	ln, err := (&net.ListenConfig{}).Listen(ctx, network, address)
+	if err != nil {
+		return err
+	}
+
+	var wg sync.WaitGroup
+
+	done := make(chan struct{})
+
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-done:
+		}
+
+		_ = ln.Close() //nolint:errcheck // Closing to stop Accept.
+	}()
+
+	defer func() {
+		close(done)
+		wg.Wait()
+	}()
+
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+
+			return err
+		}
+
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			handlePush(conn, into)
+		}()
+	}
+}
+
+// handlePush reads a snapshot frame, merges it into collector and replies with a status byte.
+func handlePush(conn net.Conn, into *Collector) {
+	defer conn.Close() //nolint:errcheck // Nothing to do with error.
+
+	if err := conn.SetDeadline(time.Now().Add(pushTimeout)); err != nil {
+		return
+	}
+
+	source, c, err := readPush(conn)
+	if err != nil {
+		_, _ = conn.Write([]byte{pushRejected}) //nolint:errcheck // Best effort.
+
+		return
+	}
+
+	into.Merge(c)
+
+	into.Lock()
+	if into.sources == nil {
+		into.sources = make(map[string]time.Time)
+	}
+
+	into.sources[source] = now()
+	into.Unlock()
+
+	_, _ = conn.Write([]byte{pushAccepted}) //nolint:errcheck // Snapshot is already merged.
+}
+
+// readPush reads and decodes a snapshot frame.
+func readPush(r io.Reader) (source string, c *Collector, err error) {
+	var size [4]byte
+
+	if _, err := io.ReadFull(r, size[:]); err != nil {
+		return "", nil, err
+	}
+
+	n := binary.BigEndian.Uint32(size[:])
+	if n > MaxPushSize {
+		return "", nil, fmt.Errorf("%w: %d bytes", ErrPushFormat, n)
+	}
+
+	frame := make([]byte, n)
+
+	if _, err := io.ReadFull(r, frame); err != nil {
+		return "", nil, err
+	}
+
+	d := urlDecoder{data: frame}
+	source = d.string()
+
+	if d.err != nil {
+		return "", nil, fmt.Errorf("%w: %v", ErrPushFormat, d.err)
+	}
+
+	c, err = decodeBinary(d.data)
+	if err != nil {
+		return "", nil, fmt.Errorf("%w: %v", ErrPushFormat, err)
+	}
+
+	return source, c, nil
+}
+
+// Sources returns time of the last snapshot accepted from every source by ListenAndAggregate.
+//
+// Sources are identified by host name and process id of PushTo, they are kept on Reset.
+func (c *Collector) Sources() map[string]time.Time {
+	if c == nil {
+		return nil
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	res := make(map[string]time.Time, len(c.sources))
+
+	for s, t := range c.sources {
+		res[s] = t
+	}
+
+	return res
+}
+
+func pushNetwork(addr string) (network, address string) {
+	if path := strings.TrimPrefix(addr, "unix:"); path != addr {
+		return "unix", path
+	}
+
+	return "tcp", addr
+}
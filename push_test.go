@@ -0,0 +1,165 @@
+package dynhist_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vearutop/dynhist-go"
+)
+
+// startAggregator runs ListenAndAggregate until the test ends and waits for listener to be ready.
+func startAggregator(t *testing.T, network, address string, into *dynhist.Collector) {
+	t.Helper()
+
+	addr := address
+	if network == "unix" {
+		addr = "unix:" + address
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+
+	go func() {
+		done <- dynhist.ListenAndAggregate(ctx, addr, into)
+	}()
+
+	t.Cleanup(func() {
+		cancel()
+		assert.True(t, errors.Is(<-done, context.Canceled))
+	})
+
+	for i := 0; ; i++ {
+		conn, err := net.Dial(network, address)
+		if err == nil {
+			require.NoError(t, conn.Close())
+
+			return
+		}
+
+		require.Less(t, i, 100, err)
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestListenAndAggregate(t *testing.T) {
+	defer dynhist.SetNow(func() time.Time { return time.Unix(1700000000, 0) })()
+
+	into := &dynhist.Collector{BucketsLimit: 10}
+	sock := filepath.Join(t.TempDir(), "agg.sock")
+
+	startAggregator(t, "unix", sock, into)
+
+	w1 := &dynhist.Collector{Name: "latency"}
+	w2 := &dynhist.Collector{}
+
+	for i := 1; i <= 100; i++ {
+		w1.Add(float64(i))
+		w2.Add(float64(i + 100))
+	}
+
+	require.NoError(t, w1.PushTo("unix:"+sock))
+	require.NoError(t, w2.PushTo("unix:"+sock))
+
+	assert.Equal(t, 0, w1.TotalCount(), "pushed data is reset")
+	assert.Equal(t, 200, into.TotalCount())
+	assert.Equal(t, 1.0, into.MinValue())
+	assert.Equal(t, 200.0, into.MaxValue())
+	assert.InDelta(t, 100.5, into.Percentile(50), 1)
+
+	sources := into.Sources()
+	require.Len(t, sources, 1, "both workers are in the same process")
+
+	for _, ts := range sources {
+		assert.Equal(t, time.Unix(1700000000, 0), ts)
+	}
+
+	before := into.String()
+
+	// Malformed and oversized frames are rejected.
+	for _, frame := range [][]byte{
+		{0, 0, 0, 3, 1, 'a', 0xff},
+		{0, 0, 0, 0},
+		{0, 0x10, 0, 1}, // MaxPushSize + 1.
+	} {
+		conn, err := net.Dial("unix", sock)
+		require.NoError(t, err)
+
+		_, err = conn.Write(frame)
+		require.NoError(t, err)
+
+		reply, err := io.ReadAll(conn)
+		require.NoError(t, err)
+		assert.Equal(t, []byte{1}, reply)
+		require.NoError(t, conn.Close())
+	}
+
+	assert.Equal(t, before, into.String())
+}
+
+func TestCollector_PushTo_tcp(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	addr := ln.Addr().String()
+	require.NoError(t, ln.Close())
+
+	into := &dynhist.Collector{}
+	startAggregator(t, "tcp", addr, into)
+
+	c := &dynhist.Collector{}
+	c.Add(1)
+	c.Add(2)
+
+	require.NoError(t, c.PushTo(addr))
+	assert.Equal(t, 2, into.TotalCount())
+}
+
+func TestCollector_PushTo_failure(t *testing.T) {
+	c := &dynhist.Collector{KeepTopK: 3, TrackQuantiles: []float64{50}, RawValues: []float64{}}
+	require.NoError(t, c.TrackThreshold("slow", 5))
+
+	crossed := 0
+	stop := c.WatchPercentile(50, 4, 0, func(bool, float64) { crossed++ })
+
+	defer stop()
+
+	for i := 1; i <= 10; i++ {
+		c.Add(float64(i))
+	}
+
+	c.SumEstimated = true
+	p50, _ := c.QuickPercentile(50)
+
+	assert.Error(t, c.PushTo("unix:"+filepath.Join(t.TempDir(), "missing.sock")))
+
+	// Data is kept for the next attempt.
+	assert.Equal(t, 10, c.TotalCount())
+	assert.Equal(t, 1.0, c.MinValue())
+	assert.Equal(t, 10.0, c.MaxValue())
+	assert.True(t, c.SumEstimated)
+	assert.Equal(t, map[string]int{"slow": 5}, c.ThresholdCounts())
+	assert.Equal(t, []float64{10, 9, 8}, c.TopValues())
+	assert.Len(t, c.RawValues, 10)
+
+	q, ok := c.QuickPercentile(50)
+	assert.True(t, ok)
+	assert.Equal(t, p50, q)
+
+	// Watcher keeps its estimate and does not cross again.
+	c.Add(5)
+	assert.Equal(t, 1, crossed)
+	assert.Equal(t, map[string]int{"slow": 5}, c.ThresholdCounts())
+	assert.Len(t, c.RawValues, 11)
+}
+
+func TestListenAndAggregate_nil(t *testing.T) {
+	err := dynhist.ListenAndAggregate(context.Background(), "unix:"+filepath.Join(t.TempDir(), "agg.sock"), nil)
+	assert.True(t, errors.Is(err, dynhist.ErrNilCollector), err)
+}
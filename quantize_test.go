@@ -0,0 +1,37 @@
+package dynhist_test
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vearutop/dynhist-go"
+	"github.com/vearutop/dynhist-go/internal/dataset"
+)
+
+func TestCollector_Quantize(t *testing.T) {
+	plain := dynhist.Collector{BucketsLimit: 200}
+	quantized := dynhist.Collector{BucketsLimit: 200, Quantize: time.Millisecond.Seconds()}
+	r := dataset.New(1)
+
+	// Nanosecond durations around 50ms.
+	for i := 0; i < 10000; i++ {
+		d := time.Duration(50e6 + 5e6*r.NormFloat64())
+		plain.AddDuration(d)
+		quantized.AddDuration(d)
+	}
+
+	assert.Less(t, quantized.Merges, plain.Merges/10)
+	assert.Less(t, len(quantized.Buckets), 200)
+
+	for _, b := range quantized.Buckets {
+		assert.Equal(t, b.Min, b.Max, "values share zero-width buckets")
+		assert.InDelta(t, 0, math.Remainder(b.Min, 0.001), 1e-12)
+	}
+
+	// Sums are not quantized.
+	assert.Equal(t, plain.Count, quantized.Count)
+	assert.InDelta(t, plain.Sum, quantized.Sum, 1e-9)
+	assert.InDelta(t, plain.Percentile(50), quantized.Percentile(50), 0.001)
+}
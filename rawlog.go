@@ -0,0 +1,137 @@
+package dynhist
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+	"strconv"
+)
+
+// rawLogMagic starts binary raw log, it is followed by a byte of SchemaVersion.
+var rawLogMagic = []byte("DHRL")
+
+// writeRaw appends value to RawWriter, write errors are stored in LastWriteErr.
+func (c *Collector) writeRaw(v float64) {
+	if c.rawClosed {
+		return
+	}
+
+	if c.rawBuf == nil {
+		c.rawBuf = bufio.NewWriter(c.RawWriter)
+
+		if !c.RawText {
+			c.rawBuf.Write(rawLogMagic)             //nolint:errcheck // Error is checked on Flush.
+			c.rawBuf.WriteByte(byte(SchemaVersion)) //nolint:errcheck // Error is checked on Flush.
+		}
+	}
+
+	var (
+		buf [32]byte
+		b   []byte
+	)
+
+	if c.RawText {
+		b = strconv.AppendFloat(buf[:0], v, 'g', -1, 64)
+		b = append(b, '\n')
+	} else {
+		b = buf[:8]
+		binary.LittleEndian.PutUint64(b, math.Float64bits(v))
+	}
+
+	if _, err := c.rawBuf.Write(b); err != nil {
+		c.LastWriteErr = err
+	}
+}
+
+// Flush writes buffered raw values to RawWriter.
+func (c *Collector) Flush() error {
+	if c == nil {
+		return nil
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	if c.rawBuf == nil {
+		return c.LastWriteErr
+	}
+
+	if err := c.rawBuf.Flush(); err != nil {
+		c.LastWriteErr = err
+	}
+
+	return c.LastWriteErr
+}
+
+// Close flushes buffered raw values and closes RawWriter if it implements io.Closer.
+//
+// Values collected after Close are not written to RawWriter, so that the log has a single header.
+func (c *Collector) Close() error {
+	if c == nil {
+		return nil
+	}
+
+	err := c.Flush()
+
+	c.Lock()
+	defer c.Unlock()
+
+	if cl, ok := c.RawWriter.(io.Closer); ok {
+		if cErr := cl.Close(); err == nil {
+			err = cErr
+		}
+	}
+
+	c.rawBuf = nil
+	c.rawClosed = true
+
+	return err
+}
+
+// ReplayRaw adds values from a raw log written with RawWriter to collector.
+//
+// Both binary and text (RawText) formats are supported.
+func ReplayRaw(r io.Reader, c *Collector) error {
+	br := bufio.NewReader(r)
+
+	head, err := br.Peek(len(rawLogMagic) + 1)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+
+	if !bytes.HasPrefix(head, rawLogMagic) {
+		return replayText(br, c)
+	}
+
+	if err := checkVersion(int(head[len(rawLogMagic)])); err != nil {
+		return err
+	}
+
+	if _, err := br.Discard(len(head)); err != nil {
+		return err
+	}
+
+	var buf [8]byte
+
+	for {
+		if _, err := io.ReadFull(br, buf[:]); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+
+			return err
+		}
+
+		c.Add(math.Float64frombits(binary.LittleEndian.Uint64(buf[:])))
+	}
+}
+
+func replayText(r io.Reader, c *Collector) error {
+	_, err := c.ReadLines(context.Background(), r, nil)
+
+	return err
+}
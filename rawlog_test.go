@@ -0,0 +1,102 @@
+package dynhist_test
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vearutop/dynhist-go"
+	"github.com/vearutop/dynhist-go/internal/dataset"
+)
+
+func TestReplayRaw(t *testing.T) {
+	fn := filepath.Join(t.TempDir(), "raw.log")
+
+	f, err := os.Create(fn)
+	require.NoError(t, err)
+
+	c := dynhist.Collector{RawWriter: f}
+	r := dataset.New(1)
+
+	for i := 0; i < 1000000; i++ {
+		c.Add(r.ExpFloat64())
+	}
+
+	require.NoError(t, c.Close())
+
+	f, err = os.Open(fn) //nolint:gosec
+	require.NoError(t, err)
+
+	defer func() {
+		require.NoError(t, f.Close())
+	}()
+
+	replayed := dynhist.Collector{}
+	require.NoError(t, dynhist.ReplayRaw(f, &replayed))
+
+	assert.Equal(t, c.String(), replayed.String())
+	assert.Equal(t, c.Sum, replayed.Sum)
+}
+
+func TestReplayRaw_text(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	c := dynhist.Collector{RawWriter: buf, RawText: true}
+
+	for _, v := range []float64{0.1, 2, 3e10, -4} {
+		c.Add(v)
+	}
+
+	assert.Equal(t, "", buf.String(), "values are buffered")
+	require.NoError(t, c.Flush())
+	assert.Equal(t, "0.1\n2\n3e+10\n-4\n", buf.String())
+
+	replayed := dynhist.Collector{}
+	require.NoError(t, dynhist.ReplayRaw(buf, &replayed))
+	assert.Equal(t, c.String(), replayed.String())
+
+	err := dynhist.ReplayRaw(strings.NewReader("1\nfoo\n"), &replayed)
+	assert.EqualError(t, err, `line 2: strconv.ParseFloat: parsing "foo": invalid syntax`)
+}
+
+func TestCollector_RawWriter_error(t *testing.T) {
+	c := dynhist.Collector{RawWriter: failingWriter{}}
+
+	for i := 0; i < 10000; i++ {
+		c.Add(float64(i))
+	}
+
+	assert.Equal(t, 10000, c.Count)
+	assert.EqualError(t, c.Flush(), "failed")
+	assert.EqualError(t, c.LastWriteErr, "failed")
+}
+
+func TestReplayRaw_version(t *testing.T) {
+	err := dynhist.ReplayRaw(strings.NewReader("DHRL\x07"), &dynhist.Collector{})
+	assert.True(t, errors.Is(err, dynhist.ErrUnsupportedVersion))
+}
+
+func TestCollector_Close_rawWriter(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	c := dynhist.Collector{RawWriter: buf}
+
+	c.Add(1)
+	require.NoError(t, c.Close())
+
+	// Values after Close are collected, but not written.
+	c.Add(2)
+	require.NoError(t, c.Flush())
+	require.NoError(t, c.Close())
+
+	assert.Equal(t, 2, c.Count)
+	assert.Equal(t, 5+8, buf.Len())
+	assert.Equal(t, 1, strings.Count(buf.String(), "DHRL"))
+
+	replayed := dynhist.Collector{}
+	require.NoError(t, dynhist.ReplayRaw(buf, &replayed))
+	assert.Equal(t, 1, replayed.Count)
+}
@@ -0,0 +1,163 @@
+package dynhist
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+var (
+	errDotInCommaFormat = errors.New("unexpected dot in comma-separated decimal")
+	errWeightedFields   = errors.New("expected value and count")
+	errNegativeCount    = errors.New("negative count")
+)
+
+// ctxCheckLines is a number of lines between context checks in ReadLines.
+const ctxCheckLines = 1000
+
+// ParseFloat parses a float64 value from a trimmed line.
+func ParseFloat(s string) (float64, error) {
+	return strconv.ParseFloat(strings.TrimSpace(s), 64)
+}
+
+// ParseFloatComma parses a float64 value with comma as a decimal separator, for example "0,25".
+//
+// Dots are rejected, because they may be used as thousands separators in such locales.
+func ParseFloatComma(s string) (float64, error) {
+	s = strings.TrimSpace(s)
+
+	if strings.Contains(s, ".") {
+		return 0, fmt.Errorf("%w: %q", errDotInCommaFormat, s)
+	}
+
+	return strconv.ParseFloat(strings.Replace(s, ",", ".", 1), 64)
+}
+
+// ParseWeighted parses a value and a non-negative integer count separated by whitespace, for example "0.25 12".
+func ParseWeighted(s string) (v float64, n int, err error) {
+	fields := strings.Fields(s)
+	if len(fields) != 2 {
+		return 0, 0, fmt.Errorf("%w, %d fields in %q", errWeightedFields, len(fields), s)
+	}
+
+	if v, err = strconv.ParseFloat(fields[0], 64); err != nil {
+		return 0, 0, err
+	}
+
+	if n, err = strconv.Atoi(fields[1]); err != nil {
+		return 0, 0, err
+	}
+
+	if n < 0 {
+		return 0, 0, fmt.Errorf("%w: %d", errNegativeCount, n)
+	}
+
+	return v, n, nil
+}
+
+// ReadLines adds values parsed from lines of r.
+//
+// If parse is nil, ParseFloat is used. Empty lines are skipped.
+// Context is checked periodically, so that reading of a hanging stream can be abandoned,
+// lines longer than MaxLineLen result in bufio.ErrTooLong.
+// It returns number of added values and the first error.
+func (c *Collector) ReadLines(ctx context.Context, r io.Reader, parse func(string) (float64, error)) (n int, err error) {
+	if c == nil {
+		return 0, ErrNilCollector
+	}
+
+	if parse == nil {
+		parse = ParseFloat
+	}
+
+	err = c.scanLines(ctx, r, func(text string) error {
+		v, err := parse(text)
+		if err != nil {
+			return err
+		}
+
+		c.Add(v)
+		n++
+
+		return nil
+	})
+
+	return n, err
+}
+
+// ReadWeightedLines adds values with counts parsed from lines of r, for example pre-aggregated exports.
+//
+// If parse is nil, ParseWeighted is used. Lines are read as in ReadLines.
+// It returns number of added values (sum of counts) and the first error.
+func (c *Collector) ReadWeightedLines(
+	ctx context.Context,
+	r io.Reader,
+	parse func(string) (float64, int, error),
+) (n int, err error) {
+	if c == nil {
+		return 0, ErrNilCollector
+	}
+
+	if parse == nil {
+		parse = ParseWeighted
+	}
+
+	err = c.scanLines(ctx, r, func(text string) error {
+		v, cnt, err := parse(text)
+		if err != nil {
+			return err
+		}
+
+		c.AddN(v, cnt)
+		n += cnt
+
+		return nil
+	})
+
+	return n, err
+}
+
+// scanLines calls f for non-empty lines of r.
+func (c *Collector) scanLines(ctx context.Context, r io.Reader, f func(text string) error) error {
+	s := bufio.NewScanner(r)
+
+	if c.MaxLineLen > 0 {
+		size := c.MaxLineLen + 1 // Room for a trailing \r.
+		if size > bufio.MaxScanTokenSize {
+			size = bufio.MaxScanTokenSize
+		}
+
+		s.Buffer(make([]byte, 0, size), c.MaxLineLen+1)
+	}
+
+	line := 0
+
+	for s.Scan() {
+		line++
+
+		if line%ctxCheckLines == 0 {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+		}
+
+		text := s.Text()
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+
+		if err := f(text); err != nil {
+			return fmt.Errorf("line %d: %w", line, err)
+		}
+	}
+
+	if err := s.Err(); err != nil {
+		return fmt.Errorf("line %d: %w", line+1, err)
+	}
+
+	return ctx.Err()
+}
@@ -0,0 +1,124 @@
+package dynhist_test
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vearutop/dynhist-go"
+	"github.com/vearutop/dynhist-go/internal/dataset"
+)
+
+// endlessReader produces lines of values and cancels context after a number of lines.
+type endlessReader struct {
+	lines       int
+	cancelAfter int
+	cancel      func()
+}
+
+func (r *endlessReader) Read(p []byte) (int, error) {
+	r.lines++
+
+	if r.lines == r.cancelAfter {
+		r.cancel()
+	}
+
+	return copy(p, "1.5\n"), nil
+}
+
+func TestCollector_ReadLines(t *testing.T) {
+	c := dynhist.Collector{}
+
+	n, err := c.ReadLines(context.Background(), strings.NewReader("1\n 2 \n\n3.5\r\n"), nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, n)
+	assert.Equal(t, 6.5, c.Sum)
+
+	n, err = c.ReadLines(context.Background(), strings.NewReader("1\n2\nabc\n4\n"), nil)
+	assert.EqualError(t, err, `line 3: strconv.ParseFloat: parsing "abc": invalid syntax`)
+	assert.Equal(t, 2, n)
+}
+
+func TestCollector_ReadLines_cancel(t *testing.T) {
+	c := dynhist.Collector{}
+	ctx, cancel := context.WithCancel(context.Background())
+
+	n, err := c.ReadLines(ctx, &endlessReader{cancelAfter: 5000, cancel: cancel}, nil)
+	assert.True(t, errors.Is(err, context.Canceled))
+	assert.Less(t, n, 6000)
+	assert.Equal(t, n, c.Count)
+}
+
+func TestCollector_ReadLines_maxLineLen(t *testing.T) {
+	c := dynhist.Collector{MaxLineLen: 10}
+
+	n, err := c.ReadLines(context.Background(), strings.NewReader("1\n2\n"+strings.Repeat("1", 20)+"\n4\n"), nil)
+	assert.True(t, errors.Is(err, bufio.ErrTooLong))
+	assert.EqualError(t, err, "line 3: bufio.Scanner: token too long")
+	assert.Equal(t, 2, n)
+}
+
+func TestCollector_ReadLines_decimalComma(t *testing.T) {
+	c := dynhist.Collector{}
+
+	n, err := c.ReadLines(context.Background(), strings.NewReader("0,25\n1\n2,5\n"), dynhist.ParseFloatComma)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, n)
+	assert.Equal(t, 3.75, c.Sum)
+
+	// Strict parsing by default.
+	_, err = c.ReadLines(context.Background(), strings.NewReader("0.25\n0,5\n"), nil)
+	assert.EqualError(t, err, `line 2: strconv.ParseFloat: parsing "0,5": invalid syntax`)
+
+	// Mixed separators.
+	_, err = c.ReadLines(context.Background(), strings.NewReader("0,25\n0.5\n"), dynhist.ParseFloatComma)
+	assert.EqualError(t, err, `line 2: unexpected dot in comma-separated decimal: "0.5"`)
+}
+
+func TestCollector_ReadWeightedLines(t *testing.T) {
+	r := dataset.New(1)
+
+	var weighted, expanded strings.Builder
+
+	for i := 0; i < 1000; i++ {
+		v := r.ExpFloat64()
+		n := r.Intn(5)
+
+		fmt.Fprintf(&weighted, "%g\t%d\n", v, n)
+
+		for j := 0; j < n; j++ {
+			fmt.Fprintf(&expanded, "%g\n", v)
+		}
+	}
+
+	cw := dynhist.Collector{}
+	ce := dynhist.Collector{}
+
+	nw, err := cw.ReadWeightedLines(context.Background(), strings.NewReader(weighted.String()), nil)
+	require.NoError(t, err)
+
+	ne, err := ce.ReadLines(context.Background(), strings.NewReader(expanded.String()), nil)
+	require.NoError(t, err)
+
+	assert.Equal(t, ne, nw)
+	assert.Equal(t, ce.Count, cw.Count)
+	assert.InDelta(t, ce.Sum, cw.Sum, 1e-9)
+
+	for _, p := range []float64{10, 50, 90, 99, 100} {
+		assert.Equal(t, ce.Percentile(p), cw.Percentile(p), p)
+	}
+
+	_, err = cw.ReadWeightedLines(context.Background(), strings.NewReader("1 2\n3\n"), nil)
+	assert.EqualError(t, err, `line 2: expected value and count, 1 fields in "3"`)
+
+	_, err = cw.ReadWeightedLines(context.Background(), strings.NewReader("1 2\n3 x\n"), nil)
+	assert.EqualError(t, err, `line 2: strconv.Atoi: parsing "x": invalid syntax`)
+
+	_, err = cw.ReadWeightedLines(context.Background(), strings.NewReader("1 -2\n"), nil)
+	assert.EqualError(t, err, `line 1: negative count: -2`)
+}
@@ -0,0 +1,36 @@
+package dynhist
+
+import "math"
+
+// relativeFloor is a magnitude relative to the largest absolute value, below which
+// RelativeErrorWidth uses absolute width.
+const relativeFloor = 1e-9
+
+// RelativeErrorWidth creates a weight function that bounds relative error of percentiles.
+//
+// Weight of a pair is the width of merged bucket relative to its midpoint, so that buckets
+// approximate log-spaced layout without tuning to data range. Merges within maxRel (for example 0.05)
+// are preferred, merges beyond it are weighted by their share of count, so that sparse tails
+// lose resolution first when BucketsLimit is too small to meet maxRel everywhere.
+//
+// Midpoints near zero are replaced with a floor relative to the largest magnitude, so buckets
+// around zero and of negative values are weighted by absolute width.
+func RelativeErrorWidth(maxRel float64) func(b1, b2, bTot Bucket) float64 {
+	return func(b1, b2, bTot Bucket) float64 {
+		floor := relativeFloor * math.Max(math.Abs(bTot.Min), math.Abs(bTot.Max))
+		mid := math.Max(math.Abs(b1.Min+b2.Max)/2, floor)
+
+		if mid == 0 {
+			return 0
+		}
+
+		rel := (b2.Max - b1.Min) / mid
+		if rel <= maxRel || bTot.Count == 0 {
+			return rel
+		}
+
+		share := float64(b1.Count+b2.Count) / float64(bTot.Count)
+
+		return maxRel + (rel-maxRel)*share
+	}
+}
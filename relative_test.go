@@ -0,0 +1,79 @@
+package dynhist_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vearutop/dynhist-go"
+	"github.com/vearutop/dynhist-go/internal/dataset"
+)
+
+func maxRelError(limit int, wf func(b1, b2, bTot dynhist.Bucket) float64) float64 {
+	c := dynhist.Collector{BucketsLimit: limit, WeightFunc: wf, RawValues: []float64{}}
+	r := dataset.New(1)
+
+	// Log-uniform values over 6 decades.
+	for i := 0; i < 20000; i++ {
+		c.Add(1e-3 * math.Exp(r.Float64()*math.Log(1e6)))
+	}
+
+	maxErr := 0.0
+
+	for _, e := range c.AccuracyReport([]float64{1, 10, 50, 90, 99, 99.9}) {
+		if e.RelError > maxErr {
+			maxErr = e.RelError
+		}
+	}
+
+	return maxErr
+}
+
+func TestRelativeErrorWidth(t *testing.T) {
+	for _, tc := range []struct {
+		limit  int
+		maxErr float64
+	}{
+		{limit: 20, maxErr: 1},
+		{limit: 50, maxErr: 0.4},
+		{limit: 100, maxErr: 0.2},
+	} {
+		relErr := maxRelError(tc.limit, dynhist.RelativeErrorWidth(0.05))
+		expErr := maxRelError(tc.limit, dynhist.ExpWidth(1.2, 1))
+
+		assert.Less(t, relErr, tc.maxErr, tc.limit)
+		assert.Less(t, relErr, expErr, tc.limit)
+	}
+}
+
+func TestRelativeErrorWidth_zeroAndNegative(t *testing.T) {
+	c := dynhist.Collector{BucketsLimit: 30, WeightFunc: dynhist.RelativeErrorWidth(0.05), StrictWeights: true}
+	r := dataset.New(1)
+
+	for i := 0; i < 10000; i++ {
+		v := 1e-3 * math.Exp(r.Float64()*math.Log(1e6))
+
+		switch r.Intn(3) {
+		case 0:
+			c.Add(0)
+		case 1:
+			c.Add(-v)
+		default:
+			c.Add(v)
+		}
+	}
+
+	assert.Equal(t, 10000, c.Count)
+	assert.Len(t, c.Buckets, 30)
+
+	// Zeros keep a separate bucket.
+	zeros := 0
+
+	for _, b := range c.Buckets {
+		if b.Min == 0 && b.Max == 0 {
+			zeros = b.Count
+		}
+	}
+
+	assert.Greater(t, zeros, 3000)
+}
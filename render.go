@@ -0,0 +1,643 @@
+package dynhist
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// RenderOptions controls text rendering of collector.
+type RenderOptions struct {
+	// PrintSum enables printing of a summary value in a bucket.
+	PrintSum bool
+
+	// PrintGaps appends a footer with a share of value range not covered by buckets.
+	PrintGaps bool
+
+	// ValueFormatter formats bucket boundaries, "%.2f" is used by default.
+	ValueFormatter func(v float64) string
+
+	// DualUnits prints boundaries both as raw values and formatted with ValueFormatter.
+	//
+	// Raw values are printed in the shortest exact representation, for example
+	// [0.00012 0.00048] (120µs 480µs).
+	DualUnits bool
+
+	// DecimalSeparator replaces "." in rendered numbers, for example ",".
+	DecimalSeparator string
+
+	// Weighting defines what percent column and bars represent, ByCount is used by default.
+	Weighting Weighting
+
+	// PercentBase defines whether percent column and bars are relative to all values (default)
+	// or to displayed buckets, the base is stated in the footer when buckets are omitted.
+	PercentBase PercentBase
+
+	// Thresholds are labeled values to mark in rendered buckets, for example {"SLO": 0.25}.
+	//
+	// A threshold between buckets is rendered as a separator line, a threshold within a bucket
+	// is appended to its row. Thresholds outside of data range are rendered at the top or bottom.
+	Thresholds map[string]float64
+
+	// CountFormatter formats counts of buckets, decimal integer is used by default.
+	CountFormatter func(n int) string
+
+	// SumFormatter formats sums of buckets, "%.2f" is used by default.
+	//
+	// Columns are formatted independently, for example integer boundaries can be rendered
+	// with ValueFormatter while sums keep fractions.
+	SumFormatter func(v float64) string
+
+	// PercentFormatter formats shares of buckets in percents, "%.2f" is used by default.
+	//
+	// Percent sign is appended to the formatted value.
+	PercentFormatter func(v float64) string
+
+	// BarsOnLeft renders bars before boundaries, aligned to the right.
+	BarsOnLeft bool
+
+	// MaxWidth limits width of bucket rows in characters, 0 means unlimited.
+	//
+	// Bars are shortened to fit, sum column is omitted if rows do not fit without bars.
+	MaxWidth int
+
+	// MaxRows limits number of rendered buckets, 0 means unlimited.
+	//
+	// When exceeded, first and last buckets and buckets with highest counts are rendered,
+	// followed by a summary of omitted buckets. Values less than 2 are treated as 2.
+	MaxRows int
+
+	// FoldOutliers is a share (0-1) of values at either extreme to fold into a single open-ended row,
+	// 0 disables folding.
+	//
+	// Edge buckets with cumulative share of count below FoldOutliers are rendered as one row,
+	// for example "[> 512.00]", so that far outliers do not dominate widths of columns.
+	// Collected data is not changed.
+	FoldOutliers float64
+
+	// Emphasize returns a style of bucket row, for example to highlight a bucket of p99 with ANSI escape codes.
+	Emphasize func(row BarRow) Style
+
+	// Percentiles lists percentiles (0-100) to print in a footer, for example []float64{50, 99}.
+	Percentiles []float64
+
+	// StaleAfter annotates a percentile in the footer with the age of its newest value
+	// if its Freshness exceeds StaleAfter, 0 disables annotations. It requires TrackFreshness.
+	StaleAfter time.Duration
+
+	// HideStale omits stale percentiles from the footer instead of annotating them.
+	HideStale bool
+}
+
+// Style wraps text of a rendered row.
+//
+// Prefix and Suffix are not counted in MaxWidth, so they should be zero-width sequences
+// like ANSI escape codes.
+type Style struct {
+	Prefix string
+	Suffix string
+}
+
+// Weighting defines a basis for shares of buckets.
+type Weighting int
+
+// Weighting values.
+const (
+	// ByCount renders shares of count of values.
+	ByCount Weighting = iota
+
+	// BySum renders shares of sum of values, for example to show where bytes go in a histogram of sizes.
+	//
+	// Absolute values of bucket sums are used, so buckets with negative sums contribute by magnitude.
+	BySum
+)
+
+// weigh returns a non-negative weight of bucket.
+func (w Weighting) weigh(b Bucket) float64 {
+	if w == BySum {
+		return math.Abs(b.Sum)
+	}
+
+	return float64(b.Count)
+}
+
+// total returns a sum of weights of buckets.
+func (w Weighting) total(buckets []Bucket) float64 {
+	res := 0.0
+
+	for _, b := range buckets {
+		res += w.weigh(b)
+	}
+
+	return res
+}
+
+// PercentBase defines what percent column and bars are relative to.
+type PercentBase int
+
+// PercentBase values.
+const (
+	// PercentOfTotal renders shares of all values.
+	PercentOfTotal PercentBase = iota
+
+	// PercentOfDisplayed renders shares of values in displayed buckets, when some buckets are omitted by MaxRows.
+	PercentOfDisplayed
+)
+
+// DurationFormatter returns a ValueFormatter that renders values as time.Duration of unit.
+//
+// For example, DurationFormatter(time.Second) renders 0.00012 as 120µs.
+func DurationFormatter(unit time.Duration) func(v float64) string {
+	return func(v float64) string {
+		return time.Duration(v * float64(unit)).String()
+	}
+}
+
+// Render renders buckets with options.
+//
+// Collector state is copied under the lock and formatted after the lock is released,
+// so a panicking ValueFormatter or Transform does not leave collector locked.
+func (c *Collector) Render(opts RenderOptions) string {
+	if c == nil {
+		return ""
+	}
+
+	state := c.renderState(opts.Percentiles)
+	total, buckets, labels := state.total, state.buckets, state.labels
+	meta, tracked, sumEstimated, percentiles := state.meta, state.tracked, state.sumEstimated, state.percentiles
+
+	if len(buckets) == 0 {
+		return ""
+	}
+
+	rows, omitted, omittedPercent := barData(buckets, 0, opts)
+	rowLabels(rows, buckets, labels)
+	mainFormat, humanFormat := opts.boundsFormats()
+
+	bounds := boundsColumn{format: mainFormat, open: "[", close: "]"}
+	human := boundsColumn{format: humanFormat, open: " (", close: ")"}
+	fixed := opts.decimal(formatFixed)
+
+	mainValues := make([]string, 0, 2*len(rows))
+	humanValues := make([]string, 0, 2*len(rows))
+
+	for _, r := range rows {
+		mainValues = append(mainValues, r.MinText, r.MaxText)
+		humanValues = append(humanValues, r.HumanMin, r.HumanMax)
+	}
+
+	bounds.setValues(mainValues)
+	human.setValues(humanValues)
+
+	countText, percentText, sumText := strconv.Itoa, fixed, fixed
+	if opts.CountFormatter != nil {
+		countText = opts.CountFormatter
+	}
+
+	if opts.SumFormatter != nil {
+		sumText = opts.SumFormatter
+	}
+
+	if opts.PercentFormatter != nil {
+		percentText = opts.PercentFormatter
+	}
+
+	cLen := printfLen("%d", total.Count)
+	sLen := 0
+	pLen := 5
+
+	if opts.CountFormatter != nil {
+		cLen = 0
+	}
+
+	for _, r := range rows {
+		if l := utf8.RuneCountInString(percentText(r.Percent)); l > pLen {
+			pLen = l
+		}
+
+		if l := utf8.RuneCountInString(countText(r.Count)); opts.CountFormatter != nil && l > cLen {
+			cLen = l
+		}
+	}
+
+	printSum := opts.PrintSum
+	if printSum {
+		sLen = printfLen("%.2f", total.Sum)
+
+		if opts.SumFormatter != nil {
+			sLen = 0
+
+			for _, r := range rows {
+				if l := utf8.RuneCountInString(sumText(r.Sum)); l > sLen {
+					sLen = l
+				}
+			}
+		}
+	}
+
+	if opts.MaxWidth > 0 {
+		width := bounds.lineWidth() + human.lineWidth() + 1 + cLen + 1 + pLen + 1
+
+		if printSum && width+1+sLen > opts.MaxWidth {
+			printSum = false
+		}
+
+		if printSum {
+			width += 1 + sLen
+		}
+
+		// Bar needs a leading space.
+		if budget := opts.MaxWidth - width - 1; budget > 0 {
+			rows, _, _ = barData(buckets, budget, opts)
+			rowLabels(rows, buckets, labels)
+		} else {
+			for i := range rows {
+				rows[i].Bar = 0
+			}
+		}
+	}
+
+	var res strings.Builder
+
+	if meta != "" {
+		res.WriteString(meta)
+		res.WriteString("\n")
+	}
+
+	barWidth := 0
+
+	if opts.BarsOnLeft {
+		for _, r := range rows {
+			if r.Bar > barWidth {
+				barWidth = r.Bar
+			}
+		}
+
+		if barWidth > 0 {
+			res.WriteString(strings.Repeat(" ", barWidth+1))
+		}
+	}
+
+	bounds.header(&res)
+	human.header(&res)
+	if opts.Weighting == BySum {
+		fmt.Fprintf(&res, " %*s %*s", cLen, "cnt", pLen+1, "sum%")
+	} else {
+		fmt.Fprintf(&res, " %*s %*s", cLen, "cnt", pLen+1, "total%")
+	}
+
+	if printSum {
+		fmt.Fprintf(&res, " %*s", sLen, "sum")
+	}
+
+	if opts.Weighting == BySum {
+		fmt.Fprintf(&res, " (%d events, sum %s)\n", total.Count, sumText(total.Sum))
+	} else {
+		fmt.Fprintf(&res, " (%d events)\n", total.Count)
+	}
+
+	marks := newThresholdMarks(opts.Thresholds, opts.decimal(formatRaw))
+	if opts.ValueFormatter != nil {
+		marks.format = opts.ValueFormatter
+	}
+
+	marks.below(&res, rows[0].Min, ", below data range")
+
+	for i, r := range rows {
+		marks.below(&res, r.Min, "")
+
+		style := Style{}
+		if opts.Emphasize != nil {
+			style = opts.Emphasize(r)
+		}
+
+		res.WriteString(style.Prefix)
+
+		if barWidth > 0 {
+			res.WriteString(padLeft(strings.Repeat(".", r.Bar), barWidth))
+			res.WriteString(" ")
+		}
+
+		bounds.row(&res, i)
+		human.row(&res, i)
+		fmt.Fprintf(&res, " %s %s%%", padLeft(countText(r.Count), cLen), padLeft(percentText(r.Percent), pLen))
+
+		if printSum {
+			fmt.Fprintf(&res, " %*s", sLen, sumText(r.Sum))
+		}
+
+		if r.Bar > 0 && !opts.BarsOnLeft {
+			fmt.Fprint(&res, " ", strings.Repeat(".", r.Bar))
+		}
+
+		res.WriteString(style.Suffix)
+
+		if r.Label != "" {
+			res.WriteString(" # ")
+			res.WriteString(r.Label)
+		}
+
+		marks.within(&res, r.Max)
+		fmt.Fprintln(&res)
+	}
+
+	marks.below(&res, math.Inf(1), ", above data range")
+
+	if omitted > 0 {
+		pct := opts.decimal(formatPercent)
+
+		fmt.Fprintf(&res, "… %d buckets omitted (%s%% of values), ", omitted, pct(omittedPercent))
+
+		if opts.PercentBase == PercentOfDisplayed {
+			fmt.Fprintf(&res, "percents of displayed %s%% of values\n", pct(100-omittedPercent))
+		} else {
+			res.WriteString("percents of all values\n")
+		}
+	}
+
+	if sumEstimated && (opts.PrintSum || opts.Weighting == BySum) {
+		res.WriteString("sums estimated from bucket bounds\n")
+	}
+
+	if opts.PrintGaps {
+		fmt.Fprintf(&res, "gaps: %s%% of value range\n", opts.decimal(formatPercent)(100*gapFraction(buckets)))
+	}
+
+	for _, t := range tracked {
+		fmt.Fprintf(&res, "> %s (%s): %d (%s%%)\n", t.name, opts.decimal(formatRaw)(t.value), t.count,
+			fixed(share(t.count, total.Count)))
+	}
+
+	value := fixed
+	if opts.ValueFormatter != nil {
+		value = opts.ValueFormatter
+	}
+
+	for _, p := range percentiles {
+		stale := opts.StaleAfter > 0 && p.age > opts.StaleAfter
+
+		if stale && opts.HideStale {
+			continue
+		}
+
+		fmt.Fprintf(&res, "p%s: %s", opts.decimal(formatRaw)(p.percent), value(p.value))
+
+		if stale {
+			fmt.Fprintf(&res, " (stale, last value %s ago)", p.age.Round(time.Second))
+		}
+
+		fmt.Fprintln(&res)
+	}
+
+	return res.String()
+}
+
+type threshold struct {
+	name  string
+	value float64
+}
+
+// thresholdMarks renders sorted thresholds as rows are rendered.
+type thresholdMarks struct {
+	items  []threshold
+	format func(v float64) string
+	next   int
+}
+
+func newThresholdMarks(thresholds map[string]float64, format func(v float64) string) *thresholdMarks {
+	tm := &thresholdMarks{format: format}
+
+	for name, v := range thresholds {
+		tm.items = append(tm.items, threshold{name: name, value: v})
+	}
+
+	sort.Slice(tm.items, func(i, j int) bool {
+		if tm.items[i].value == tm.items[j].value {
+			return tm.items[i].name < tm.items[j].name
+		}
+
+		return tm.items[i].value < tm.items[j].value
+	})
+
+	return tm
+}
+
+// below renders separator lines for pending thresholds less than v.
+func (tm *thresholdMarks) below(w *strings.Builder, v float64, note string) {
+	for ; tm.next < len(tm.items) && tm.items[tm.next].value < v; tm.next++ {
+		t := tm.items[tm.next]
+		fmt.Fprintf(w, "---- %s (%s)%s ----\n", t.name, tm.format(t.value), note)
+	}
+}
+
+// within appends pending thresholds not greater than max to the current row.
+func (tm *thresholdMarks) within(w *strings.Builder, max float64) {
+	for sep := " <- "; tm.next < len(tm.items) && tm.items[tm.next].value <= max; tm.next++ {
+		t := tm.items[tm.next]
+		fmt.Fprintf(w, "%s%s (%s)", sep, t.name, tm.format(t.value))
+		sep = ", "
+	}
+}
+
+// topRows keeps first and last buckets and buckets with highest counts within maxRows,
+// it returns kept and omitted buckets in original order.
+func topRows(buckets []Bucket, maxRows int) (rows, omitted []Bucket) {
+	if maxRows <= 0 || len(buckets) <= maxRows {
+		return buckets, nil
+	}
+
+	if maxRows < 2 {
+		maxRows = 2
+	}
+
+	idx := make([]int, 0, len(buckets)-2)
+	for i := 1; i < len(buckets)-1; i++ {
+		idx = append(idx, i)
+	}
+
+	sort.SliceStable(idx, func(i, j int) bool {
+		return buckets[idx[i]].Count > buckets[idx[j]].Count
+	})
+
+	keep := make([]bool, len(buckets))
+	keep[0] = true
+	keep[len(buckets)-1] = true
+
+	for _, i := range idx[:maxRows-2] {
+		keep[i] = true
+	}
+
+	rows = make([]Bucket, 0, maxRows)
+
+	for i, b := range buckets {
+		if keep[i] {
+			rows = append(rows, b)
+		} else {
+			omitted = append(omitted, b)
+		}
+	}
+
+	return rows, omitted
+}
+
+// renderState is collector state copied for Render.
+type renderState struct {
+	total        Bucket
+	buckets      []Bucket
+	labels       []string
+	meta         string
+	tracked      []trackedThreshold
+	sumEstimated bool
+	percentiles  []percentileLine
+}
+
+// renderState copies collector state in original units, Transform is called under the lock.
+func (c *Collector) renderState(percents []float64) renderState {
+	c.Lock()
+	defer c.Unlock()
+
+	s := renderState{
+		total:        c.Bucket,
+		buckets:      append([]Bucket(nil), c.Buckets...),
+		labels:       append([]string(nil), c.labels...),
+		meta:         c.metaHeader(),
+		tracked:      append([]trackedThreshold(nil), c.thresholds...),
+		sumEstimated: c.SumEstimated,
+	}
+
+	c.Transform.outward(&s.total, s.buckets)
+	s.percentiles = c.percentileLines(percents)
+
+	return s
+}
+
+// metaHeader renders Name, Unit and Help, it returns empty string if Name is not set.
+func (c *Collector) metaHeader() string {
+	if c.Name == "" {
+		return ""
+	}
+
+	h := c.Name
+
+	if c.Unit != "" {
+		h += " (" + c.Unit + ")"
+	}
+
+	if c.Help != "" {
+		h += ": " + c.Help
+	}
+
+	return h
+}
+
+// decimal wraps number formatter to use DecimalSeparator.
+func (opts RenderOptions) decimal(format func(v float64) string) func(v float64) string {
+	if opts.DecimalSeparator == "" || opts.DecimalSeparator == "." {
+		return format
+	}
+
+	return func(v float64) string {
+		return strings.Replace(format(v), ".", opts.DecimalSeparator, 1)
+	}
+}
+
+// share returns percentage of count in total without integer overflow.
+func share(count, total int) float64 {
+	return 100 * float64(count) / float64(total)
+}
+
+func formatFixed(v float64) string {
+	return strconv.FormatFloat(v, 'f', 2, 64)
+}
+
+func formatPercent(v float64) string {
+	return strconv.FormatFloat(v, 'f', 1, 64)
+}
+
+func formatRaw(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// boundsColumn renders boundaries of buckets, with an optional secondary formatting in parentheses.
+type boundsColumn struct {
+	format func(v float64) string
+	values []string // Pairs of min and max.
+	width  int
+	open   string
+	close  string
+}
+
+func (bc *boundsColumn) prepare(buckets []Bucket) {
+	if bc.format == nil {
+		return
+	}
+
+	values := make([]string, 0, 2*len(buckets))
+
+	for _, b := range buckets {
+		values = append(values, bc.format(b.Min), bc.format(b.Max))
+	}
+
+	bc.setValues(values)
+}
+
+// setValues sets formatted pairs of min and max.
+func (bc *boundsColumn) setValues(values []string) {
+	if bc.format == nil {
+		return
+	}
+
+	bc.values = values
+	bc.width = 0
+
+	for _, v := range bc.values {
+		if l := utf8.RuneCountInString(v); l > bc.width {
+			bc.width = l
+		}
+	}
+}
+
+// lineWidth returns width of column in characters.
+func (bc *boundsColumn) lineWidth() int {
+	if bc.format == nil {
+		return 0
+	}
+
+	return utf8.RuneCountInString(bc.open) + 2*bc.width + 1 + utf8.RuneCountInString(bc.close)
+}
+
+func (bc *boundsColumn) header(w *strings.Builder) {
+	bc.write(w, "min", "max")
+}
+
+func (bc *boundsColumn) row(w *strings.Builder, i int) {
+	if bc.format == nil {
+		return
+	}
+
+	bc.write(w, bc.values[2*i], bc.values[2*i+1])
+}
+
+func (bc *boundsColumn) write(w *strings.Builder, min, max string) {
+	if bc.format == nil {
+		return
+	}
+
+	w.WriteString(bc.open)
+	w.WriteString(padLeft(min, bc.width))
+	w.WriteString(" ")
+	w.WriteString(padLeft(max, bc.width))
+	w.WriteString(bc.close)
+}
+
+func padLeft(s string, width int) string {
+	if l := utf8.RuneCountInString(s); l < width {
+		return strings.Repeat(" ", width-l) + s
+	}
+
+	return s
+}
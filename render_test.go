@@ -0,0 +1,357 @@
+package dynhist_test
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vearutop/dynhist-go"
+	"github.com/vearutop/dynhist-go/internal/dataset"
+	"github.com/vearutop/dynhist-go/internal/golden"
+)
+
+func TestCollector_Render_dualUnits(t *testing.T) {
+	c := dynhist.Collector{BucketsLimit: 3}
+
+	for _, v := range []float64{0.00012, 0.00048, 0.0015, 0.002, 0.25} {
+		c.Add(v)
+	}
+
+	assert.Equal(t, `[  min   max] cnt total% (5 events)
+[120µs 480µs] 2 40.00% ........................................
+[1.5ms   2ms] 2 40.00% ........................................
+[250ms 250ms] 1 20.00% ....................
+`, c.Render(dynhist.RenderOptions{ValueFormatter: dynhist.DurationFormatter(time.Second)}))
+
+	assert.Equal(t, `[    min     max] (  min   max) cnt total%  sum (5 events)
+[0.00012 0.00048] (120µs 480µs) 2 40.00% 0.00 ........................................
+[ 0.0015   0.002] (1.5ms   2ms) 2 40.00% 0.00 ........................................
+[   0.25    0.25] (250ms 250ms) 1 20.00% 0.25 ....................
+`, c.Render(dynhist.RenderOptions{
+		ValueFormatter: dynhist.DurationFormatter(time.Second),
+		DualUnits:      true,
+		PrintSum:       true,
+	}))
+
+	assert.Equal(t, c.String(), c.Render(dynhist.RenderOptions{DualUnits: true}),
+		"DualUnits has no effect without ValueFormatter")
+}
+
+func TestCollector_Render_hugeCounts(t *testing.T) {
+	c := dynhist.Collector{}
+	c.Add(1)
+	c.Add(2)
+
+	c.Buckets[0].Count = 4e18
+	c.Buckets[1].Count = 1e18
+	c.Count = 5e18
+
+	assert.Equal(t, `[ min  max]                 cnt total% (5000000000000000000 events)
+[1.00 1.00] 4000000000000000000 80.00% ................................................................................
+[2.00 2.00] 1000000000000000000 20.00% ....................
+`, c.String())
+}
+
+func TestCollector_Render_fullShare(t *testing.T) {
+	c := dynhist.Collector{}
+	c.Add(1)
+	c.Add(1)
+
+	assert.Equal(t, `[ min  max] cnt  total% (2 events)
+[1.00 1.00] 2 100.00% ....................................................................................................
+`, c.String())
+}
+
+func TestCollector_Render_decimalSeparator(t *testing.T) {
+	c := dynhist.Collector{BucketsLimit: 2}
+
+	for _, v := range []float64{0.25, 0.5, 1.75} {
+		c.Add(v)
+	}
+
+	assert.Equal(t, `[ min  max] cnt total%  sum (3 events)
+[0,25 0,50] 2 66,67% 0,75 ..................................................................
+[1,75 1,75] 1 33,33% 1,75 .................................
+`, c.Render(dynhist.RenderOptions{DecimalSeparator: ",", PrintSum: true}))
+}
+
+func TestCollector_Render_panickingFormatter(t *testing.T) {
+	c := dynhist.Collector{}
+	c.Add(1)
+
+	assert.Panics(t, func() {
+		c.Render(dynhist.RenderOptions{ValueFormatter: func(v float64) string {
+			panic("failed")
+		}})
+	})
+
+	done := make(chan struct{})
+
+	go func() {
+		c.Add(2)
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("collector is left locked")
+	}
+
+	assert.Equal(t, 2, c.Count)
+}
+
+func TestCollector_Render_maxRows(t *testing.T) {
+	c := dynhist.Collector{BucketsLimit: 10}
+
+	for i := 0; i < 10; i++ {
+		for j := 0; j <= i%5; j++ {
+			c.Add(float64(i))
+		}
+	}
+
+	require.Len(t, c.Buckets, 10)
+
+	assert.Equal(t, `[ min  max] cnt total% (30 events)
+[0.00 0.00]  1  3.33% ...
+[3.00 3.00]  4 13.33% .............
+[4.00 4.00]  5 16.67% ................
+[9.00 9.00]  5 16.67% ................
+… 6 buckets omitted (50.0% of values), percents of all values
+`, c.Render(dynhist.RenderOptions{MaxRows: 4}))
+
+	// First and last buckets are always rendered.
+	assert.Equal(t, `[ min  max] cnt total% (30 events)
+[0.00 0.00]  1  3.33% ...
+[9.00 9.00]  5 16.67% ................
+… 8 buckets omitted (80.0% of values), percents of all values
+`, c.Render(dynhist.RenderOptions{MaxRows: 1}))
+
+	assert.Equal(t, c.String(), c.Render(dynhist.RenderOptions{MaxRows: 10}))
+
+	assert.Equal(t, `[ min  max] cnt total% (30 events)
+[0.00 0.00]  1  6.67% ......
+[3.00 3.00]  4 26.67% ..........................
+[4.00 4.00]  5 33.33% .................................
+[9.00 9.00]  5 33.33% .................................
+… 6 buckets omitted (50.0% of values), percents of displayed 50.0% of values
+`, c.Render(dynhist.RenderOptions{MaxRows: 4, PercentBase: dynhist.PercentOfDisplayed}))
+
+	// Without omitted buckets bases are equal.
+	assert.Equal(t, c.String(), c.Render(dynhist.RenderOptions{PercentBase: dynhist.PercentOfDisplayed}))
+}
+
+func TestCollector_Render_bySumNegative(t *testing.T) {
+	c := dynhist.Collector{BucketsLimit: 2}
+
+	for _, v := range []float64{-3, -1, 2, 2} {
+		c.Add(v)
+	}
+
+	// Absolute sums are used for shares.
+	assert.Equal(t, `[  min   max] cnt   sum% (4 events, sum 0.00)
+[-3.00 -1.00] 2 50.00% ..................................................
+[ 2.00  2.00] 2 50.00% ..................................................
+`, c.Render(dynhist.RenderOptions{Weighting: dynhist.BySum}))
+}
+
+func TestCollector_Render_thresholds(t *testing.T) {
+	c := dynhist.Collector{BucketsLimit: 4}
+
+	for _, v := range []float64{0.01, 0.02, 0.05, 0.1, 0.12, 0.3, 0.35, 0.9, 1.5} {
+		c.Add(v)
+	}
+
+	golden.Assert(t, "thresholds", c.Render(dynhist.RenderOptions{Thresholds: map[string]float64{
+		"fast":    0.001,
+		"SLO":     0.25,
+		"p-inner": 0.11,
+		"edge":    0.35,
+		"timeout": 1.0,
+		"limit":   5,
+	}}))
+}
+
+func TestCollector_Render_maxWidth(t *testing.T) {
+	c := dynhist.Collector{BucketsLimit: 3, PrintSum: true}
+
+	for i := 0; i < 10; i++ {
+		c.Add(float64(i))
+	}
+
+	opts := dynhist.RenderOptions{PrintSum: true}
+
+	for _, tc := range []struct {
+		width    int
+		expected string
+	}{
+		{width: 0, expected: `[ min  max] cnt total%   sum (10 events)
+[0.00 3.00]  4 40.00%  6.00 ........................................
+[4.00 6.00]  3 30.00% 15.00 ..............................
+[7.00 9.00]  3 30.00% 24.00 ..............................
+`},
+		{width: 50, expected: `[ min  max] cnt total%   sum (10 events)
+[0.00 3.00]  4 40.00%  6.00 ........
+[4.00 6.00]  3 30.00% 15.00 ......
+[7.00 9.00]  3 30.00% 24.00 ......
+`},
+		{width: 30, expected: `[ min  max] cnt total%   sum (10 events)
+[0.00 3.00]  4 40.00%  6.00
+[4.00 6.00]  3 30.00% 15.00
+[7.00 9.00]  3 30.00% 24.00
+`},
+		{width: 25, expected: `[ min  max] cnt total% (10 events)
+[0.00 3.00]  4 40.00% .
+[4.00 6.00]  3 30.00%
+[7.00 9.00]  3 30.00%
+`},
+	} {
+		opts.MaxWidth = tc.width
+		out := c.Render(opts)
+		assert.Equal(t, tc.expected, out, tc.width)
+
+		if tc.width == 0 {
+			continue
+		}
+
+		for _, line := range strings.Split(out, "\n")[1:] {
+			assert.LessOrEqual(t, len(line), tc.width)
+		}
+	}
+}
+
+func TestCollector_Render_formatters(t *testing.T) {
+	c := dynhist.Collector{BucketsLimit: 4}
+
+	for i := 0; i < 3000000; i++ {
+		c.Add(float64(i % 1000))
+	}
+
+	c.Add(5000)
+
+	golden.Assert(t, "formatters", c.Render(dynhist.RenderOptions{
+		CountFormatter: func(n int) string {
+			switch {
+			case n >= 1e6:
+				return strconv.FormatFloat(float64(n)/1e6, 'f', 1, 64) + "M"
+			case n >= 1e3:
+				return strconv.FormatFloat(float64(n)/1e3, 'f', 1, 64) + "k"
+			default:
+				return strconv.Itoa(n)
+			}
+		},
+		PercentFormatter: func(v float64) string {
+			return strconv.FormatFloat(v, 'f', 0, 64)
+		},
+	}))
+}
+
+func TestCollector_Render_sumFormatter(t *testing.T) {
+	c := dynhist.Collector{}
+
+	// Integer boundaries with fractional sums, for example of scaled or estimated data.
+	require.NoError(t, c.LoadBuckets([]dynhist.Bucket{
+		{Min: 1, Max: 2, Count: 3, Sum: 4.5},
+		{Min: 5, Max: 5, Count: 1, Sum: 5},
+		{Min: 8, Max: 9, Count: 2, Sum: 16.75},
+	}))
+
+	integer := func(v float64) string {
+		return strconv.FormatFloat(v, 'f', 0, 64)
+	}
+
+	// Boundaries and sums are formatted independently.
+	assert.Equal(t, `[min max] cnt total%   sum (6 events)
+[1 2] 3 50.00%   4.5 ..................................................
+[5 5] 1 16.67%     5 ................
+[8 9] 2 33.33% 16.75 .................................
+`, c.Render(dynhist.RenderOptions{
+		PrintSum:       true,
+		ValueFormatter: integer,
+		SumFormatter: func(v float64) string {
+			return strconv.FormatFloat(v, 'g', -1, 64)
+		},
+	}))
+
+	assert.Equal(t, `[ min  max] cnt   sum% (6 events, sum 26)
+[1.00 2.00] 3 17.14% .................
+[5.00 5.00] 1 19.05% ...................
+[8.00 9.00] 2 63.81% ...............................................................
+`, c.Render(dynhist.RenderOptions{Weighting: dynhist.BySum, SumFormatter: integer}))
+}
+
+func TestCollector_Render_barsOnLeft(t *testing.T) {
+	c := dynhist.Collector{BucketsLimit: 4}
+
+	for i := 0; i < 100; i++ {
+		c.Add(float64(i % 7))
+	}
+
+	golden.Assert(t, "bars_on_left", c.Render(dynhist.RenderOptions{BarsOnLeft: true, PrintSum: true}))
+}
+
+func TestCollector_Render_emphasize(t *testing.T) {
+	c := dynhist.Collector{BucketsLimit: 4}
+
+	for i := 0; i < 100; i++ {
+		c.Add(float64(i % 7))
+	}
+
+	opts := dynhist.RenderOptions{Thresholds: map[string]float64{"SLO": 5}}
+	plain := c.Render(opts)
+
+	opts.Emphasize = func(row dynhist.BarRow) dynhist.Style {
+		if row.Count < 20 {
+			return dynhist.Style{}
+		}
+
+		return dynhist.Style{Prefix: "\x1b[1m", Suffix: "\x1b[0m"}
+	}
+
+	out := c.Render(opts)
+
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	require.Len(t, lines, 5)
+
+	assert.Equal(t, "\x1b[1m[4.00 5.00]  28 28.00% ............................\x1b[0m <- SLO (5)", lines[3])
+	assert.Equal(t, "[6.00 6.00]  14 14.00% ..............", lines[4])
+	assert.Equal(t, plain, strings.NewReplacer("\x1b[1m", "", "\x1b[0m", "").Replace(out))
+}
+
+func TestCollector_Render_foldOutliers(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		lower   []float64
+		upper   []float64
+		options dynhist.RenderOptions
+	}{
+		{name: "fold_outliers_one", lower: []float64{-1e9}, upper: []float64{1e9}},
+		{name: "fold_outliers_several", lower: []float64{-1e9, -1e8, -1e7}, upper: []float64{1e7, 1e8, 1e9}},
+	} {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			c := dynhist.Collector{BucketsLimit: 12}
+			r := dataset.New(1)
+
+			for i := 0; i < 3000; i++ {
+				c.Add(100 + 10*r.NormFloat64())
+			}
+
+			for _, v := range append(tc.lower, tc.upper...) {
+				c.Add(v)
+			}
+
+			p99 := c.Percentile(99)
+
+			golden.Assert(t, tc.name, c.Render(dynhist.RenderOptions{FoldOutliers: 0.002, PrintSum: true}))
+
+			// Data is not changed.
+			assert.Equal(t, p99, c.Percentile(99))
+			assert.Equal(t, 3000+len(tc.lower)+len(tc.upper), c.Count)
+		})
+	}
+}
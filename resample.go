@@ -0,0 +1,99 @@
+package dynhist
+
+import (
+	"math"
+	"sort"
+)
+
+// Resample distributes counts and sums of buckets across ranges defined by sorted bounds.
+//
+// Resulting buckets have boundaries [bounds[i], bounds[i+1]], so n bounds produce n-1 buckets.
+// Each source bucket is split proportionally to the overlap of its range with target ranges,
+// zero-width source buckets are point masses that go to a zero-width target at the same value if it exists,
+// or to the half-open [Min, Max) target containing the value otherwise (last target is closed).
+// Counts are rounded so that a source bucket fully covered by bounds is conserved exactly,
+// parts of data outside of bounds are dropped.
+//
+// Bounds and resulting buckets are in original units if Transform is set.
+// Nil is returned if there are less than two bounds or bounds are not sorted.
+func (c *Collector) Resample(bounds []float64) []Bucket {
+	if c == nil {
+		return nil
+	}
+
+	_, buckets := c.snapshotBuckets()
+
+	return resample(buckets, bounds)
+}
+
+func resample(buckets []Bucket, bounds []float64) []Bucket {
+	if len(bounds) < 2 || !sort.Float64sAreSorted(bounds) {
+		return nil
+	}
+
+	res := make([]Bucket, len(bounds)-1)
+	for i := range res {
+		res[i].Min = bounds[i]
+		res[i].Max = bounds[i+1]
+	}
+
+	for _, b := range buckets {
+		if b.Count == 0 && b.Sum == 0 {
+			continue
+		}
+
+		if b.Min == b.Max {
+			if i := pointTarget(res, b.Min); i >= 0 {
+				res[i].Count += b.Count
+				res[i].Sum += b.Sum
+			}
+
+			continue
+		}
+
+		width := b.Max - b.Min
+		cumFrac := 0.0
+		cumCount := 0
+
+		// First target that ends after the beginning of source bucket.
+		start := sort.Search(len(res), func(i int) bool { return res[i].Max > b.Min })
+
+		for i := start; i < len(res) && res[i].Min < b.Max; i++ {
+			overlap := math.Min(b.Max, res[i].Max) - math.Max(b.Min, res[i].Min)
+			if overlap <= 0 {
+				continue
+			}
+
+			frac := overlap / width
+			cumFrac += frac
+
+			cnt := int(math.Round(float64(b.Count)*cumFrac)) - cumCount
+			cumCount += cnt
+
+			res[i].Count += cnt
+			res[i].Sum += b.Sum * frac
+		}
+	}
+
+	return res
+}
+
+// pointTarget finds index of range that receives a point mass at v, or -1.
+func pointTarget(ranges []Bucket, v float64) int {
+	i := sort.Search(len(ranges), func(i int) bool { return ranges[i].Max >= v })
+
+	// Prefer exact zero-width range.
+	for j := i; j < len(ranges) && ranges[j].Min <= v; j++ {
+		if ranges[j].Min == v && ranges[j].Max == v {
+			return j
+		}
+	}
+
+	for j := i; j < len(ranges) && ranges[j].Min <= v; j++ {
+		if v < ranges[j].Max || j == len(ranges)-1 {
+			return j
+		}
+	}
+
+	return -1
+}
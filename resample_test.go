@@ -0,0 +1,96 @@
+package dynhist_test
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vearutop/dynhist-go"
+	"github.com/vearutop/dynhist-go/internal/dataset"
+)
+
+func randomCollector(r *dataset.Source) *dynhist.Collector {
+	c := &dynhist.Collector{BucketsLimit: 2 + r.Intn(30)}
+	n := 1 + r.Intn(2000)
+
+	for i := 0; i < n; i++ {
+		switch r.Intn(3) {
+		case 0:
+			c.Add(float64(r.Intn(10))) // Point masses.
+		case 1:
+			c.Add(r.ExpFloat64() * 100)
+		default:
+			c.Add(r.NormFloat64()*10 + 50)
+		}
+	}
+
+	return c
+}
+
+func TestCollector_Resample_conservation(t *testing.T) {
+	r := dataset.New(1)
+
+	for k := 0; k < 200; k++ {
+		c := randomCollector(r)
+
+		bounds := []float64{c.Min, c.Max}
+		for i := r.Intn(50); i > 0; i-- {
+			bounds = append(bounds, c.Min+r.Float64()*(c.Max-c.Min))
+		}
+
+		sort.Float64s(bounds)
+
+		res := c.Resample(bounds)
+		require.Len(t, res, len(bounds)-1)
+
+		cnt, sum := 0, 0.0
+
+		for i, b := range res {
+			assert.Equal(t, bounds[i], b.Min)
+			assert.Equal(t, bounds[i+1], b.Max)
+			assert.GreaterOrEqual(t, b.Count, 0)
+
+			cnt += b.Count
+			sum += b.Sum
+		}
+
+		assert.Equal(t, c.Count, cnt)
+		assert.InDelta(t, c.Sum, sum, 1e-6*(1+c.Sum))
+	}
+}
+
+func TestCollector_Resample_identity(t *testing.T) {
+	r := dataset.New(1)
+
+	for k := 0; k < 200; k++ {
+		c := randomCollector(r)
+
+		bounds := make([]float64, 0, 2*len(c.Buckets))
+		for _, b := range c.Buckets {
+			bounds = append(bounds, b.Min, b.Max)
+		}
+
+		res := c.Resample(bounds)
+
+		// Every other range is a gap between buckets.
+		for i, b := range c.Buckets {
+			assert.Equal(t, b.Min, res[2*i].Min)
+			assert.Equal(t, b.Max, res[2*i].Max)
+			assert.Equal(t, b.Count, res[2*i].Count)
+			assert.InDelta(t, b.Sum, res[2*i].Sum, 1e-9*(1+b.Sum))
+
+			if 2*i+1 < len(res) {
+				assert.Equal(t, 0, res[2*i+1].Count)
+			}
+		}
+	}
+}
+
+func TestCollector_Resample_invalid(t *testing.T) {
+	c := dynhist.Collector{}
+	c.Add(1)
+
+	assert.Nil(t, c.Resample([]float64{1}))
+	assert.Nil(t, c.Resample([]float64{2, 1}))
+}
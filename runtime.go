@@ -0,0 +1,92 @@
+package dynhist
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime/metrics"
+	"time"
+)
+
+var (
+	// ErrUnsupportedMetric is returned for unknown runtime metrics or metrics that are not histograms.
+	ErrUnsupportedMetric = errors.New("unsupported runtime metric")
+
+	// ErrInvalidHistogram is returned by LoadFromRuntimeMetrics for a histogram of invalid shape.
+	ErrInvalidHistogram = errors.New("invalid runtime histogram")
+)
+
+// CollectRuntimeMetric reads histogram runtime metric, for example "/sched/latencies:seconds".
+func CollectRuntimeMetric(name string) (*Collector, error) {
+	h, err := readHistogram(name)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Collector{}
+	if err := c.LoadFromRuntimeMetrics(h); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// WatchRuntimeMetric periodically reads histogram runtime metric and calls fn with a collector
+// of values observed since the previous read.
+//
+// It returns ctx.Err() when ctx is done, or an error if metric is not supported or invalid.
+func WatchRuntimeMetric(ctx context.Context, name string, interval time.Duration, fn func(c *Collector)) error {
+	prev, err := readHistogram(name)
+	if err != nil {
+		return err
+	}
+
+	tick, stop := newTicker(interval)
+	defer stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-tick:
+			h, err := readHistogram(name)
+			if err != nil {
+				return err
+			}
+
+			delta := &metrics.Float64Histogram{
+				Buckets: h.Buckets,
+				Counts:  make([]uint64, len(h.Counts)),
+			}
+
+			for i, cnt := range h.Counts {
+				if i < len(prev.Counts) {
+					cnt -= prev.Counts[i]
+				}
+
+				delta.Counts[i] = cnt
+			}
+
+			prev = h
+
+			c := &Collector{}
+			if err := c.LoadFromRuntimeMetrics(delta); err != nil {
+				return err
+			}
+
+			fn(c)
+		}
+	}
+}
+
+func readHistogram(name string) (*metrics.Float64Histogram, error) {
+	s := []metrics.Sample{{Name: name}}
+
+	metrics.Read(s)
+
+	if s[0].Value.Kind() != metrics.KindFloat64Histogram {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedMetric, name)
+	}
+
+	return s[0].Value.Float64Histogram(), nil
+}
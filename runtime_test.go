@@ -0,0 +1,274 @@
+package dynhist_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"runtime"
+	"runtime/metrics"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vearutop/dynhist-go"
+	"github.com/vearutop/dynhist-go/internal/dataset"
+)
+
+func ExampleWatchRuntimeMetric() {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := dynhist.WatchRuntimeMetric(ctx, "/sched/latencies:seconds", time.Second, func(c *dynhist.Collector) {
+		fmt.Printf("scheduler latency p99: %.6fs\n", c.Percentile(99))
+	})
+	if err != nil && !errors.Is(err, context.DeadlineExceeded) {
+		fmt.Println(err)
+	}
+}
+
+func TestCollectRuntimeMetric(t *testing.T) {
+	c, err := dynhist.CollectRuntimeMetric("/gc/heap/allocs-by-size:bytes")
+	require.NoError(t, err)
+	assert.Greater(t, c.Count, 0)
+
+	_, err = dynhist.CollectRuntimeMetric("/unknown:seconds")
+	assert.True(t, errors.Is(err, dynhist.ErrUnsupportedMetric))
+
+	_, err = dynhist.CollectRuntimeMetric("/gc/cycles/total:gc-cycles")
+	assert.True(t, errors.Is(err, dynhist.ErrUnsupportedMetric))
+}
+
+func TestWatchRuntimeMetric(t *testing.T) {
+	const name = "/gc/heap/allocs-by-size:bytes"
+
+	tick := make(chan time.Time)
+	started := make(chan struct{})
+
+	defer dynhist.SetTicker(func(d time.Duration) (<-chan time.Time, func()) {
+		close(started) // Ticker is created after the initial read.
+
+		return tick, func() {}
+	})()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	collected := make(chan *dynhist.Collector, 1)
+	done := make(chan error)
+
+	go func() {
+		done <- dynhist.WatchRuntimeMetric(ctx, name, time.Second, func(c *dynhist.Collector) {
+			collected <- c
+		})
+	}()
+
+	<-started
+
+	total, err := dynhist.CollectRuntimeMetric(name)
+	require.NoError(t, err)
+
+	// Allocations between reads.
+	var keep [][]byte
+	for i := 0; i < 100; i++ {
+		keep = append(keep, make([]byte, 350000))
+	}
+
+	runtime.KeepAlive(keep)
+
+	tick <- time.Now()
+	c := <-collected
+
+	cancel()
+	assert.True(t, errors.Is(<-done, context.Canceled))
+
+	assert.GreaterOrEqual(t, c.Count, 100)
+	assert.Less(t, c.Count, total.Count, "delta is expected to be smaller than total")
+	assert.Greater(t, c.Percentile(99.9), 300000.0)
+
+	err = dynhist.WatchRuntimeMetric(context.Background(), "/unknown:seconds", time.Second, nil)
+	assert.True(t, errors.Is(err, dynhist.ErrUnsupportedMetric))
+}
+
+func TestCollector_LoadFromRuntimeMetrics_sumEstimated(t *testing.T) {
+	h := &metrics.Float64Histogram{
+		Buckets: []float64{0, 1, 2, 4, 8, 16, 32},
+		Counts:  make([]uint64, 6),
+	}
+	r := dataset.New(1)
+	trueSum := 0.0
+
+	for i := 0; i < 10000; i++ {
+		v := 32 * r.Float64() * r.Float64()
+		trueSum += v
+
+		for j := 1; j < len(h.Buckets); j++ {
+			if v < h.Buckets[j] {
+				h.Counts[j-1]++
+
+				break
+			}
+		}
+	}
+
+	c := dynhist.Collector{}
+	require.NoError(t, c.LoadFromRuntimeMetrics(h))
+
+	assert.True(t, c.SumEstimated)
+	assert.Greater(t, c.Sum, trueSum, "upper boundaries overestimate")
+	assert.LessOrEqual(t, (c.Sum-trueSum)/trueSum, c.EstimatedSumError())
+	assert.Less(t, c.EstimatedSumError(), 1.0)
+
+	assert.Contains(t, c.Render(dynhist.RenderOptions{PrintSum: true}), "\nsums estimated from bucket bounds\n")
+	assert.NotContains(t, c.String(), "estimated")
+
+	j, err := json.Marshal(&c)
+	require.NoError(t, err)
+	assert.Contains(t, string(j), `"sum_estimated":true`)
+
+	m := dynhist.Collector{}
+	m.Add(1)
+	assert.Equal(t, 0.0, m.EstimatedSumError())
+
+	m.SumEstimated = true
+	j, err = json.Marshal(&m)
+	require.NoError(t, err)
+
+	var d dynhist.Collector
+	require.NoError(t, json.Unmarshal(j, &d))
+	assert.True(t, d.SumEstimated)
+
+	m.SumEstimated = false
+
+	m.Merge(&c)
+	assert.True(t, m.SumEstimated)
+
+	m.Reset()
+	assert.False(t, m.SumEstimated)
+}
+
+func TestCollector_LoadFromRuntimeMetrics_invalid(t *testing.T) {
+	c := &dynhist.Collector{}
+	c.Add(1)
+
+	for name, h := range map[string]*metrics.Float64Histogram{
+		"nil":             nil,
+		"empty":           {},
+		"single boundary": {Buckets: []float64{1}, Counts: []uint64{}},
+		"short counts":    {Buckets: []float64{1, 2, 3}, Counts: []uint64{1}},
+		"long counts":     {Buckets: []float64{1, 2}, Counts: []uint64{1, 2}},
+		"unsorted":        {Buckets: []float64{1, 3, 2}, Counts: []uint64{1, 2}},
+		"nan":             {Buckets: []float64{1, math.NaN()}, Counts: []uint64{1}},
+	} {
+		err := c.LoadFromRuntimeMetrics(h)
+		assert.True(t, errors.Is(err, dynhist.ErrInvalidHistogram), "%s: %v", name, err)
+	}
+
+	assert.Equal(t, 1, c.TotalCount())
+	assert.Equal(t, 1.0, c.MaxValue())
+	assert.False(t, c.SumEstimated)
+
+	assert.NoError(t, c.LoadFromRuntimeMetrics(&metrics.Float64Histogram{
+		Buckets: []float64{math.Inf(-1), 1, math.Inf(1)},
+		Counts:  []uint64{2, 3},
+	}))
+	assert.Equal(t, 5, c.TotalCount())
+}
+
+func TestCollector_LoadFromRuntimeMetrics_concurrentAdd(t *testing.T) {
+	const n = 1 << 16
+
+	h := &metrics.Float64Histogram{
+		Buckets: make([]float64, n+1),
+		Counts:  make([]uint64, n),
+	}
+
+	for i := range h.Buckets {
+		h.Buckets[i] = float64(i)
+	}
+
+	for i := range h.Counts {
+		h.Counts[i] = 1
+	}
+
+	c := &dynhist.Collector{}
+	done := make(chan error)
+
+	go func() {
+		done <- c.LoadFromRuntimeMetrics(h)
+	}()
+
+	added := 0
+
+	for loading := true; loading; {
+		select {
+		case err := <-done:
+			require.NoError(t, err)
+
+			loading = false
+		default:
+			c.Add(float64(added % 100))
+			added++
+		}
+	}
+
+	// Adds are not blocked while buckets are prepared, run with -race to check the critical section.
+	assert.Greater(t, added, 0)
+	assert.GreaterOrEqual(t, c.TotalCount(), n)
+	assert.LessOrEqual(t, c.TotalCount(), n+added)
+	assert.NoError(t, c.Validate())
+}
+
+func TestCollector_LoadFromRuntimeMetrics_trackIDs(t *testing.T) {
+	c := &dynhist.Collector{TrackIDs: true}
+	h := &metrics.Float64Histogram{
+		Buckets: []float64{0, 1, 2, 4},
+		Counts:  []uint64{1, 2, 3},
+	}
+
+	require.NoError(t, c.LoadFromRuntimeMetrics(h))
+	assert.Equal(t, []uint64{1, 2, 3}, c.BucketIDs())
+
+	// Replaced buckets receive new identifiers even with the same layout.
+	require.NoError(t, c.LoadFromRuntimeMetrics(h))
+	assert.Equal(t, []uint64{4, 5, 6}, c.BucketIDs())
+}
+
+func TestCollector_LoadFromRuntimeMetrics_json(t *testing.T) {
+	c := &dynhist.Collector{}
+	require.NoError(t, c.LoadFromRuntimeMetrics(&metrics.Float64Histogram{
+		Buckets: []float64{math.Inf(-1), 0, 1, 2, math.Inf(1)},
+		Counts:  []uint64{1, 2, 3, 4},
+	}))
+
+	j, err := json.Marshal(c)
+	require.NoError(t, err)
+	assert.Equal(t, `{"version":1,"count":10,"sum":8,"sum_estimated":true,"min":"-Inf","max":"+Inf","buckets":[`+
+		`{"min":"-Inf","max":0,"count":1,"sum":0},{"min":0,"max":1,"count":2,"sum":2},`+
+		`{"min":1,"max":2,"count":3,"sum":6},{"min":2,"max":"+Inf","count":4,"sum":0}]}`, string(j))
+	assert.Equal(t, string(j), string(c.AppendJSON(nil)))
+
+	var d dynhist.Collector
+
+	require.NoError(t, json.Unmarshal(j, &d))
+	assert.Equal(t, c.Bucket, d.Bucket)
+	assert.Equal(t, c.Buckets, d.Buckets)
+	assert.True(t, d.SumEstimated)
+
+	var buf bytes.Buffer
+
+	ctx, cancel := context.WithCancel(context.Background())
+	tick := make(chan time.Time)
+
+	defer dynhist.SetTicker(func(time.Duration) (<-chan time.Time, func()) { return tick, func() {} })()
+
+	go func() {
+		tick <- time.Unix(0, 0).UTC()
+		cancel()
+	}()
+
+	assert.Equal(t, context.Canceled, c.StreamJSON(ctx, &buf, time.Second))
+	assert.Contains(t, buf.String(), `"max":"+Inf"`)
+	assert.Contains(t, buf.String(), `"99.9":"+Inf"`)
+}
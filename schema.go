@@ -0,0 +1,39 @@
+package dynhist
+
+import (
+	"errors"
+	"fmt"
+)
+
+// SchemaVersion is a version of serialization formats, it is embedded in every emitted format.
+//
+// Readers accept current and previous versions:
+//   - 0 denotes data written before versioning was introduced, it has the same layout as version 1,
+//   - 1 is the current version.
+const SchemaVersion = 1
+
+// ErrUnsupportedVersion is returned when serialized data has unknown schema version.
+var ErrUnsupportedVersion = errors.New("unsupported schema version")
+
+// VersionError describes unsupported schema version.
+type VersionError struct {
+	Version int
+}
+
+// Error implements error.
+func (e VersionError) Error() string {
+	return fmt.Sprintf("%v: %d, max supported: %d", ErrUnsupportedVersion, e.Version, SchemaVersion)
+}
+
+// Unwrap returns ErrUnsupportedVersion.
+func (e VersionError) Unwrap() error {
+	return ErrUnsupportedVersion
+}
+
+func checkVersion(v int) error {
+	if v < SchemaVersion-1 || v > SchemaVersion {
+		return VersionError{Version: v}
+	}
+
+	return nil
+}
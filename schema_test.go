@@ -0,0 +1,49 @@
+package dynhist_test
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vearutop/dynhist-go"
+)
+
+func TestSchemaVersion_JSON(t *testing.T) {
+	expected := dynhist.Collector{BucketsLimit: 3}
+	for i := 0; i < 10; i++ {
+		expected.Add(float64(i))
+	}
+
+	for _, fn := range []string{"testdata/schema/v0.json", "testdata/schema/v1.json"} {
+		data, err := os.ReadFile(fn)
+		require.NoError(t, err)
+
+		c := dynhist.Collector{}
+		require.NoError(t, json.Unmarshal(data, &c), fn)
+		assert.Equal(t, expected.String(), c.String(), fn)
+	}
+
+	data, err := os.ReadFile("testdata/schema/v2.json")
+	require.NoError(t, err)
+
+	c := dynhist.Collector{}
+	err = json.Unmarshal(data, &c)
+
+	var ve dynhist.VersionError
+
+	require.True(t, errors.As(err, &ve), err)
+	assert.Equal(t, 2, ve.Version)
+	assert.True(t, errors.Is(err, dynhist.ErrUnsupportedVersion))
+	assert.Equal(t, 0, c.Count)
+
+	// Current version is emitted.
+	j, err := json.Marshal(&expected)
+	require.NoError(t, err)
+
+	current, err := os.ReadFile("testdata/schema/v1.json")
+	require.NoError(t, err)
+	assert.JSONEq(t, string(current), string(j))
+}
@@ -0,0 +1,124 @@
+package dynhist
+
+import "math"
+
+// snap moves boundaries between bucket at i and its neighbors to nice numbers (1, 2 or 5 × 10^k)
+// if SnapBoundaries allows.
+//
+// Outer boundaries of the first and last buckets are not moved to keep totals exact.
+func (c *Collector) snap(i int) {
+	if c.SnapBoundaries <= 0 {
+		return
+	}
+
+	b := c.Buckets[i]
+	tol := c.SnapBoundaries * (c.Transform.inv(b.Max) - c.Transform.inv(b.Min))
+
+	c.snapPair(i, tol)
+	c.snapPair(i+1, tol)
+}
+
+// snapPair moves boundary between buckets at i-1 and i to the nearest nice number within tol.
+//
+// Nice number becomes Max of the lower bucket, Min of the upper bucket is the next float.
+// Boundaries of zero-width buckets are not moved, boundaries are not moved across raw values.
+func (c *Collector) snapPair(i int, tol float64) {
+	if i <= 0 || i >= len(c.Buckets) {
+		return
+	}
+
+	t := c.Transform
+	prev, next := &c.Buckets[i-1], &c.Buckets[i]
+
+	if prev.Min == prev.Max || next.Min == next.Max {
+		return
+	}
+
+	// Gap between buckets in original units.
+	lo, hi := t.inv(prev.Max), t.inv(next.Min)
+
+	v := niceCeil(lo)
+	if v > hi && v-hi > tol {
+		v = niceFloor(lo)
+	}
+
+	if v > hi && v-hi > tol || v < lo && lo-v > tol {
+		return
+	}
+
+	if v < lo && c.hasRawValue(v, lo, false) || v >= hi && c.hasRawValue(hi, v, true) {
+		return
+	}
+
+	x := t.fwd(v)
+	if x < prev.Min || x >= next.Max {
+		return
+	}
+
+	prev.Max = x
+	next.Min = math.Nextafter(x, math.Inf(1))
+}
+
+// hasRawValue checks if RawValues have a value in (lo, hi], or in [lo, hi] if closed.
+func (c *Collector) hasRawValue(lo, hi float64, closed bool) bool {
+	for _, v := range c.RawValues {
+		if (v > lo || closed && v == lo) && v <= hi {
+			return true
+		}
+	}
+
+	return false
+}
+
+// niceFloor returns the largest nice number not greater than v.
+func niceFloor(v float64) float64 {
+	if v == 0 || math.IsInf(v, 0) || math.IsNaN(v) {
+		return v
+	}
+
+	if v < 0 {
+		return -niceCeil(-v)
+	}
+
+	p := decade(v)
+
+	for _, m := range []float64{5, 2, 1} {
+		if m*p <= v {
+			return m * p
+		}
+	}
+
+	return p
+}
+
+// niceCeil returns the smallest nice number not less than v.
+func niceCeil(v float64) float64 {
+	if v == 0 || math.IsInf(v, 0) || math.IsNaN(v) {
+		return v
+	}
+
+	if v < 0 {
+		return -niceFloor(-v)
+	}
+
+	p := decade(v)
+
+	for _, m := range []float64{1, 2, 5} {
+		if m*p >= v {
+			return m * p
+		}
+	}
+
+	return 10 * p
+}
+
+// decade returns the largest power of 10 not greater than positive v.
+func decade(v float64) float64 {
+	p := math.Pow(10, math.Floor(math.Log10(v)))
+
+	if p > v {
+		p /= 10
+	}
+
+	return p
+}
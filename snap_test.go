@@ -0,0 +1,104 @@
+package dynhist_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vearutop/dynhist-go"
+	"github.com/vearutop/dynhist-go/internal/dataset"
+)
+
+func isNice(v float64) bool {
+	if v == 0 {
+		return true
+	}
+
+	m := math.Abs(v) / math.Pow(10, math.Floor(math.Log10(math.Abs(v))))
+	for _, n := range []float64{1, 2, 5, 10} {
+		if math.Abs(m-n) < 1e-9 {
+			return true
+		}
+	}
+
+	return false
+}
+
+func TestCollector_SnapBoundaries(t *testing.T) {
+	plain := dynhist.Collector{BucketsLimit: 10, WeightFunc: dynhist.LatencyWidth}
+	snapped := dynhist.Collector{BucketsLimit: 10, WeightFunc: dynhist.LatencyWidth, SnapBoundaries: 0.2}
+	r := dataset.New(1)
+
+	// Latency-like values in seconds.
+	for i := 0; i < 10000; i++ {
+		v := math.Exp(-4 + 0.8*r.NormFloat64())
+		plain.Add(v)
+		snapped.Add(v)
+	}
+
+	require.NoError(t, snapped.Validate())
+	assert.Equal(t, plain.Count, snapped.Count)
+	assert.Equal(t, plain.Sum, snapped.Sum)
+	assert.Equal(t, plain.Min, snapped.Buckets[0].Min)
+	assert.Equal(t, plain.Max, snapped.Buckets[len(snapped.Buckets)-1].Max)
+
+	countNice := func(c *dynhist.Collector) int {
+		n := 0
+
+		for _, b := range c.Buckets[:len(c.Buckets)-1] {
+			if isNice(b.Max) {
+				n++
+			}
+		}
+
+		return n
+	}
+
+	assert.Equal(t, 0, countNice(&plain))
+	assert.GreaterOrEqual(t, countNice(&snapped), 5)
+
+	cnt, sum := 0, 0.0
+
+	for _, b := range snapped.Buckets {
+		cnt += b.Count
+		sum += b.Sum
+	}
+
+	assert.Equal(t, snapped.Count, cnt)
+	assert.InDelta(t, snapped.Sum, sum, 1e-9)
+}
+
+func TestCollector_SnapBoundaries_rawValues(t *testing.T) {
+	c := dynhist.Collector{BucketsLimit: 5, SnapBoundaries: 0.5, RawValues: []float64{}}
+	r := dataset.New(2)
+
+	for i := 0; i < 1000; i++ {
+		c.Add(float64(r.Intn(30)) * 0.37)
+	}
+
+	require.NoError(t, c.Validate())
+
+	// Boundaries are not moved across raw values, so they are counted in their buckets.
+	for _, b := range c.Buckets {
+		n := 0
+
+		for _, v := range c.RawValues {
+			if v >= b.Min && v <= b.Max {
+				n++
+			}
+		}
+
+		assert.Equal(t, b.Count, n, b)
+	}
+
+	nice := 0
+
+	for _, b := range c.Buckets[:len(c.Buckets)-1] {
+		if isNice(b.Max) {
+			nice++
+		}
+	}
+
+	assert.Greater(t, nice, 0)
+}
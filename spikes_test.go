@@ -0,0 +1,44 @@
+package dynhist_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vearutop/dynhist-go"
+	"github.com/vearutop/dynhist-go/internal/dataset"
+)
+
+func TestCollector_PreserveSpikes(t *testing.T) {
+	for _, preserve := range []int{0, 1} {
+		c := dynhist.Collector{
+			BucketsLimit:   10,
+			PreserveSpikes: preserve,
+		}
+		r := dataset.New(1)
+		zeros := 0
+
+		for i := 0; i < 100000; i++ {
+			if r.Float64() < 0.6 {
+				zeros++
+
+				c.Add(0)
+
+				continue
+			}
+
+			c.Add(r.ExpFloat64() / 10)
+		}
+
+		assert.LessOrEqual(t, len(c.Buckets), 10)
+
+		if preserve == 0 {
+			assert.NotEqual(t, 0.0, c.Buckets[0].Max, "spike is expected to be merged without protection")
+
+			continue
+		}
+
+		assert.Equal(t, dynhist.Bucket{Min: 0, Max: 0, Count: zeros}, c.Buckets[0])
+		assert.Contains(t, c.String(), fmt.Sprintf("[0.00 0.00]  %d ", zeros))
+	}
+}
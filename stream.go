@@ -0,0 +1,73 @@
+package dynhist
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"strconv"
+	"time"
+)
+
+// streamPercentiles are reported in every StreamJSON snapshot.
+var streamPercentiles = []float64{50, 90, 99, 99.9}
+
+// newTicker is a replaceable ticker constructor for tests.
+var newTicker = func(d time.Duration) (<-chan time.Time, func()) {
+	t := time.NewTicker(d)
+
+	return t.C, t.Stop
+}
+
+type streamSnapshot struct {
+	Time time.Time `json:"time"`
+	collectorJSON
+	Percentiles map[string]jsonBound `json:"percentiles"`
+}
+
+// StreamJSON periodically writes collector snapshots as newline-delimited JSON.
+//
+// Every line contains timestamp, totals, percentiles and buckets.
+// It returns ctx.Err() when ctx is done or an error of writing to w,
+// ErrNilCollector is returned for a nil collector.
+func (c *Collector) StreamJSON(ctx context.Context, w io.Writer, interval time.Duration) error {
+	if c == nil {
+		return ErrNilCollector
+	}
+
+	tick, stop := newTicker(interval)
+	defer stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case t := <-tick:
+			line, err := json.Marshal(c.streamSnapshot(t))
+			if err != nil {
+				return err
+			}
+
+			if _, err := w.Write(append(line, '\n')); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// streamSnapshot copies collector data for StreamJSON, Transform is called under the lock.
+func (c *Collector) streamSnapshot(t time.Time) streamSnapshot {
+	c.Lock()
+	defer c.Unlock()
+
+	s := streamSnapshot{
+		Time:          t,
+		Percentiles:   make(map[string]jsonBound, len(streamPercentiles)),
+		collectorJSON: c.snapshotJSON(),
+	}
+
+	for _, p := range streamPercentiles {
+		s.Percentiles[strconv.FormatFloat(p, 'f', -1, 64)] = jsonBound(c.percentile(p))
+	}
+
+	return s
+}
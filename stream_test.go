@@ -0,0 +1,85 @@
+package dynhist_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vearutop/dynhist-go"
+)
+
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) {
+	return 0, errors.New("failed")
+}
+
+func TestCollector_StreamJSON(t *testing.T) {
+	tick := make(chan time.Time)
+
+	defer dynhist.SetTicker(func(d time.Duration) (<-chan time.Time, func()) {
+		assert.Equal(t, time.Second, d)
+
+		return tick, func() {}
+	})()
+
+	c := dynhist.Collector{}
+	for i := 1; i <= 100; i++ {
+		c.Add(float64(i))
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	buf := bytes.NewBuffer(nil)
+	done := make(chan error)
+
+	go func() {
+		done <- c.StreamJSON(ctx, buf, time.Second)
+	}()
+
+	ts := time.Date(2021, 1, 2, 3, 4, 5, 0, time.UTC)
+	tick <- ts
+	tick <- ts.Add(time.Second)
+	cancel()
+
+	assert.True(t, errors.Is(<-done, context.Canceled))
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 2)
+
+	var s struct {
+		Time        time.Time          `json:"time"`
+		Count       int                `json:"count"`
+		Sum         float64            `json:"sum"`
+		Percentiles map[string]float64 `json:"percentiles"`
+		Buckets     []dynhist.Bucket   `json:"buckets"`
+	}
+
+	require.NoError(t, json.Unmarshal([]byte(lines[1]), &s))
+	assert.Equal(t, ts.Add(time.Second), s.Time)
+	assert.Equal(t, 100, s.Count)
+	assert.Equal(t, 5050.0, s.Sum)
+	assert.Equal(t, c.Percentile(99), s.Percentiles["99"])
+	assert.Equal(t, c.Percentile(99.9), s.Percentiles["99.9"])
+	assert.Len(t, s.Buckets, len(c.Buckets))
+}
+
+func TestCollector_StreamJSON_writeError(t *testing.T) {
+	tick := make(chan time.Time, 1)
+
+	defer dynhist.SetTicker(func(d time.Duration) (<-chan time.Time, func()) {
+		return tick, func() {}
+	})()
+
+	c := dynhist.Collector{}
+	c.Add(1)
+
+	tick <- time.Now()
+
+	assert.EqualError(t, c.StreamJSON(context.Background(), failingWriter{}, time.Second), "failed")
+}
@@ -0,0 +1,89 @@
+package dynhist
+
+import "math"
+
+// SumPercentile returns value v such that values not greater than v make percent of total sum.
+//
+// The value is interpolated linearly within the bucket where cumulative sum crosses the target.
+// Minimum or maximum is returned for percent at 0 or 100. Result is undefined for negative values,
+// NaN is returned if any bucket has a negative sum or if the total sum is zero.
+// If SumEstimated is set, the result is derived from estimated sums, see EstimatedSumError.
+func (c *Collector) SumPercentile(percent float64) float64 {
+	if c == nil {
+		return math.NaN()
+	}
+
+	total, buckets := c.snapshotBuckets()
+
+	if len(buckets) == 0 || total.Sum <= 0 {
+		return math.NaN()
+	}
+
+	for _, b := range buckets {
+		if b.Sum < 0 {
+			return math.NaN()
+		}
+	}
+
+	if percent <= 0 {
+		return total.Min
+	}
+
+	if percent >= 100 {
+		return total.Max
+	}
+
+	target := percent * total.Sum / 100
+	cum := 0.0
+
+	for _, b := range buckets {
+		if cum+b.Sum >= target && b.Sum > 0 {
+			return b.Min + (b.Max-b.Min)*(target-cum)/b.Sum
+		}
+
+		cum += b.Sum
+	}
+
+	return total.Max
+}
+
+// EstimatedSumError returns the worst-case error of bucket sums relative to the total sum, if SumEstimated is set.
+//
+// True sum of a bucket is between Count×Min and Count×Max, so the error of an estimated
+// sum is not greater than the distance to the farthest of these bounds. Zero is returned
+// if sums are not estimated, +Inf if the bound is unknown (infinite boundaries or zero total sum).
+func (c *Collector) EstimatedSumError() float64 {
+	if c == nil {
+		return 0
+	}
+
+	c.Lock()
+	estimated := c.SumEstimated
+	c.Unlock()
+
+	if !estimated {
+		return 0
+	}
+
+	total, buckets := c.snapshotBuckets()
+	worst := 0.0
+
+	for _, b := range buckets {
+		if b.Count == 0 {
+			continue
+		}
+
+		n := float64(b.Count)
+		worst += math.Max(math.Abs(b.Sum-n*b.Min), math.Abs(n*b.Max-b.Sum))
+	}
+
+	if worst == 0 {
+		return 0
+	}
+
+	if total.Sum == 0 || math.IsNaN(worst) {
+		return math.Inf(1)
+	}
+
+	return worst / math.Abs(total.Sum)
+}
@@ -0,0 +1,75 @@
+package dynhist_test
+
+import (
+	"math"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vearutop/dynhist-go"
+	"github.com/vearutop/dynhist-go/internal/dataset"
+)
+
+func TestCollector_SumPercentile(t *testing.T) {
+	c := dynhist.Collector{BucketsLimit: 30, WeightFunc: dynhist.LatencyWidth}
+	r := dataset.New(1)
+	values := make([]float64, 0, 10000)
+
+	for i := 0; i < 10000; i++ {
+		v := math.Round(math.Exp(7 + 1.5*r.NormFloat64())) // Response sizes in bytes.
+		values = append(values, v)
+		c.Add(v)
+	}
+
+	sort.Float64s(values)
+
+	for _, p := range []float64{10, 50, 90, 99} {
+		target := p * c.Sum / 100
+		cum, exact := 0.0, 0.0
+
+		for _, v := range values {
+			cum += v
+			if cum >= target {
+				exact = v
+
+				break
+			}
+		}
+
+		est := c.SumPercentile(p)
+
+		for _, b := range c.Buckets {
+			if b.Min <= exact && exact <= b.Max {
+				assert.InDelta(t, exact, est, b.Max-b.Min, p)
+			}
+		}
+	}
+
+	assert.Equal(t, c.Min, c.SumPercentile(0))
+	assert.Equal(t, c.Max, c.SumPercentile(100))
+}
+
+func TestCollector_SumPercentile_undefined(t *testing.T) {
+	c := dynhist.Collector{}
+	assert.True(t, math.IsNaN(c.SumPercentile(50)))
+
+	c.Add(0)
+	assert.True(t, math.IsNaN(c.SumPercentile(50)))
+
+	c.Add(-1)
+	c.Add(5)
+	assert.True(t, math.IsNaN(c.SumPercentile(50)))
+}
+
+func TestCollector_SumPercentile_points(t *testing.T) {
+	c := dynhist.Collector{}
+
+	for i := 0; i < 9; i++ {
+		c.Add(1)
+	}
+
+	c.Add(9) // Half of the sum.
+
+	assert.Equal(t, 1.0, c.SumPercentile(50))
+	assert.Equal(t, 9.0, c.SumPercentile(51))
+}
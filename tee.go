@@ -0,0 +1,94 @@
+package dynhist
+
+import (
+	"reflect"
+	"sort"
+	"time"
+)
+
+// Adder collects values, it is implemented by Collector and TeeAdder.
+type Adder interface {
+	Add(v float64)
+}
+
+var (
+	_ Adder = &Collector{}
+	_ Adder = &TeeAdder{}
+)
+
+// TeeAdder collects values into multiple collectors.
+type TeeAdder struct {
+	cs []*Collector
+}
+
+// Tee creates an adder that collects every value into all non-nil collectors.
+//
+// A value is added to all collectors at once: collectors are locked together in a stable order,
+// so readers do not observe a value in one collector and not in another. Duplicate collectors are ignored.
+func Tee(cs ...*Collector) *TeeAdder {
+	t := &TeeAdder{}
+	seen := make(map[*Collector]bool, len(cs))
+
+	for _, c := range cs {
+		if c == nil || seen[c] {
+			continue
+		}
+
+		seen[c] = true
+		t.cs = append(t.cs, c)
+	}
+
+	// Stable locking order prevents deadlocks between tees with shared collectors.
+	sort.Slice(t.cs, func(i, j int) bool {
+		return reflect.ValueOf(t.cs[i]).Pointer() < reflect.ValueOf(t.cs[j]).Pointer()
+	})
+
+	return t
+}
+
+// Add collects value, it is a no-op on a nil TeeAdder.
+func (t *TeeAdder) Add(v float64) {
+	t.AddN(v, 1)
+}
+
+// AddN collects value n times, non-positive n is ignored. It is a no-op on a nil TeeAdder.
+func (t *TeeAdder) AddN(v float64, n int) {
+	if t == nil || n <= 0 {
+		return
+	}
+
+	for _, c := range t.cs {
+		c.Lock()
+	}
+
+	defer t.unlock()
+
+	for _, c := range t.cs {
+		c.addN(v, n)
+		c.mergeOverLimit()
+	}
+}
+
+// AddDuration collects duration in seconds, it is a no-op on a nil TeeAdder.
+func (t *TeeAdder) AddDuration(d time.Duration) {
+	t.Add(d.Seconds())
+}
+
+// unlock unlocks all collectors and then calls pending watchers.
+func (t *TeeAdder) unlock() {
+	var events []watchEvent
+
+	for _, c := range t.cs {
+		events = append(events, c.events...)
+		c.events = nil
+
+		c.Unlock()
+	}
+
+	notify(events)
+}
+
+// AddDuration collects duration in seconds.
+func (c *Collector) AddDuration(d time.Duration) {
+	c.Add(d.Seconds())
+}
@@ -0,0 +1,90 @@
+package dynhist_test
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vearutop/dynhist-go"
+	"github.com/vearutop/dynhist-go/internal/dataset"
+)
+
+func TestTee(t *testing.T) {
+	all := &dynhist.Collector{BucketsLimit: 30}
+	window := &dynhist.Collector{BucketsLimit: 5, Transform: dynhist.LogTransform, TrackIDs: true}
+	expAll := &dynhist.Collector{BucketsLimit: 30}
+	expWindow := &dynhist.Collector{BucketsLimit: 5, Transform: dynhist.LogTransform, TrackIDs: true}
+
+	tee := dynhist.Tee(all, nil, window, all)
+	r := dataset.New(1)
+
+	var a dynhist.Adder = tee
+
+	for i := 0; i < 1000; i++ {
+		v := 1 + r.ExpFloat64()
+
+		a.Add(v)
+		expAll.Add(v)
+		expWindow.Add(v)
+	}
+
+	tee.AddN(3, 10)
+	tee.AddN(3, 0)
+	expAll.AddN(3, 10)
+	expWindow.AddN(3, 10)
+
+	tee.AddDuration(1500 * time.Millisecond)
+	expAll.AddDuration(1500 * time.Millisecond)
+	expWindow.Add(1.5)
+
+	assert.Equal(t, 1011, all.Count)
+	assert.Equal(t, expAll.Buckets, all.Buckets)
+	assert.Equal(t, expWindow.Buckets, window.Buckets)
+	assert.Equal(t, expWindow.BucketIDs(), window.BucketIDs())
+
+	// No targets.
+	dynhist.Tee().Add(1)
+	dynhist.Tee(nil).Add(1)
+}
+
+func TestTee_concurrent(t *testing.T) {
+	c1 := &dynhist.Collector{}
+	c2 := &dynhist.Collector{}
+
+	var (
+		wg     sync.WaitGroup
+		events int
+		mu     sync.Mutex
+	)
+
+	// Watcher may use collectors, they are unlocked before notification.
+	stop := c1.WatchPercentile(50, 100, 0, func(crossed bool, value float64) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		events++
+		_ = c2.TotalCount()
+	})
+	defer stop()
+
+	for _, tee := range []*dynhist.TeeAdder{dynhist.Tee(c1, c2), dynhist.Tee(c2, c1)} {
+		tee := tee
+
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for i := 0; i < 1000; i++ {
+				tee.Add(float64(i))
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	assert.Equal(t, 2000, c1.TotalCount())
+	assert.Equal(t, 2000, c2.TotalCount())
+	assert.Equal(t, 1, events)
+}
@@ -0,0 +1,60 @@
+package dynhist
+
+import "errors"
+
+// ErrDataCollected is returned when configuration can not be changed after values are collected.
+var ErrDataCollected = errors.New("values are already collected")
+
+type trackedThreshold struct {
+	name  string
+	value float64
+	count int
+}
+
+// TrackThreshold registers a named threshold to count values exceeding it exactly.
+//
+// Thresholds must be registered before values are collected, ErrDataCollected is returned otherwise.
+// Registering an existing name replaces its value. Counts are reported by ThresholdCounts and
+// in the footer of Render.
+func (c *Collector) TrackThreshold(name string, t float64) error {
+	if c == nil {
+		return ErrNilCollector
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	if c.Count > 0 {
+		return ErrDataCollected
+	}
+
+	for i := range c.thresholds {
+		if c.thresholds[i].name == name {
+			c.thresholds[i].value = t
+
+			return nil
+		}
+	}
+
+	c.thresholds = append(c.thresholds, trackedThreshold{name: name, value: t})
+
+	return nil
+}
+
+// ThresholdCounts returns numbers of values greater than registered thresholds by name.
+func (c *Collector) ThresholdCounts() map[string]int {
+	if c == nil {
+		return nil
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	res := make(map[string]int, len(c.thresholds))
+
+	for _, t := range c.thresholds {
+		res[t.name] = t.count
+	}
+
+	return res
+}
@@ -0,0 +1,53 @@
+package dynhist_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vearutop/dynhist-go"
+	"github.com/vearutop/dynhist-go/dynhisttest"
+	"github.com/vearutop/dynhist-go/internal/dataset"
+)
+
+func TestCollector_TrackThreshold(t *testing.T) {
+	c := dynhist.Collector{BucketsLimit: 5}
+
+	require.NoError(t, c.TrackThreshold("SLO", 0.25))
+	require.NoError(t, c.TrackThreshold("timeout", 0.5))
+	require.NoError(t, c.TrackThreshold("timeout", 1))
+
+	r := dataset.New(1)
+	exceeded := map[string]int{}
+
+	for i := 0; i < 10000; i++ {
+		v := r.ExpFloat64() / 5
+		c.Add(v)
+
+		if v > 0.25 {
+			exceeded["SLO"]++
+		}
+
+		if v > 1 {
+			exceeded["timeout"]++
+		}
+	}
+
+	assert.Equal(t, exceeded, c.ThresholdCounts())
+
+	// Bucket-derived estimate is approximate.
+	estimated := int(float64(c.Count) * (1 - dynhisttest.FractionBetween(&c, c.Min, 0.25)))
+	assert.NotEqual(t, exceeded["SLO"], estimated)
+	assert.InDelta(t, exceeded["SLO"], estimated, 500)
+
+	assert.Equal(t, dynhist.ErrDataCollected, c.TrackThreshold("late", 2))
+
+	c.Reset()
+	c.Add(2)
+	assert.Equal(t, map[string]int{"SLO": 1, "timeout": 1}, c.ThresholdCounts())
+	assert.Equal(t, `[ min  max] cnt  total% (1 events)
+[2.00 2.00] 1 100.00% ....................................................................................................
+> SLO (0.25): 1 (100.00%)
+> timeout (1): 1 (100.00%)
+`, c.String())
+}
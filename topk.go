@@ -0,0 +1,142 @@
+package dynhist
+
+import (
+	"math"
+	"sort"
+)
+
+// TopValues returns exact largest values collected with KeepTopK in descending order.
+//
+// It returns nil if KeepTopK is not enabled or there are no values.
+func (c *Collector) TopValues() []float64 {
+	if c == nil {
+		return nil
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	if len(c.top) == 0 {
+		return nil
+	}
+
+	res := append([]float64(nil), c.top...)
+	sort.Sort(sort.Reverse(sort.Float64Slice(res)))
+
+	return res
+}
+
+// keepTop adds value n times to the min-heap of largest values.
+func (c *Collector) keepTop(v float64, n int) {
+	c.topCount += n
+
+	if math.IsNaN(v) {
+		return
+	}
+
+	for i := 0; i < n; i++ {
+		if len(c.top) < c.KeepTopK {
+			c.top = append(c.top, v)
+			c.topUp(len(c.top) - 1)
+
+			continue
+		}
+
+		// Heap root is the smallest kept value, lower values are skipped in constant time.
+		if v <= c.top[0] {
+			return
+		}
+
+		c.top[0] = v
+		c.topDown(0)
+	}
+}
+
+func (c *Collector) topUp(i int) {
+	h := c.top
+
+	for i > 0 {
+		parent := (i - 1) / 2
+		if h[parent] <= h[i] {
+			return
+		}
+
+		h[parent], h[i] = h[i], h[parent]
+		i = parent
+	}
+}
+
+func (c *Collector) topDown(i int) {
+	h := c.top
+
+	for {
+		smallest := i
+
+		if l := 2*i + 1; l < len(h) && h[l] < h[smallest] {
+			smallest = l
+		}
+
+		if r := 2*i + 2; r < len(h) && h[r] < h[smallest] {
+			smallest = r
+		}
+
+		if smallest == i {
+			return
+		}
+
+		h[smallest], h[i] = h[i], h[smallest]
+		i = smallest
+	}
+}
+
+// mergeTop adds largest values of other collector with count of values they represent.
+//
+// Largest values stay exact if other collector keeps at least as many values or all of its values.
+func (c *Collector) mergeTop(top []float64, topCount, keepTopK int) {
+	if c.KeepTopK <= 0 {
+		return
+	}
+
+	for _, v := range top {
+		c.keepTop(v, 1)
+	}
+
+	c.topCount += topCount - len(top)
+
+	if keepTopK < c.KeepTopK && len(top) < topCount {
+		// Values dropped by other collector may belong to largest values, top is not exact anymore.
+		c.topCount = -1
+	}
+}
+
+// resetTop discards largest values, so that they are not used for data that replaced buckets.
+func (c *Collector) resetTop() {
+	c.top = c.top[:0]
+	c.topCount = 0
+}
+
+// topPercentile returns exact nearest-rank percentile if it is within largest kept values.
+func (c *Collector) topPercentile(percent float64) (float64, bool) {
+	if len(c.top) == 0 || c.topCount != c.Count {
+		return 0, false
+	}
+
+	target := percent * float64(c.Count) / 100
+	target -= cdfEpsilon * target
+
+	i := int(math.Ceil(target)) - 1
+	if i < 0 {
+		i = 0
+	}
+
+	// Index in descending order.
+	j := c.Count - 1 - i
+	if j < 0 || j >= len(c.top) {
+		return 0, false
+	}
+
+	sorted := append([]float64(nil), c.top...)
+	sort.Sort(sort.Reverse(sort.Float64Slice(sorted)))
+
+	return sorted[j], true
+}
@@ -0,0 +1,91 @@
+package dynhist_test
+
+import (
+	"math"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vearutop/dynhist-go"
+	"github.com/vearutop/dynhist-go/internal/dataset"
+)
+
+func TestCollector_KeepTopK(t *testing.T) {
+	c := dynhist.Collector{BucketsLimit: 20, KeepTopK: 100, RawValues: []float64{}}
+	assert.Nil(t, c.TopValues())
+
+	r := dataset.New(1)
+
+	for i := 0; i < 100000; i++ {
+		c.Add(math.Exp(r.NormFloat64()))
+	}
+
+	sorted := append([]float64(nil), c.RawValues...)
+	sort.Sort(sort.Reverse(sort.Float64Slice(sorted)))
+
+	assert.Equal(t, sorted[:100], c.TopValues())
+
+	// Percentiles within 100 largest of 100000 values are exact.
+	for _, e := range c.AccuracyReport([]float64{99.91, 99.95, 99.99, 100}) {
+		assert.Equal(t, e.Exact, e.Estimated, e.Percentile)
+	}
+
+	assert.Equal(t, sorted[0], c.Percentile(100))
+	assert.Equal(t, sorted[11], c.Percentile(99.989))
+	assert.NotEqual(t, 0.0, c.AccuracyReport([]float64{99})[0].AbsError, "p99 is estimated from buckets")
+
+	plain := dynhist.Collector{BucketsLimit: 20}
+	for _, v := range c.RawValues {
+		plain.Add(v)
+	}
+
+	assert.Equal(t, plain.Percentile(99), c.Percentile(99))
+	assert.NotEqual(t, plain.Percentile(99.97), c.Percentile(99.97))
+
+	// Values below the smallest kept value do not change the heap and do not allocate.
+	top := c.TopValues()
+
+	assert.Equal(t, 0.0, testing.AllocsPerRun(1000, func() {
+		c.Add(0.5)
+	}))
+	assert.Equal(t, top, c.TopValues())
+
+	// Exact values are discarded when buckets are replaced.
+	require.NoError(t, c.LoadBuckets(c.Buckets))
+	assert.Nil(t, c.TopValues())
+	c.Add(1000)
+	assert.Equal(t, []float64{1000}, c.TopValues())
+
+	c.Reset()
+	c.Add(1000)
+	assert.Equal(t, 1000.0, c.Percentile(100))
+}
+
+func TestCollector_KeepTopK_merge(t *testing.T) {
+	a := &dynhist.Collector{BucketsLimit: 5, KeepTopK: 3}
+	b := &dynhist.Collector{BucketsLimit: 5, KeepTopK: 3}
+
+	for i := 1; i <= 100; i++ {
+		a.Add(float64(i))
+		b.Add(float64(i) + 0.5)
+	}
+
+	a.Merge(b)
+
+	assert.Equal(t, []float64{100.5, 100, 99.5}, a.TopValues())
+	assert.Equal(t, 100.5, a.Percentile(100))
+	assert.Equal(t, 100.0, a.Percentile(99.5))
+	assert.Equal(t, 99.5, a.Percentile(99))
+
+	// Fewer values kept by other collector make top inexact.
+	c := &dynhist.Collector{BucketsLimit: 5, KeepTopK: 1}
+	c.Add(1000)
+	c.Add(999)
+
+	a.Merge(c)
+
+	assert.Equal(t, []float64{1000, 100.5, 100}, a.TopValues())
+	assert.Equal(t, a.TopValues(), a.Clone().TopValues())
+	assert.NotEqual(t, 100.5, a.Percentile(99.5), "second largest value 999 is not kept")
+}
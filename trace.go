@@ -0,0 +1,56 @@
+package dynhist
+
+import (
+	"strconv"
+	"strings"
+)
+
+// traceMerge writes a merge line to Trace before merging buckets at mergePoint-1 and mergePoint.
+func (c *Collector) traceMerge(mergePoint int) {
+	b1 := c.Buckets[mergePoint-1]
+	b2 := c.Buckets[mergePoint]
+	w := c.weight(mergePoint)
+
+	var line strings.Builder
+
+	line.WriteString("merge ")
+	traceBucket(&line, b1)
+	line.WriteString(" ")
+	traceBucket(&line, b2)
+	line.WriteString(" w=")
+	line.WriteString(formatRaw(w))
+
+	next, found := 0.0, false
+
+	for i := 1; i < len(c.Buckets); i++ {
+		if i == mergePoint {
+			continue
+		}
+
+		if w := c.weight(i); !found || w < next {
+			next, found = w, true
+		}
+	}
+
+	if found {
+		line.WriteString(" next=")
+		line.WriteString(formatRaw(next))
+	}
+
+	line.WriteString("\n")
+
+	_, _ = c.Trace.Write([]byte(line.String()))
+}
+
+// traceInsert writes an insert line to Trace.
+func (c *Collector) traceInsert(v float64, i int) {
+	_, _ = c.Trace.Write([]byte("insert " + formatRaw(v) + " at " + strconv.Itoa(i) + "\n"))
+}
+
+func traceBucket(w *strings.Builder, b Bucket) {
+	w.WriteString("[")
+	w.WriteString(formatRaw(b.Min))
+	w.WriteString(" ")
+	w.WriteString(formatRaw(b.Max))
+	w.WriteString("]")
+}
@@ -0,0 +1,27 @@
+package dynhist_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vearutop/dynhist-go"
+)
+
+func TestCollector_Trace(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	c := dynhist.Collector{BucketsLimit: 3, Trace: buf}
+
+	for _, v := range []float64{5, 1, 3, 4, 4, 10} {
+		c.Add(v)
+	}
+
+	assert.Equal(t, `insert 5 at 0
+insert 1 at 0
+insert 3 at 1
+insert 4 at 2
+merge [3 3] [4 4] w=1 next=1
+insert 10 at 3
+merge [3 4] [5 5] w=2 next=3
+`, buf.String())
+}
@@ -0,0 +1,65 @@
+package dynhist
+
+import "math"
+
+// Transform defines a monotonically increasing transformation of values and its inverse.
+type Transform struct {
+	Fwd func(v float64) float64
+	Inv func(v float64) float64
+}
+
+var (
+	// LogTransform buckets decimal logarithms of positive values.
+	LogTransform = &Transform{
+		Fwd: math.Log10,
+		Inv: func(v float64) float64 { return math.Pow(10, v) },
+	}
+
+	// SqrtTransform buckets square roots of non-negative values.
+	SqrtTransform = &Transform{
+		Fwd: math.Sqrt,
+		Inv: func(v float64) float64 { return v * v },
+	}
+)
+
+// outward converts boundaries of totals and buckets from transformed values to original units in place.
+func (t *Transform) outward(total *Bucket, buckets []Bucket) {
+	if t != nil {
+		convert(t.Inv, total, buckets)
+	}
+}
+
+// inward converts boundaries of totals and buckets from original units to transformed values in place.
+func (t *Transform) inward(total *Bucket, buckets []Bucket) {
+	if t != nil {
+		convert(t.Fwd, total, buckets)
+	}
+}
+
+func convert(f func(v float64) float64, total *Bucket, buckets []Bucket) {
+	total.Min = f(total.Min)
+	total.Max = f(total.Max)
+
+	for i := range buckets {
+		buckets[i].Min = f(buckets[i].Min)
+		buckets[i].Max = f(buckets[i].Max)
+	}
+}
+
+// inv converts a boundary to original units.
+func (t *Transform) inv(v float64) float64 {
+	if t == nil {
+		return v
+	}
+
+	return t.Inv(v)
+}
+
+// fwd converts a boundary from original units.
+func (t *Transform) fwd(v float64) float64 {
+	if t == nil {
+		return v
+	}
+
+	return t.Fwd(v)
+}
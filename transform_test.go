@@ -0,0 +1,138 @@
+package dynhist_test
+
+import (
+	"encoding/json"
+	"io"
+	"math"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vearutop/dynhist-go"
+	"github.com/vearutop/dynhist-go/internal/dataset"
+)
+
+func TestCollector_Transform(t *testing.T) {
+	for name, tr := range map[string]*dynhist.Transform{
+		"log":  dynhist.LogTransform,
+		"sqrt": dynhist.SqrtTransform,
+	} {
+		c := dynhist.Collector{BucketsLimit: 20, Transform: tr}
+		r := dataset.New(1)
+		values := make([]float64, 0, 10000)
+		sum := 0.0
+
+		for i := 0; i < 10000; i++ {
+			v := math.Pow(10, 9*r.Float64()) // 9 orders of magnitude.
+			values = append(values, v)
+			sum += v
+			c.Add(v)
+		}
+
+		sort.Float64s(values)
+		assert.InDelta(t, sum, c.Sum, 1e-9*sum, name)
+
+		rows := c.BarData(0, dynhist.RenderOptions{})
+
+		for _, p := range []float64{10, 50, 90, 99} {
+			exact := values[int(math.Ceil(p*float64(len(values))/100))-1]
+			est := c.Percentile(p)
+
+			// Exact value belongs to the bucket that provides estimate.
+			i := sort.Search(len(rows), func(i int) bool { return rows[i].Max >= est*(1-1e-9) })
+			require.Less(t, i, len(rows), name)
+			assert.LessOrEqual(t, rows[i].Min, est*(1+1e-9), "%s p%v", name, p)
+			assert.LessOrEqual(t, rows[i].Min, exact*(1+1e-9), "%s p%v", name, p)
+			assert.GreaterOrEqual(t, rows[i].Max*(1+1e-9), exact, "%s p%v", name, p)
+		}
+	}
+}
+
+func TestCollector_Transform_String(t *testing.T) {
+	c := dynhist.Collector{BucketsLimit: 4, Transform: dynhist.LogTransform}
+
+	for _, v := range []float64{1, 10, 10, 100, 1000, 1000, 1000} {
+		c.Add(v)
+	}
+
+	assert.Equal(t, `[    min     max] cnt total% (7 events)
+[   1.00    1.00] 1 14.29% ..............
+[  10.00   10.00] 2 28.57% ............................
+[ 100.00  100.00] 1 14.29% ..............
+[1000.00 1000.00] 3 42.86% ..........................................
+`, c.String())
+	assert.InDelta(t, 1000.0, c.Percentile(99), 1e-9)
+
+	j, err := json.Marshal(&c)
+	require.NoError(t, err)
+
+	c2 := dynhist.Collector{Transform: dynhist.LogTransform}
+	require.NoError(t, json.Unmarshal(j, &c2))
+	assert.Equal(t, c.String(), c2.String())
+	assert.InDelta(t, 3.0, c2.Max, 1e-9)
+}
+
+func TestCollector_Transform_readAPIs(t *testing.T) {
+	c := &dynhist.Collector{BucketsLimit: 100, Transform: dynhist.LogTransform}
+
+	for i := 1; i <= 1000; i++ {
+		c.Add(float64(i))
+	}
+
+	c.Add(500)
+
+	assert.InDelta(t, 500, c.PercentileWithin(1, 1000, 50), 20)
+
+	res := c.Resample([]float64{1, 500, 1000})
+	require.Len(t, res, 2)
+	assert.InDelta(t, 500, res[0].Count, 10)
+	assert.InDelta(t, 500, res[1].Count, 10)
+
+	n, _ := c.CountOf(2000)
+	assert.Equal(t, 0, n)
+
+	n, exact := c.CountOf(500)
+	assert.False(t, exact && n == 0, "500 was added twice")
+
+	i, isNewMax := c.AddClassified(0.5)
+	assert.Equal(t, 0, i)
+	assert.False(t, isNewMax)
+
+	i, isNewMax = c.AddClassified(5000)
+	assert.Equal(t, len(c.Buckets)-1, i)
+	assert.True(t, isNewMax)
+}
+
+func TestCollector_Transform_panickingInv(t *testing.T) {
+	c := dynhist.Collector{Name: "m", Transform: &dynhist.Transform{
+		Fwd: func(v float64) float64 { return v },
+		Inv: func(v float64) float64 { panic("failed") },
+	}}
+	c.Add(1)
+
+	for name, f := range map[string]func(){
+		"String":     func() { _ = c.String() },
+		"Render":     func() { c.Render(dynhist.RenderOptions{Percentiles: []float64{50}}) },
+		"BarData":    func() { c.BarData(0, dynhist.RenderOptions{}) },
+		"Prometheus": func() { _ = c.WritePrometheus(io.Discard, "") },
+		"EncodeURL":  func() { c.EncodeURL() },
+		"JSON":       func() { _, _ = c.MarshalJSON() },
+	} {
+		assert.Panics(t, f, name)
+
+		done := make(chan struct{})
+
+		go func() {
+			c.Add(2)
+			close(done)
+		}()
+
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatalf("collector is left locked after %s", name)
+		}
+	}
+}
@@ -0,0 +1,271 @@
+package dynhist
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+)
+
+var (
+	// ErrURLFormat is returned by DecodeURL for malformed input.
+	ErrURLFormat = errors.New("invalid URL-encoded histogram")
+
+	// ErrURLTooLarge is returned by DecodeURL when input or decoded data exceeds limits.
+	ErrURLTooLarge = errors.New("URL-encoded histogram is too large")
+)
+
+const (
+	// MaxURLLength is a maximum length of a string accepted by DecodeURL.
+	MaxURLLength = 1 << 16
+
+	// maxURLPayload limits size of decompressed data.
+	maxURLPayload = 1 << 20
+
+	urlDeflate = 1
+
+	// maxInt is math.MaxInt that is not available in Go 1.16.
+	maxInt = int(^uint(0) >> 1)
+)
+
+// EncodeURL returns collected data as a compact base64url string, safe for URL query parameters.
+//
+// Data starts with SchemaVersion and a flags byte, followed by a payload (deflate compressed
+// if it is shorter) of Name, Unit, Help and buckets with exact boundaries, counts and sums.
+// Adjacent boundaries are XOR-ed with the previous one and varint-encoded, so a histogram
+// of 10 buckets takes about 350 characters. Boundaries are encoded in original units if
+// Transform is set, configuration is not encoded.
+func (c *Collector) EncodeURL() string {
+	if c == nil {
+		return ""
+	}
+
+	c.Lock()
+	name, unit, help := c.Name, c.Unit, c.Help
+	c.Unlock()
+
+	_, buckets := c.snapshotBuckets()
+
+	return base64.RawURLEncoding.EncodeToString(encodeBinary(name, unit, help, buckets))
+}
+
+// encodeBinary encodes buckets in original units with metadata in the format of EncodeURL.
+func encodeBinary(name, unit, help string, buckets []Bucket) []byte {
+	payload := make([]byte, 0, 64+20*len(buckets))
+
+	for _, s := range []string{name, unit, help} {
+		payload = appendUvarint(payload, uint64(len(s)))
+		payload = append(payload, s...)
+	}
+
+	payload = appendUvarint(payload, uint64(len(buckets)))
+	prev := uint64(0)
+
+	for _, b := range buckets {
+		minBits, maxBits := math.Float64bits(b.Min), math.Float64bits(b.Max)
+
+		payload = appendUvarint(payload, minBits^prev)
+		payload = appendUvarint(payload, maxBits^minBits)
+		payload = appendUvarint(payload, uint64(b.Count))
+		payload = appendUint64(payload, math.Float64bits(b.Sum))
+		prev = maxBits
+	}
+
+	data := []byte{SchemaVersion, 0}
+
+	var buf bytes.Buffer
+
+	fw, _ := flate.NewWriter(&buf, flate.BestCompression) //nolint:errcheck // Level is valid.
+	_, _ = fw.Write(payload)                              //nolint:errcheck // Buffer does not fail.
+	_ = fw.Close()                                        //nolint:errcheck // Buffer does not fail.
+
+	if buf.Len() < len(payload) {
+		data[1] = urlDeflate
+		payload = buf.Bytes()
+	}
+
+	return append(data, payload...)
+}
+
+// DecodeURL returns a collector with data encoded by EncodeURL.
+//
+// Input longer than MaxURLLength results in ErrURLTooLarge, malformed input in ErrURLFormat,
+// VersionError or errors of ValidateBuckets.
+func DecodeURL(s string) (*Collector, error) {
+	if len(s) > MaxURLLength {
+		return nil, fmt.Errorf("%w: %d characters", ErrURLTooLarge, len(s))
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrURLFormat, err)
+	}
+
+	return decodeBinary(data)
+}
+
+// decodeBinary returns a collector with data encoded by encodeBinary.
+func decodeBinary(data []byte) (*Collector, error) {
+	var err error
+
+	if len(data) < 2 {
+		return nil, fmt.Errorf("%w: %d bytes is too short", ErrURLFormat, len(data))
+	}
+
+	if err := checkVersion(int(data[0])); err != nil {
+		return nil, err
+	}
+
+	payload := data[2:]
+
+	switch data[1] {
+	case 0:
+	case urlDeflate:
+		br := bytes.NewReader(payload)
+
+		// Reader implements io.ByteReader, so flate does not read past compressed data.
+		payload, err = io.ReadAll(io.LimitReader(flate.NewReader(br), maxURLPayload+1))
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrURLFormat, err)
+		}
+
+		if len(payload) > maxURLPayload {
+			return nil, fmt.Errorf("%w: more than %d bytes decompressed", ErrURLTooLarge, maxURLPayload)
+		}
+
+		if br.Len() > 0 {
+			return nil, fmt.Errorf("%w: %d trailing bytes", ErrURLFormat, br.Len())
+		}
+	default:
+		return nil, fmt.Errorf("%w: unknown flags %d", ErrURLFormat, data[1])
+	}
+
+	d := urlDecoder{data: payload}
+	c := &Collector{Name: d.string(), Unit: d.string(), Help: d.string()}
+
+	n := d.uvarint()
+	if n > uint64(MaxBucketsHardLimit) {
+		return nil, fmt.Errorf("%w: %d buckets", ErrURLTooLarge, n)
+	}
+
+	// A bucket takes at least 3 varint bytes and 8 bytes of sum.
+	if n > uint64(len(d.data)/11) {
+		return nil, fmt.Errorf("%w: %d bytes for %d buckets", ErrURLFormat, len(d.data), n)
+	}
+
+	buckets := make([]Bucket, 0, int(n))
+	prev := uint64(0)
+
+	for i := uint64(0); i < n && d.err == nil; i++ {
+		minBits := d.uvarint() ^ prev
+		maxBits := d.uvarint() ^ minBits
+		cnt := d.uvarint()
+		sum := d.float()
+		prev = maxBits
+
+		if cnt > uint64(maxInt) {
+			return nil, fmt.Errorf("%w: bucket %d count %d", ErrURLFormat, i, cnt)
+		}
+
+		buckets = append(buckets, Bucket{
+			Min:   math.Float64frombits(minBits),
+			Max:   math.Float64frombits(maxBits),
+			Count: int(cnt),
+			Sum:   sum,
+		})
+	}
+
+	if d.err == nil && len(d.data) > 0 {
+		d.err = fmt.Errorf("%w: %d trailing bytes", ErrURLFormat, len(d.data))
+	}
+
+	if d.err != nil {
+		return nil, d.err
+	}
+
+	if len(buckets) > DefaultBucketsLimit {
+		c.BucketsLimit = len(buckets)
+	}
+
+	if err := c.LoadBuckets(buckets); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+func appendUvarint(dst []byte, v uint64) []byte {
+	var buf [binary.MaxVarintLen64]byte
+
+	return append(dst, buf[:binary.PutUvarint(buf[:], v)]...)
+}
+
+func appendUint64(dst []byte, v uint64) []byte {
+	var buf [8]byte
+
+	binary.LittleEndian.PutUint64(buf[:], v)
+
+	return append(dst, buf[:]...)
+}
+
+// urlDecoder reads payload of EncodeURL and keeps the first error.
+type urlDecoder struct {
+	data []byte
+	err  error
+}
+
+func (d *urlDecoder) uvarint() uint64 {
+	if d.err != nil {
+		return 0
+	}
+
+	v, n := binary.Uvarint(d.data)
+	if n <= 0 {
+		d.err = fmt.Errorf("%w: truncated or invalid varint", ErrURLFormat)
+
+		return 0
+	}
+
+	d.data = d.data[n:]
+
+	return v
+}
+
+func (d *urlDecoder) float() float64 {
+	if d.err != nil {
+		return 0
+	}
+
+	if len(d.data) < 8 {
+		d.err = fmt.Errorf("%w: truncated float", ErrURLFormat)
+
+		return 0
+	}
+
+	v := math.Float64frombits(binary.LittleEndian.Uint64(d.data))
+	d.data = d.data[8:]
+
+	return v
+}
+
+func (d *urlDecoder) string() string {
+	n := d.uvarint()
+	if d.err != nil {
+		return ""
+	}
+
+	if n > uint64(len(d.data)) {
+		d.err = fmt.Errorf("%w: truncated string", ErrURLFormat)
+
+		return ""
+	}
+
+	s := string(d.data[:n])
+	d.data = d.data[n:]
+
+	return s
+}
@@ -0,0 +1,77 @@
+package dynhist_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vearutop/dynhist-go"
+	"github.com/vearutop/dynhist-go/internal/dataset"
+)
+
+func TestCollector_EncodeURL(t *testing.T) {
+	c := dynhist.Collector{BucketsLimit: 10, Name: "latency", Unit: "seconds"}
+	r := dataset.New(1)
+
+	for i := 0; i < 10000; i++ {
+		c.Add(r.ExpFloat64() / 10)
+	}
+
+	s := c.EncodeURL()
+	assert.Less(t, len(s), 500)
+	assert.NotContains(t, s, "+")
+	assert.NotContains(t, s, "/")
+	assert.NotContains(t, s, "=")
+
+	d, err := dynhist.DecodeURL(s)
+	require.NoError(t, err)
+	assert.Equal(t, c.Buckets, d.Buckets)
+	assert.Equal(t, c.Count, d.Count)
+	assert.InDelta(t, c.Sum, d.Sum, 1e-9)
+	assert.Equal(t, "latency", d.Name)
+	assert.Equal(t, "seconds", d.Unit)
+	assert.Equal(t, c.String(), d.String())
+
+	// Large histograms are compressed.
+	c = dynhist.Collector{BucketsLimit: 1000}
+
+	for i := 0; i < 10000; i++ {
+		c.Add(float64(r.Intn(2000)) / 4)
+	}
+
+	d, err = dynhist.DecodeURL(c.EncodeURL())
+	require.NoError(t, err)
+	assert.Equal(t, c.Buckets, d.Buckets)
+	assert.Equal(t, 1000, d.BucketsLimit)
+
+	empty := dynhist.Collector{}
+	d, err = dynhist.DecodeURL(empty.EncodeURL())
+	require.NoError(t, err)
+	assert.Equal(t, 0, d.Count)
+}
+
+func TestDecodeURL_errors(t *testing.T) {
+	c := dynhist.Collector{}
+	c.Add(1)
+	c.Add(2)
+
+	s := c.EncodeURL()
+
+	for _, tc := range []struct {
+		s   string
+		err error
+	}{
+		{s: "!!!", err: dynhist.ErrURLFormat},
+		{s: "", err: dynhist.ErrURLFormat},
+		{s: s[:len(s)-4], err: dynhist.ErrURLFormat},
+		{s: s + "AA", err: dynhist.ErrURLFormat},
+		{s: "AQI", err: dynhist.ErrURLFormat},
+		{s: "CQA", err: dynhist.ErrUnsupportedVersion},
+		{s: strings.Repeat("A", dynhist.MaxURLLength+1), err: dynhist.ErrURLTooLarge},
+	} {
+		_, err := dynhist.DecodeURL(tc.s)
+		assert.True(t, errors.Is(err, tc.err), tc.s, err)
+	}
+}
@@ -0,0 +1,101 @@
+package dynhist_test
+
+import (
+	"math"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vearutop/dynhist-go"
+	"github.com/vearutop/dynhist-go/internal/dataset"
+)
+
+func TestCollector_WarmupCount(t *testing.T) {
+	r := dataset.New(1)
+
+	values := make([]float64, 1000)
+	for i := range values {
+		values[i] = math.Round(r.ExpFloat64()*1000) / 10
+	}
+
+	shuffled := append([]float64(nil), values...)
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+
+	ps := []float64{50, 90, 99}
+
+	build := func(warmup int, values []float64) *dynhist.Collector {
+		c := &dynhist.Collector{
+			BucketsLimit: 10,
+			WarmupCount:  warmup,
+			WeightFunc:   dynhist.LatencyWidth,
+		}
+
+		for _, v := range values {
+			c.Add(v)
+		}
+
+		assert.LessOrEqual(t, len(c.Buckets), 10)
+
+		return c
+	}
+
+	orderSensitivity := func(warmup int) float64 {
+		s, u := build(warmup, sorted), build(warmup, shuffled)
+		d := 0.0
+
+		for _, p := range ps {
+			d += math.Abs(s.Percentile(p) - u.Percentile(p))
+		}
+
+		return d
+	}
+
+	withoutWarmup := orderSensitivity(0)
+	withWarmup := orderSensitivity(len(values))
+
+	assert.Greater(t, withoutWarmup, 0.0)
+	assert.Equal(t, 0.0, withWarmup)
+	assert.Empty(t, dynhist.DiffString(build(len(values), sorted), build(len(values), shuffled)))
+}
+
+func TestCollector_WarmupCount_postponesMerge(t *testing.T) {
+	c := dynhist.Collector{
+		BucketsLimit: 5,
+		WarmupCount:  20,
+	}
+
+	for i := 0; i < 19; i++ {
+		c.Add(float64(i))
+	}
+
+	assert.Len(t, c.Buckets, 19)
+
+	c.Add(19)
+	assert.Len(t, c.Buckets, 5)
+
+	c.Add(20)
+	assert.Len(t, c.Buckets, 5)
+}
+
+func TestCollector_WarmupCount_hardLimit(t *testing.T) {
+	defer func(prev int) { dynhist.MaxBucketsHardLimit = prev }(dynhist.MaxBucketsHardLimit)
+
+	dynhist.MaxBucketsHardLimit = 50
+
+	c := dynhist.Collector{
+		BucketsLimit: 5,
+		WarmupCount:  1000,
+	}
+
+	for i := 0; i < 999; i++ {
+		c.Add(float64(i))
+		assert.LessOrEqual(t, len(c.Buckets), 50)
+	}
+
+	assert.Len(t, c.Buckets, 50)
+	assert.Equal(t, 999, c.Count)
+
+	c.Add(999)
+	assert.Len(t, c.Buckets, 5)
+}
@@ -0,0 +1,92 @@
+package dynhist
+
+// percentileWatcher notifies about crossings of a threshold by P² estimate of a percentile.
+type percentileWatcher struct {
+	q          p2Quantile
+	threshold  float64
+	hysteresis float64
+	above      bool
+	fn         func(crossed bool, value float64)
+}
+
+// watchEvent is a pending notification of a watcher.
+type watchEvent struct {
+	fn      func(crossed bool, value float64)
+	crossed bool
+	value   float64
+}
+
+// WatchPercentile registers a callback for crossings of threshold by a percentile (0-100].
+//
+// Percentile is estimated with P² algorithm after every added value, estimation starts at registration.
+// Function fn is called with true and current estimate when estimate rises above threshold, and with false
+// when estimate falls below threshold-hysteresis. Function is called outside of the lock after
+// value is added, so it may use collector.
+//
+// It returns a function to stop watching.
+func (c *Collector) WatchPercentile(percent, threshold, hysteresis float64, fn func(crossed bool, value float64)) (stop func()) {
+	if c == nil {
+		return func() {}
+	}
+
+	w := &percentileWatcher{
+		q:          newP2Quantile(percent),
+		threshold:  threshold,
+		hysteresis: hysteresis,
+		fn:         fn,
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	c.watchers = append(c.watchers, w)
+
+	return func() {
+		c.Lock()
+		defer c.Unlock()
+
+		for i, cw := range c.watchers {
+			if cw == w {
+				c.watchers = append(c.watchers[:i], c.watchers[i+1:]...)
+
+				break
+			}
+		}
+	}
+}
+
+// watch updates watchers with value and records crossings as pending events.
+func (c *Collector) watch(v float64, n int) {
+	for _, w := range c.watchers {
+		w.q.addN(v, n)
+
+		value := w.q.value()
+
+		switch {
+		case !w.above && value > w.threshold:
+			w.above = true
+		case w.above && value < w.threshold-w.hysteresis:
+			w.above = false
+		default:
+			continue
+		}
+
+		c.events = append(c.events, watchEvent{fn: w.fn, crossed: w.above, value: value})
+	}
+}
+
+// unlockAndNotify unlocks collector and calls pending watchers.
+func (c *Collector) unlockAndNotify() {
+	events := c.events
+	c.events = nil
+
+	c.Unlock()
+
+	notify(events)
+}
+
+func notify(events []watchEvent) {
+	for _, e := range events {
+		e.fn(e.crossed, e.value)
+	}
+}
@@ -0,0 +1,80 @@
+package dynhist_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vearutop/dynhist-go"
+	"github.com/vearutop/dynhist-go/internal/dataset"
+)
+
+func TestCollector_WatchPercentile(t *testing.T) {
+	type event struct {
+		crossed bool
+		value   float64
+		count   int
+	}
+
+	var (
+		c      dynhist.Collector
+		events []event
+	)
+
+	r := dataset.New(1)
+
+	stop := c.WatchPercentile(50, 50, 5, func(crossed bool, value float64) {
+		// Collector is not locked during notification.
+		events = append(events, event{crossed: crossed, value: value, count: c.TotalCount()})
+	})
+
+	for i := 0; i < 1000; i++ {
+		c.Add(40 * r.Float64())
+	}
+
+	assert.Empty(t, events)
+
+	// Shift up.
+	for i := 0; i < 3000; i++ {
+		c.Add(60 + 40*r.Float64())
+	}
+
+	require.Len(t, events, 1)
+	assert.True(t, events[0].crossed)
+	assert.Greater(t, events[0].value, 50.0)
+	assert.Greater(t, events[0].count, 1500)
+
+	// Shift down.
+	for i := 0; i < 6000; i++ {
+		c.Add(40 * r.Float64())
+	}
+
+	require.Len(t, events, 2)
+	assert.False(t, events[1].crossed)
+	assert.Less(t, events[1].value, 45.0)
+
+	stop()
+
+	c.AddN(100, 100000)
+	assert.Len(t, events, 2)
+}
+
+func TestCollector_WatchPercentile_multiple(t *testing.T) {
+	var (
+		c        dynhist.Collector
+		p50, p99 []bool
+	)
+
+	stop50 := c.WatchPercentile(50, 10, 0, func(crossed bool, value float64) { p50 = append(p50, crossed) })
+	defer stop50()
+
+	stop99 := c.WatchPercentile(99, 10, 0, func(crossed bool, value float64) { p99 = append(p99, crossed) })
+	defer stop99()
+
+	for i := 0; i < 1000; i++ {
+		c.Add(float64(i % 20))
+	}
+
+	assert.Empty(t, p50)
+	assert.Equal(t, []bool{true}, p99)
+}
@@ -0,0 +1,108 @@
+package dynhist
+
+import "math"
+
+// PercentileWithin returns percentile of values conditioned on value range [lo, hi].
+//
+// Buckets crossing the range boundaries are pro-rated by overlapping width, the result is
+// linearly interpolated within the bucket where percentile falls. Range and result are in original
+// units if Transform is set.
+// NaN is returned if lo >= hi or there is no data in the range.
+func (c *Collector) PercentileWithin(lo, hi, percent float64) float64 {
+	if c == nil {
+		return math.NaN()
+	}
+
+	if !(lo < hi) {
+		return math.NaN()
+	}
+
+	_, buckets := c.snapshotBuckets()
+
+	type part struct {
+		min, max, count float64
+	}
+
+	parts := make([]part, 0, len(buckets))
+	total := 0.0
+
+	for _, b := range buckets {
+		if b.Max < lo || b.Min > hi || b.Count == 0 {
+			continue
+		}
+
+		p := part{min: math.Max(b.Min, lo), max: math.Min(b.Max, hi), count: float64(b.Count)}
+
+		if b.Max > b.Min {
+			p.count *= (p.max - p.min) / (b.Max - b.Min)
+		}
+
+		if p.count <= 0 {
+			continue
+		}
+
+		total += p.count
+		parts = append(parts, p)
+	}
+
+	if len(parts) == 0 {
+		return math.NaN()
+	}
+
+	target := percent * total / 100
+	acc := 0.0
+
+	for _, p := range parts {
+		if acc+p.count >= target {
+			return p.min + (p.max-p.min)*math.Max(0, target-acc)/p.count
+		}
+
+		acc += p.count
+	}
+
+	return parts[len(parts)-1].max
+}
+
+// Range returns buckets overlapping value range [lo, hi] and their aggregate.
+//
+// Buckets crossing the range boundaries are clipped, their counts and sums are pro-rated
+// by overlapping width assuming uniform distribution within bucket (counts are rounded). Empty result and zero aggregate are returned
+// if lo > hi or the range does not overlap any bucket.
+func (c *Collector) Range(lo, hi float64) ([]Bucket, Bucket) {
+	if !(lo <= hi) {
+		return nil, Bucket{}
+	}
+
+	_, buckets := c.snapshotBuckets()
+
+	var (
+		res   []Bucket
+		total Bucket
+	)
+
+	for _, b := range buckets {
+		if b.Max < lo || b.Min > hi {
+			continue
+		}
+
+		p := Bucket{Min: math.Max(b.Min, lo), Max: math.Min(b.Max, hi), Count: b.Count, Sum: b.Sum}
+
+		if b.Max > b.Min && (p.Min > b.Min || p.Max < b.Max) {
+			frac := (p.Max - p.Min) / (b.Max - b.Min)
+			p.Count = int(math.Round(float64(b.Count) * frac))
+			// Values are assumed uniform within bucket, so the mean is shifted to the middle of the clipped part.
+			p.Sum = frac * (b.Sum + float64(b.Count)*((p.Min+p.Max)-(b.Min+b.Max))/2)
+		}
+
+		if len(res) == 0 {
+			total.Min = p.Min
+		}
+
+		total.Max = p.Max
+		total.Count += p.Count
+		total.Sum += p.Sum
+		res = append(res, p)
+	}
+
+	return res, total
+}
@@ -0,0 +1,132 @@
+package dynhist_test
+
+import (
+	"math"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vearutop/dynhist-go"
+	"github.com/vearutop/dynhist-go/internal/dataset"
+)
+
+func TestCollector_PercentileWithin(t *testing.T) {
+	c := dynhist.Collector{
+		BucketsLimit: 50,
+		RawValues:    []float64{},
+	}
+	r := dataset.New(1)
+
+	for i := 0; i < 100000; i++ {
+		c.Add(r.Float64() * 1000)
+	}
+
+	exact := func(lo, hi, p float64) float64 {
+		var vs []float64
+
+		for _, v := range c.RawValues {
+			if v >= lo && v <= hi {
+				vs = append(vs, v)
+			}
+		}
+
+		sort.Float64s(vs)
+
+		return vs[int(math.Ceil(p*float64(len(vs))/100))-1]
+	}
+
+	bucketWidth := 1000.0 / 50
+
+	for _, tc := range [][3]float64{
+		{0, 1000, 50},
+		{100, 200, 50},
+		{333, 777, 99},
+		{500, 1000, 90},
+		{10, 15, 10},
+	} {
+		assert.InDelta(t, exact(tc[0], tc[1], tc[2]), c.PercentileWithin(tc[0], tc[1], tc[2]), bucketWidth/4, tc)
+	}
+
+	assert.True(t, math.IsNaN(c.PercentileWithin(2, 1, 50)))
+	assert.True(t, math.IsNaN(c.PercentileWithin(1, 1, 50)))
+	assert.True(t, math.IsNaN(c.PercentileWithin(2000, 3000, 50)))
+}
+
+func TestCollector_PercentileWithin_pointMass(t *testing.T) {
+	c := dynhist.Collector{}
+
+	for i := 0; i < 10; i++ {
+		c.Add(5)
+	}
+
+	c.Add(100)
+
+	assert.Equal(t, 5.0, c.PercentileWithin(0, 10, 99))
+	assert.True(t, math.IsNaN(c.PercentileWithin(10, 20, 50)))
+}
+
+func TestCollector_Range(t *testing.T) {
+	c := dynhist.Collector{BucketsLimit: 20, RawValues: []float64{}}
+	r := dataset.New(1)
+
+	for i := 0; i < 100000; i++ {
+		c.Add(10 * r.Float64())
+	}
+
+	for _, rng := range [][2]float64{{2.5, 7.3}, {0, 10}, {3.33, 3.34}, {-5, 1.7}} {
+		lo, hi := rng[0], rng[1]
+		exact := dynhist.Bucket{}
+
+		for _, v := range c.RawValues {
+			if v >= lo && v <= hi {
+				exact.Count++
+				exact.Sum += v
+			}
+		}
+
+		buckets, total := c.Range(lo, hi)
+
+		assert.InDelta(t, exact.Count, total.Count, 0.01*float64(exact.Count)+float64(len(buckets)), rng)
+		assert.InDelta(t, exact.Sum, total.Sum, 0.02*exact.Sum, rng)
+		assert.GreaterOrEqual(t, total.Min, lo)
+		assert.LessOrEqual(t, total.Max, hi)
+
+		cnt := 0
+		for _, b := range buckets {
+			cnt += b.Count
+		}
+
+		assert.Equal(t, total.Count, cnt)
+	}
+
+	buckets, total := c.Range(0, 20)
+	assert.Equal(t, c.Buckets, buckets)
+	assert.Equal(t, c.Count, total.Count)
+
+	buckets, total = c.Range(3, 2)
+	assert.Empty(t, buckets)
+	assert.Equal(t, dynhist.Bucket{}, total)
+
+	buckets, total = c.Range(11, 12)
+	assert.Empty(t, buckets)
+	assert.Equal(t, dynhist.Bucket{}, total)
+}
+
+func TestCollector_Range_gap(t *testing.T) {
+	c := dynhist.Collector{BucketsLimit: 2}
+	c.Add(1)
+	c.Add(2)
+	c.Add(8)
+	c.Add(9)
+
+	buckets, total := c.Range(4, 6)
+	assert.Empty(t, buckets)
+	assert.Equal(t, dynhist.Bucket{}, total)
+
+	buckets, total = c.Range(1.5, 8.5)
+	assert.Equal(t, []dynhist.Bucket{
+		{Min: 1.5, Max: 2, Count: 1, Sum: 1.75},
+		{Min: 8, Max: 8.5, Count: 1, Sum: 8.25},
+	}, buckets)
+	assert.Equal(t, dynhist.Bucket{Min: 1.5, Max: 8.5, Count: 2, Sum: 10}, total)
+}